@@ -4,6 +4,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -47,6 +48,52 @@ type QuotaConfig struct {
 	WindowSeconds int32 `json:"windowSeconds"`
 }
 
+// FairShareConfig divides the capacity of a shared Agent between the namespaces
+// whose Tasks use it, so a single namespace cannot take the whole maxConcurrentTasks
+// budget or quota.
+type FairShareConfig struct {
+	// MaxConcurrentTasksPerNamespace limits the number of Tasks from each namespace
+	// that can run concurrently using this Agent. Tasks over their namespace's limit
+	// wait in Queued phase without blocking Tasks from other namespaces.
+	//
+	// - nil or 0: no per-namespace limit
+	// - positive number: maximum number of Running Tasks per namespace
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentTasksPerNamespace *int32 `json:"maxConcurrentTasksPerNamespace,omitempty"`
+
+	// QuotaPerNamespace limits the rate of Task starts from each namespace,
+	// in addition to the Agent-wide quota.
+	// +optional
+	QuotaPerNamespace *QuotaConfig `json:"quotaPerNamespace,omitempty"`
+
+	// Namespaces overrides the per-namespace defaults for specific namespaces.
+	// The first entry matching a Task's namespace applies.
+	// +optional
+	// +listType=atomic
+	Namespaces []NamespaceShare `json:"namespaces,omitempty"`
+}
+
+// NamespaceShare overrides the fair share of the namespaces matching Namespace.
+type NamespaceShare struct {
+	// Namespace is a namespace name or glob pattern (e.g., "team-*"),
+	// matched the same way as allowedNamespaces.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Namespace string `json:"namespace"`
+
+	// MaxConcurrentTasks overrides maxConcurrentTasksPerNamespace for matching namespaces.
+	// nil inherits the default; 0 removes the per-namespace limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentTasks *int32 `json:"maxConcurrentTasks,omitempty"`
+
+	// Quota overrides quotaPerNamespace for matching namespaces.
+	// nil inherits the default.
+	// +optional
+	Quota *QuotaConfig `json:"quota,omitempty"`
+}
+
 // TaskStartRecord represents a record of a Task start for quota tracking.
 // Stored in AgentStatus to persist across controller restarts.
 type TaskStartRecord struct {
@@ -78,6 +125,99 @@ type ServerConfig struct {
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=65535
 	Port int32 `json:"port,omitempty"`
+
+	// Replicas is the number of server replicas.
+	// With more than one replica, each Task is pinned to a ready replica when it
+	// starts (recorded in status.serverPod) and attaches to that replica directly,
+	// so its session always runs on the same server. Follow-up Tasks (sessionRef)
+	// are pinned to the replica of the session they continue. A persistent workspace
+	// shared by several replicas needs a ReadWriteMany access mode; the server is not
+	// created otherwise.
+	// Defaults to 1 if not specified.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Workspace makes the server's workspace persistent.
+	// When set, the controller provisions a PersistentVolumeClaim owned by the Agent
+	// and mounts it at workspaceDir, so cloned repositories and caches survive server
+	// restarts and rollouts. Git contexts are kept on the volume and refreshed in place
+	// (fetch and reset) when the server restarts, instead of being cloned again.
+	// When not set, the workspace is an emptyDir.
+	// +optional
+	Workspace *ServerWorkspace `json:"workspace,omitempty"`
+
+	// IdleTimeoutSeconds scales the server down to zero replicas after it has had
+	// no Tasks in progress for this many seconds. A Task created for a scaled-down
+	// server is Queued with reason ServerStarting, the server is scaled back up, and
+	// the Task starts once the server has ready replicas.
+	// When not set, the server always runs.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	IdleTimeoutSeconds *int32 `json:"idleTimeoutSeconds,omitempty"`
+
+	// ReadyTimeoutSeconds is how long a Task waits for the server to become ready.
+	// Tasks for a server that is not ready (e.g., during a rollout, while crash-looping,
+	// or while starting after being scaled down) stay Queued until the Agent's
+	// ServerReady condition is True, and fail once they have waited this long.
+	// Defaults to 600 (10 minutes) if not specified.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ReadyTimeoutSeconds *int32 `json:"readyTimeoutSeconds,omitempty"`
+
+	// DrainTimeoutSeconds bounds how long a change to the server Pod waits for the
+	// Agent's Running Tasks to finish. While the Agent is draining (its Draining
+	// condition is True), new Tasks stay Queued with reason ServerDraining and the
+	// running server is left as it is. The change is rolled out once no Task is
+	// running, or after this many seconds. Set to 0 to roll out changes immediately.
+	// Keep it below readyTimeoutSeconds, as Tasks waiting for the drain count that
+	// time towards it.
+	// Defaults to 300 (5 minutes) if not specified.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	DrainTimeoutSeconds *int32 `json:"drainTimeoutSeconds,omitempty"`
+
+	// ConcurrencyCounting selects what the Agent's maxConcurrentTasks counts:
+	// "Tasks" counts the Agent's Tasks holding a slot, "ActiveSessions" counts the
+	// active sessions on the server instead, when there are more of them. Tasks that
+	// were admitted but have not started their session yet still hold a slot.
+	// Defaults to "Tasks" if not specified.
+	// +optional
+	ConcurrencyCounting ConcurrencyCounting `json:"concurrencyCounting,omitempty"`
+}
+
+// ConcurrencyCounting selects what maxConcurrentTasks counts for a Server-mode Agent.
+// +kubebuilder:validation:Enum=Tasks;ActiveSessions
+type ConcurrencyCounting string
+
+const (
+	// ConcurrencyCountingTasks counts the Agent's Tasks that hold a concurrency slot.
+	ConcurrencyCountingTasks ConcurrencyCounting = "Tasks"
+	// ConcurrencyCountingActiveSessions counts the active sessions on the server
+	// (status.serverStatus.activeSessions) when there are more of them than Tasks
+	// holding a slot, e.g. because users work on the server directly.
+	ConcurrencyCountingActiveSessions ConcurrencyCounting = "ActiveSessions"
+)
+
+// ServerWorkspace configures the PersistentVolumeClaim of a Server-mode Agent's workspace.
+// The claim is named "{agent-name}-server-workspace" and is deleted with the Agent.
+type ServerWorkspace struct {
+	// StorageClassName is the StorageClass of the PersistentVolumeClaim.
+	// Uses the cluster's default StorageClass if not specified.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size is the requested storage size of the workspace.
+	// The size can be increased later if the StorageClass allows volume expansion.
+	// Defaults to 10Gi if not specified.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// AccessModes of the PersistentVolumeClaim.
+	// Defaults to ReadWriteOnce if not specified.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
 }
 
 // ServerStatus represents the observed state of a Server-mode Agent.
@@ -100,9 +240,33 @@ type ServerStatus struct {
 	URL string `json:"url,omitempty"`
 
 	// ReadyReplicas is the number of ready server pods from the Deployment.
-	// For Server-mode, this is typically 0 or 1 (single replica only).
 	// +optional
 	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// LastActiveTime is the last time the server had Tasks in progress.
+	// Only set when idleTimeoutSeconds is configured.
+	// +optional
+	LastActiveTime *metav1.Time `json:"lastActiveTime,omitempty"`
+
+	// ActiveSessions is the number of sessions on the server that are working on a
+	// prompt, as last reported by the server's /session/status endpoint.
+	// The controller polls the server on every reconcile of the Agent.
+	// +optional
+	ActiveSessions int32 `json:"activeSessions,omitempty"`
+
+	// IdleSessions is the number of sessions on the server that are not working on
+	// a prompt, e.g. the sessions of finished Tasks that have not been deleted yet.
+	// +optional
+	IdleSessions int32 `json:"idleSessions,omitempty"`
+
+	// OldestActiveSessionTime is the creation time of the oldest active session.
+	// Not set when no session is active.
+	// +optional
+	OldestActiveSessionTime *metav1.Time `json:"oldestActiveSessionTime,omitempty"`
+
+	// LastActivityTime is the last time a session on the server was updated.
+	// +optional
+	LastActivityTime *metav1.Time `json:"lastActivityTime,omitempty"`
 }
 
 // AgentSpec defines agent configuration
@@ -259,6 +423,33 @@ type AgentSpec struct {
 	// +optional
 	Quota *QuotaConfig `json:"quota,omitempty"`
 
+	// FairShare limits how much of this Agent's capacity each namespace can use.
+	// Use it on Agents shared through allowedNamespaces, so one namespace
+	// submitting many Tasks cannot starve the others.
+	//
+	// Example:
+	//   fairShare:
+	//     maxConcurrentTasksPerNamespace: 2
+	//     namespaces:
+	//       - namespace: "team-platform"
+	//         maxConcurrentTasks: 5
+	// +optional
+	FairShare *FairShareConfig `json:"fairShare,omitempty"`
+
+	// MaxTimeoutSeconds is the ceiling for Task execution timeouts on this Agent.
+	// Tasks requesting a longer timeoutSeconds are capped to this value, and
+	// Tasks without a timeout use it as their timeout. This prevents a stuck
+	// agent from holding a maxConcurrentTasks slot forever.
+	//
+	// - nil: no ceiling (Tasks without timeoutSeconds run until the Pod exits)
+	// - positive number: maximum Task runtime in seconds
+	//
+	// Example:
+	//   maxTimeoutSeconds: 7200  # Tasks run at most 2 hours
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxTimeoutSeconds *int32 `json:"maxTimeoutSeconds,omitempty"`
+
 	// ServerConfig enables Server mode for this Agent.
 	// When set, the Agent runs as a persistent OpenCode server (Deployment + Service)
 	// instead of creating ephemeral Pods per Task.
@@ -294,8 +485,8 @@ type AgentStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
 	// TaskStartHistory tracks recent Task starts for quota enforcement.
-	// The controller prunes entries older than the quota window automatically.
-	// This is only populated when quota is configured on the Agent.
+	// The controller prunes entries older than the longest quota window automatically.
+	// This is only populated when quota (or a per-namespace quota) is configured on the Agent.
 	// +optional
 	// +listType=atomic
 	TaskStartHistory []TaskStartRecord `json:"taskStartHistory,omitempty"`
@@ -4,6 +4,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,6 +37,61 @@ type KubeOpenCodeConfigSpec struct {
 	// If not specified, Tasks are not automatically deleted (default behavior).
 	// +optional
 	Cleanup *CleanupConfig `json:"cleanup,omitempty"`
+
+	// GitCache enables a shared cache of Git mirrors for Git contexts.
+	// git-init keeps a bare mirror of each repository in the cache, keyed by the
+	// repository URL, and only fetches the objects missing from it from the remote.
+	// If not specified, every clone fetches the repository from the remote.
+	// +optional
+	GitCache *GitCacheConfig `json:"gitCache,omitempty"`
+}
+
+// GitCacheConfig configures the shared cache of Git mirrors.
+// The cache is either a directory on each node (hostPath) or a PersistentVolumeClaim
+// shared by the Pods of a namespace. If neither is specified, the node-local
+// directory /var/lib/kubeopencode/git-cache is used.
+type GitCacheConfig struct {
+	// HostPath is the directory on the node holding the mirrors.
+	// It must be writable by the user git-init runs as, and is shared by all Pods
+	// on the node, whatever their namespace.
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+
+	// PersistentVolumeClaim keeps the mirrors on a PersistentVolumeClaim named
+	// "kubeopencode-git-cache", which the controller creates in each namespace
+	// Pods with Git contexts run in. Takes precedence over HostPath.
+	// The claim is not deleted when the cache is disabled.
+	// +optional
+	PersistentVolumeClaim *GitCacheVolumeClaim `json:"persistentVolumeClaim,omitempty"`
+
+	// MaxSize is the total size of the mirrors above which the least recently
+	// used mirrors are evicted. If not specified, the cache is not limited in size.
+	// +optional
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+
+	// MaxUnusedSeconds evicts mirrors that have not been cloned from for this long.
+	// Defaults to 604800 (7 days).
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxUnusedSeconds *int32 `json:"maxUnusedSeconds,omitempty"`
+}
+
+// GitCacheVolumeClaim configures the PersistentVolumeClaim of the Git mirror cache.
+type GitCacheVolumeClaim struct {
+	// StorageClassName is the StorageClass of the PersistentVolumeClaim.
+	// Uses the cluster's default StorageClass if not specified.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size is the requested storage size of the cache.
+	// Defaults to 20Gi if not specified.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// AccessModes of the PersistentVolumeClaim.
+	// Defaults to ReadWriteMany, so that Pods on any node can use the cache.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
 }
 
 // CleanupConfig defines cleanup policies for completed/failed Tasks.
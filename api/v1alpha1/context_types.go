@@ -65,12 +65,24 @@ type GitContext struct {
 	// +optional
 	Path string `json:"path,omitempty"`
 
-	// Ref is the Git reference (branch, tag, or commit SHA).
+	// Ref is the Git reference to check out: a branch or tag name, a full commit SHA,
+	// a ref such as "refs/pull/123/head", or a refspec such as
+	// "+refs/pull/123/head:refs/remotes/pr/123". Branches and tags are cloned directly;
+	// commit SHAs, refs and refspecs are fetched into a new repository and checked out
+	// as a detached HEAD. Abbreviated commit SHAs are not supported.
 	// Defaults to "HEAD" if not specified.
 	// +optional
 	// +kubebuilder:default="HEAD"
 	Ref string `json:"ref,omitempty"`
 
+	// BaseRef is an optional second reference (branch, tag, full commit SHA, or ref)
+	// fetched alongside Ref, e.g. the target branch of a pull request. It is stored as
+	// "refs/kubeopencode/base", so agents can diff against it with
+	// "git diff kubeopencode/base HEAD". A three-dot diff needs the merge base, so use
+	// a Depth that covers the commits in between.
+	// +optional
+	BaseRef string `json:"baseRef,omitempty"`
+
 	// Depth specifies the clone depth for shallow cloning.
 	// 1 means shallow clone (fastest), 0 means full clone.
 	// Defaults to 1 for efficiency.
@@ -78,6 +90,28 @@ type GitContext struct {
 	// +kubebuilder:default=1
 	Depth *int `json:"depth,omitempty"`
 
+	// Submodules selects how submodules are checked out: "none" skips them,
+	// "shallow" checks out the top-level submodules at depth 1, and "recursive" also
+	// checks out their nested submodules. Submodules are cloned with the credentials
+	// of SecretRef.
+	// Defaults to "none".
+	// +optional
+	Submodules GitSubmodules `json:"submodules,omitempty"`
+
+	// LFS fetches the Git LFS objects of the checked out commit, replacing the LFS
+	// pointer files with their content. Without it, LFS files are left as pointers.
+	// +optional
+	LFS *GitLFS `json:"lfs,omitempty"`
+
+	// SparseCheckout lists the directories of the repository to check out (cone
+	// mode): only these directories and the files at the root of the repository are
+	// materialized, and file contents outside of them are not downloaded. Use it to
+	// check out parts of large monorepos. Git commands that need the other files
+	// (e.g. "git log -p") download them on demand.
+	// Example: ["services/api", "libs/common"]
+	// +optional
+	SparseCheckout []string `json:"sparseCheckout,omitempty"`
+
 	// SecretRef references a Secret containing Git credentials.
 	// The Secret should contain one of:
 	//   - "username" + "password": For HTTPS token-based auth (password can be a PAT)
@@ -85,6 +119,55 @@ type GitContext struct {
 	// If not specified, anonymous clone is attempted.
 	// +optional
 	SecretRef *GitSecretReference `json:"secretRef,omitempty"`
+
+	// PushBack pushes the agent's changes to the repository after the agent exits
+	// successfully: uncommitted changes are committed, and the result is pushed to a
+	// branch with the credentials of SecretRef. The pushed branch and commit are
+	// recorded in the Task's status.contexts. Only supported for Pod-mode Agents.
+	// +optional
+	PushBack *GitPushBack `json:"pushBack,omitempty"`
+}
+
+// GitSubmodules selects how the submodules of a Git context are checked out.
+// +kubebuilder:validation:Enum=none;shallow;recursive
+type GitSubmodules string
+
+const (
+	// GitSubmodulesNone does not check out submodules.
+	GitSubmodulesNone GitSubmodules = "none"
+	// GitSubmodulesShallow checks out the top-level submodules at depth 1.
+	GitSubmodulesShallow GitSubmodules = "shallow"
+	// GitSubmodulesRecursive checks out submodules and their nested submodules at depth 1.
+	GitSubmodulesRecursive GitSubmodules = "recursive"
+)
+
+// GitLFS configures fetching the Git LFS objects of a Git context.
+type GitLFS struct {
+	// Include limits the fetched LFS objects to the files matching these patterns
+	// (see "git lfs fetch --include"). All LFS objects are fetched if empty.
+	// Example: ["assets/**", "*.bin"]
+	// +optional
+	Include []string `json:"include,omitempty"`
+}
+
+// GitPushBack configures pushing the agent's changes back to a Git repository.
+// Branch and CommitMessage are Go templates with the fields .TaskName and
+// .TaskNamespace.
+type GitPushBack struct {
+	// Branch is the branch to push to.
+	// Defaults to "kubeopencode/{{.TaskName}}".
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// CommitMessage is the message of the commit holding uncommitted changes.
+	// Defaults to "KubeOpenCode Task {{.TaskNamespace}}/{{.TaskName}}".
+	// +optional
+	CommitMessage string `json:"commitMessage,omitempty"`
+
+	// Force overwrites the branch if it exists and has diverged.
+	// Without it, such a push fails.
+	// +optional
+	Force bool `json:"force,omitempty"`
 }
 
 // GitSecretReference references a Secret for Git authentication.
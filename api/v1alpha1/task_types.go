@@ -11,7 +11,8 @@ import (
 type TaskPhase string
 
 const (
-	// TaskPhasePending means the task has not started yet
+	// TaskPhasePending means the task has not started yet,
+	// or is waiting for the retry backoff after a failed attempt.
 	TaskPhasePending TaskPhase = "Pending"
 	// TaskPhaseQueued means the task is waiting for Agent capacity.
 	// This occurs when the Agent has maxConcurrentTasks set and the limit is reached.
@@ -28,6 +29,21 @@ const (
 	TaskPhaseFailed TaskPhase = "Failed"
 )
 
+// TaskOutcome is the agent-reported outcome of a Task.
+// Unlike TaskPhase, which reflects whether the Pod exited successfully,
+// the outcome reflects whether the agent accomplished what it was asked to do.
+// +kubebuilder:validation:Enum=Succeeded;Failed;NeedsHuman
+type TaskOutcome string
+
+const (
+	// TaskOutcomeSucceeded means the agent reports that the task was accomplished
+	TaskOutcomeSucceeded TaskOutcome = "Succeeded"
+	// TaskOutcomeFailed means the agent reports that the task could not be accomplished
+	TaskOutcomeFailed TaskOutcome = "Failed"
+	// TaskOutcomeNeedsHuman means the agent requires human input or review to proceed
+	TaskOutcomeNeedsHuman TaskOutcome = "NeedsHuman"
+)
+
 const (
 	// ConditionTypeReady is the condition type for Task readiness
 	ConditionTypeReady = "Ready"
@@ -35,6 +51,9 @@ const (
 	ConditionTypeQueued = "Queued"
 	// ConditionTypeStopped is the condition type for Task stop
 	ConditionTypeStopped = "Stopped"
+	// ConditionTypeStalled is the condition type for a running Task whose Pod
+	// is not making progress (e.g., image pull failures, unschedulable)
+	ConditionTypeStalled = "Stalled"
 
 	// ReasonTaskTemplateError is the reason for TaskTemplate errors
 	ReasonTaskTemplateError = "TaskTemplateError"
@@ -44,6 +63,10 @@ const (
 	ReasonAgentAtCapacity = "AgentAtCapacity"
 	// ReasonQuotaExceeded is the reason for Agent quota limit
 	ReasonQuotaExceeded = "QuotaExceeded"
+	// ReasonNamespaceAtCapacity is the reason for Agent per-namespace capacity limit (fairShare)
+	ReasonNamespaceAtCapacity = "NamespaceAtCapacity"
+	// ReasonNamespaceQuotaExceeded is the reason for Agent per-namespace quota limit (fairShare)
+	ReasonNamespaceQuotaExceeded = "NamespaceQuotaExceeded"
 	// ReasonContextError is the reason for Context errors
 	ReasonContextError = "ContextError"
 	// ReasonUserStopped is the reason for user-initiated stop
@@ -56,6 +79,56 @@ const (
 	ReasonPodCreationError = "PodCreationError"
 	// ReasonConfigMapCreationError is the reason for ConfigMap creation failures
 	ReasonConfigMapCreationError = "ConfigMapCreationError"
+	// ReasonURLFetchError is the reason for URL context fetch failures
+	ReasonURLFetchError = "URLFetchError"
+	// ReasonTimedOut is the reason for Tasks that exceeded their execution timeout
+	ReasonTimedOut = "TimedOut"
+	// ReasonPodFailed is the reason for a Pod that failed (e.g., non-zero exit code)
+	ReasonPodFailed = "PodFailed"
+	// ReasonEvicted is the reason for a Pod that was evicted from its node
+	ReasonEvicted = "Evicted"
+	// ReasonGitInitError is the reason for Git context clone failures
+	ReasonGitInitError = "GitInitError"
+	// ReasonGitPushError is the reason for a Task whose agent succeeded but whose
+	// changes could not be pushed back to a Git context
+	ReasonGitPushError = "GitPushError"
+	// ReasonPodLost is the reason for a Pod that was deleted outside the controller
+	// (e.g., node drain, manual deletion) while the Task was running
+	ReasonPodLost = "PodLost"
+	// ReasonOOMKilled is the reason for a container that was killed for exceeding its memory limit
+	ReasonOOMKilled = "OOMKilled"
+	// ReasonImagePullError is the reason for a container image that cannot be pulled
+	ReasonImagePullError = "ImagePullError"
+	// ReasonContainerConfigError is the reason for a container that cannot be created
+	// from its configuration (e.g., a referenced credential Secret does not exist)
+	ReasonContainerConfigError = "ContainerConfigError"
+	// ReasonUnschedulable is the reason for a Pod that cannot be scheduled onto a node
+	ReasonUnschedulable = "Unschedulable"
+	// ReasonPodPending is the reason for a Pod that has been Pending longer than expected
+	ReasonPodPending = "PodPending"
+	// ReasonRetrying is the reason for a failed attempt that will be retried
+	ReasonRetrying = "Retrying"
+	// ReasonSessionRefError is the reason for a sessionRef that cannot be continued
+	// (e.g., different Agent, or the session no longer exists)
+	ReasonSessionRefError = "SessionRefError"
+	// ReasonWaitingForSession is the reason for a Task queued until the Task
+	// referenced by its sessionRef has finished
+	ReasonWaitingForSession = "WaitingForSession"
+	// ReasonSessionAvailable is the reason for a Task leaving the queue once the Task
+	// referenced by its sessionRef has finished
+	ReasonSessionAvailable = "SessionAvailable"
+	// ReasonServerStarting is the reason for a Task queued while its Server-mode Agent,
+	// scaled down after being idle, is started again
+	ReasonServerStarting = "ServerStarting"
+	// ReasonServerNotReady is the reason for a Task queued until the server of its
+	// Agent is ready, and for a Task that failed because it did not become ready in time
+	ReasonServerNotReady = "ServerNotReady"
+	// ReasonServerDraining is the reason for a Task queued while its Server-mode Agent
+	// waits for its Running Tasks to finish before rolling out a change to the server
+	ReasonServerDraining = "ServerDraining"
+	// ReasonServerReady is the reason for a Task leaving the queue once the server of
+	// its Agent is ready
+	ReasonServerReady = "ServerReady"
 )
 
 // +genclient
@@ -63,6 +136,8 @@ const (
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope="Namespaced",shortName=tk
 // +kubebuilder:printcolumn:JSONPath=`.status.phase`,name="Phase",type=string
+// +kubebuilder:printcolumn:JSONPath=`.spec.priority`,name="Priority",type=integer,priority=1
+// +kubebuilder:printcolumn:JSONPath=`.status.queuePosition`,name="Queue",type=integer,priority=1
 // +kubebuilder:printcolumn:JSONPath=`.status.agentRef.namespace`,name="Agent-NS",type=string
 // +kubebuilder:printcolumn:JSONPath=`.status.agentRef.name`,name="Agent",type=string
 // +kubebuilder:printcolumn:JSONPath=`.status.podName`,name="Pod",type=string
@@ -159,6 +234,185 @@ type TaskSpec struct {
 	// If not specified and taskTemplateRef is set, uses the template's agentRef.
 	// +optional
 	AgentRef *AgentReference `json:"agentRef,omitempty"`
+
+	// TimeoutSeconds limits how long the Task may run, measured from when
+	// its Pod is created. When exceeded, the Pod is terminated and the Task
+	// fails with reason TimedOut.
+	//
+	// If not specified, the TaskTemplate's timeoutSeconds is used.
+	// Capped by the Agent's maxTimeoutSeconds, which also applies when
+	// neither the Task nor its template sets a timeout.
+	//
+	// Example:
+	//   timeoutSeconds: 3600  # 1 hour
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// RetryPolicy retries the Task when an attempt fails for a retryable reason.
+	// Each attempt runs in a fresh Pod, and attempts are recorded in status.attempts.
+	// If not specified, the Task fails on the first failed attempt.
+	//
+	// Example:
+	//   retryPolicy:
+	//     maxAttempts: 3
+	//     backoffSeconds: 10
+	// +optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// Priority orders this Task in its Agent's admission queue.
+	// When the Agent is at capacity, Queued Tasks are admitted in order of
+	// descending priority, then creation time (FIFO). Defaults to 0.
+	// Negative values are allowed for background work.
+	//
+	// Example:
+	//   priority: 100  # Admitted before Tasks with lower priority
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// SessionRef continues the OpenCode session of a previous Task instead of
+	// starting a new one, so the agent keeps the conversation history.
+	// The referenced Task must be in the same namespace and use the same
+	// Server-mode Agent. While it has not finished, this Task stays Queued
+	// with reason WaitingForSession. The Task fails with reason SessionRefError
+	// if the referenced Task has no session or its session no longer exists.
+	//
+	// Example:
+	//   sessionRef:
+	//     name: slack-thread-1234-msg-1
+	// +optional
+	SessionRef *SessionReference `json:"sessionRef,omitempty"`
+}
+
+// SessionReference refers to a Task whose OpenCode session is continued.
+type SessionReference struct {
+	// Name of the Task in the same namespace.
+	// +required
+	Name string `json:"name"`
+}
+
+// RetryPolicy defines how failed Task attempts are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	// +required
+	MaxAttempts int32 `json:"maxAttempts"`
+
+	// BackoffSeconds is the delay before the first retry.
+	// The delay doubles for each subsequent retry, up to maxBackoffSeconds.
+	// Defaults to 10 seconds.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BackoffSeconds *int32 `json:"backoffSeconds,omitempty"`
+
+	// MaxBackoffSeconds caps the exponential backoff delay.
+	// Defaults to 300 seconds.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxBackoffSeconds *int32 `json:"maxBackoffSeconds,omitempty"`
+
+	// RetryOn lists the failure reasons that trigger a retry.
+	// Defaults to infrastructure failures: Evicted, PodLost, GitInitError,
+	// URLFetchError, and PodCreationError.
+	// Add PodFailed to also retry when the agent exits with a non-zero code,
+	// OOMKilled when it exceeds its memory limit, TimedOut to retry
+	// attempts that exceeded timeoutSeconds, or GitPushError to rerun the agent
+	// when its changes could not be pushed back.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:items:Enum=PodFailed;OOMKilled;Evicted;PodLost;GitInitError;GitPushError;URLFetchError;PodCreationError;TimedOut
+	RetryOn []string `json:"retryOn,omitempty"`
+}
+
+// TaskAttempt records a single execution attempt of a Task.
+type TaskAttempt struct {
+	// Attempt is the 1-based attempt number.
+	Attempt int32 `json:"attempt"`
+
+	// PodName is the name of the Pod created for this attempt.
+	// +optional
+	PodName string `json:"podName,omitempty"`
+
+	// StartTime is when the attempt started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// EndTime is when the attempt finished. Unset while the attempt is running.
+	// +optional
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+
+	// ExitCode is the exit code of the failed container, if any.
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// Reason is a machine-readable reason for how the attempt ended
+	// (e.g., Evicted, GitInitError, PodFailed).
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human-readable description of how the attempt ended.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ResolvedContext records what a context of a Task resolved to, so the inputs of a
+// run can be audited and reproduced.
+type ResolvedContext struct {
+	// Type is the context type.
+	Type ContextType `json:"type"`
+
+	// Name is the name of the context item, if it has one.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Source is where the content came from: the repository URL (Git), the URL (URL),
+	// or the ConfigMap as namespace/name (ConfigMap). Empty for Text and Runtime contexts.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Ref is the Git reference that was requested (Git).
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Commit is the Git commit SHA that was checked out (Git).
+	// +optional
+	Commit string `json:"commit,omitempty"`
+
+	// BaseCommit is the Git commit SHA the baseRef resolved to (Git with baseRef).
+	// +optional
+	BaseCommit string `json:"baseCommit,omitempty"`
+
+	// PushedBranch is the branch the agent's changes were pushed to (Git with pushBack).
+	// Empty if there was nothing to push.
+	// +optional
+	PushedBranch string `json:"pushedBranch,omitempty"`
+
+	// PushedCommit is the Git commit SHA that was pushed (Git with pushBack).
+	// +optional
+	PushedCommit string `json:"pushedCommit,omitempty"`
+
+	// Digest is the SHA-256 digest of the content, as "sha256:<hex>" (Text, ConfigMap,
+	// Runtime and URL). For ConfigMaps without a key, it covers all keys.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+}
+
+// TaskResults is the structured result reported by the agent.
+type TaskResults struct {
+	// Outcome is the agent-reported outcome of the task.
+	// +optional
+	Outcome TaskOutcome `json:"outcome,omitempty"`
+
+	// Summary is a short, human-readable description of what the agent did.
+	// Truncated by the controller to a bounded length.
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
+	// Outputs are key/value results for downstream consumers
+	// (e.g., a pull request URL or a commit SHA).
+	// +optional
+	Outputs map[string]string `json:"outputs,omitempty"`
 }
 
 // TaskExecutionStatus defines the observed state of Task
@@ -176,6 +430,12 @@ type TaskExecutionStatus struct {
 	// +optional
 	AgentRef *AgentReference `json:"agentRef,omitempty"`
 
+	// QueuePosition is the 1-based position of this Task in its Agent's
+	// admission queue while the Task is Queued. Position 1 is admitted next.
+	// Unset when the Task is not queued.
+	// +optional
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+
 	// Kubernetes Pod name
 	// +optional
 	PodName string `json:"podName,omitempty"`
@@ -187,6 +447,19 @@ type TaskExecutionStatus struct {
 	// +optional
 	PodNamespace string `json:"podNamespace,omitempty"`
 
+	// SessionID is the ID of the OpenCode session a Server-mode Task created on
+	// its Agent's server. The controller deletes the session when the Task is
+	// stopped or deleted, and clears this field once the session is gone.
+	// +optional
+	SessionID string `json:"sessionID,omitempty"`
+
+	// ServerPod is the server replica (Pod in the Agent's namespace) a Task of a
+	// multi-replica Server-mode Agent is pinned to. The Task attaches to this
+	// replica, and its session and workspace live there.
+	// Not set for single-replica servers, whose Tasks use the Agent's Service.
+	// +optional
+	ServerPod string `json:"serverPod,omitempty"`
+
 	// Start time
 	// +optional
 	StartTime *metav1.Time `json:"startTime,omitempty"`
@@ -195,6 +468,27 @@ type TaskExecutionStatus struct {
 	// +optional
 	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 
+	// Contexts records the contexts resolved for the current attempt, Agent contexts
+	// first, with the Git commit or content digest each one resolved to. Git commits
+	// and URL digests are filled in once the Pod's init containers have finished.
+	// +optional
+	// +listType=atomic
+	Contexts []ResolvedContext `json:"contexts,omitempty"`
+
+	// Results holds the structured result reported by the agent.
+	// The agent reports results by writing ${WORKSPACE_DIR}/.kubeopencode/result.json,
+	// which is the agent container's termination message file.
+	// Only set once the Pod has terminated and the agent reported a result.
+	// Not set for Server-mode Tasks, whose agent runs on the server.
+	// +optional
+	Results *TaskResults `json:"results,omitempty"`
+
+	// Attempts records each execution attempt, oldest first.
+	// A Task without retryPolicy has at most one attempt.
+	// +optional
+	// +listType=atomic
+	Attempts []TaskAttempt `json:"attempts,omitempty"`
+
 	// Kubernetes standard conditions
 	// +optional
 	// +listType=map
@@ -265,6 +559,12 @@ type TaskTemplateSpec struct {
 	//   4. Task.description (highest, becomes ${WORKSPACE_DIR}/task.md)
 	// +optional
 	Contexts []ContextItem `json:"contexts,omitempty"`
+
+	// TimeoutSeconds is the default execution timeout for tasks using this template.
+	// Can be overridden by Task.spec.timeoutSeconds.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
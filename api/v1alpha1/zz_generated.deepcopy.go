@@ -7,8 +7,8 @@
 package v1alpha1
 
 import (
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -93,7 +93,7 @@ func (in *AgentPodSpec) DeepCopyInto(out *AgentPodSpec) {
 	}
 	if in.Resources != nil {
 		in, out := &in.Resources, &out.Resources
-		*out = new(corev1.ResourceRequirements)
+		*out = new(v1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -170,10 +170,20 @@ func (in *AgentSpec) DeepCopyInto(out *AgentSpec) {
 		*out = new(QuotaConfig)
 		**out = **in
 	}
+	if in.FairShare != nil {
+		in, out := &in.FairShare, &out.FairShare
+		*out = new(FairShareConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxTimeoutSeconds != nil {
+		in, out := &in.MaxTimeoutSeconds, &out.MaxTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	if in.ServerConfig != nil {
 		in, out := &in.ServerConfig, &out.ServerConfig
 		*out = new(ServerConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -192,7 +202,7 @@ func (in *AgentStatus) DeepCopyInto(out *AgentStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -207,7 +217,7 @@ func (in *AgentStatus) DeepCopyInto(out *AgentStatus) {
 	if in.ServerStatus != nil {
 		in, out := &in.ServerStatus, &out.ServerStatus
 		*out = new(ServerStatus)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -377,6 +387,98 @@ func (in *Credential) DeepCopy() *Credential {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FairShareConfig) DeepCopyInto(out *FairShareConfig) {
+	*out = *in
+	if in.MaxConcurrentTasksPerNamespace != nil {
+		in, out := &in.MaxConcurrentTasksPerNamespace, &out.MaxConcurrentTasksPerNamespace
+		*out = new(int32)
+		**out = **in
+	}
+	if in.QuotaPerNamespace != nil {
+		in, out := &in.QuotaPerNamespace, &out.QuotaPerNamespace
+		*out = new(QuotaConfig)
+		**out = **in
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceShare, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FairShareConfig.
+func (in *FairShareConfig) DeepCopy() *FairShareConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(FairShareConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitCacheConfig) DeepCopyInto(out *GitCacheConfig) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(GitCacheVolumeClaim)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxUnusedSeconds != nil {
+		in, out := &in.MaxUnusedSeconds, &out.MaxUnusedSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitCacheConfig.
+func (in *GitCacheConfig) DeepCopy() *GitCacheConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitCacheConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitCacheVolumeClaim) DeepCopyInto(out *GitCacheVolumeClaim) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitCacheVolumeClaim.
+func (in *GitCacheVolumeClaim) DeepCopy() *GitCacheVolumeClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(GitCacheVolumeClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitContext) DeepCopyInto(out *GitContext) {
 	*out = *in
@@ -385,11 +487,26 @@ func (in *GitContext) DeepCopyInto(out *GitContext) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.LFS != nil {
+		in, out := &in.LFS, &out.LFS
+		*out = new(GitLFS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SparseCheckout != nil {
+		in, out := &in.SparseCheckout, &out.SparseCheckout
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.SecretRef != nil {
 		in, out := &in.SecretRef, &out.SecretRef
 		*out = new(GitSecretReference)
 		**out = **in
 	}
+	if in.PushBack != nil {
+		in, out := &in.PushBack, &out.PushBack
+		*out = new(GitPushBack)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitContext.
@@ -402,6 +519,41 @@ func (in *GitContext) DeepCopy() *GitContext {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLFS) DeepCopyInto(out *GitLFS) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLFS.
+func (in *GitLFS) DeepCopy() *GitLFS {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLFS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitPushBack) DeepCopyInto(out *GitPushBack) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitPushBack.
+func (in *GitPushBack) DeepCopy() *GitPushBack {
+	if in == nil {
+		return nil
+	}
+	out := new(GitPushBack)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitSecretReference) DeepCopyInto(out *GitSecretReference) {
 	*out = *in
@@ -488,6 +640,11 @@ func (in *KubeOpenCodeConfigSpec) DeepCopyInto(out *KubeOpenCodeConfigSpec) {
 		*out = new(CleanupConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.GitCache != nil {
+		in, out := &in.GitCache, &out.GitCache
+		*out = new(GitCacheConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeOpenCodeConfigSpec.
@@ -500,6 +657,31 @@ func (in *KubeOpenCodeConfigSpec) DeepCopy() *KubeOpenCodeConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceShare) DeepCopyInto(out *NamespaceShare) {
+	*out = *in
+	if in.MaxConcurrentTasks != nil {
+		in, out := &in.MaxConcurrentTasks, &out.MaxConcurrentTasks
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(QuotaConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceShare.
+func (in *NamespaceShare) DeepCopy() *NamespaceShare {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceShare)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodScheduling) DeepCopyInto(out *PodScheduling) {
 	*out = *in
@@ -512,14 +694,14 @@ func (in *PodScheduling) DeepCopyInto(out *PodScheduling) {
 	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]corev1.Toleration, len(*in))
+		*out = make([]v1.Toleration, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
-		*out = new(corev1.Affinity)
+		*out = new(v1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -549,6 +731,51 @@ func (in *QuotaConfig) DeepCopy() *QuotaConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedContext) DeepCopyInto(out *ResolvedContext) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedContext.
+func (in *ResolvedContext) DeepCopy() *ResolvedContext {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.BackoffSeconds != nil {
+		in, out := &in.BackoffSeconds, &out.BackoffSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxBackoffSeconds != nil {
+		in, out := &in.MaxBackoffSeconds, &out.MaxBackoffSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RetryOn != nil {
+		in, out := &in.RetryOn, &out.RetryOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuntimeContext) DeepCopyInto(out *RuntimeContext) {
 	*out = *in
@@ -587,6 +814,31 @@ func (in *SecretReference) DeepCopy() *SecretReference {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServerConfig) DeepCopyInto(out *ServerConfig) {
 	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Workspace != nil {
+		in, out := &in.Workspace, &out.Workspace
+		*out = new(ServerWorkspace)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IdleTimeoutSeconds != nil {
+		in, out := &in.IdleTimeoutSeconds, &out.IdleTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReadyTimeoutSeconds != nil {
+		in, out := &in.ReadyTimeoutSeconds, &out.ReadyTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DrainTimeoutSeconds != nil {
+		in, out := &in.DrainTimeoutSeconds, &out.DrainTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerConfig.
@@ -602,6 +854,18 @@ func (in *ServerConfig) DeepCopy() *ServerConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServerStatus) DeepCopyInto(out *ServerStatus) {
 	*out = *in
+	if in.LastActiveTime != nil {
+		in, out := &in.LastActiveTime, &out.LastActiveTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OldestActiveSessionTime != nil {
+		in, out := &in.OldestActiveSessionTime, &out.OldestActiveSessionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastActivityTime != nil {
+		in, out := &in.LastActivityTime, &out.LastActivityTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerStatus.
@@ -614,6 +878,51 @@ func (in *ServerStatus) DeepCopy() *ServerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerWorkspace) DeepCopyInto(out *ServerWorkspace) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerWorkspace.
+func (in *ServerWorkspace) DeepCopy() *ServerWorkspace {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerWorkspace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionReference) DeepCopyInto(out *SessionReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionReference.
+func (in *SessionReference) DeepCopy() *SessionReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SystemImageConfig) DeepCopyInto(out *SystemImageConfig) {
 	*out = *in
@@ -656,6 +965,34 @@ func (in *Task) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskAttempt) DeepCopyInto(out *TaskAttempt) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskAttempt.
+func (in *TaskAttempt) DeepCopy() *TaskAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskExecutionStatus) DeepCopyInto(out *TaskExecutionStatus) {
 	*out = *in
@@ -672,9 +1009,26 @@ func (in *TaskExecutionStatus) DeepCopyInto(out *TaskExecutionStatus) {
 		in, out := &in.CompletionTime, &out.CompletionTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Contexts != nil {
+		in, out := &in.Contexts, &out.Contexts
+		*out = make([]ResolvedContext, len(*in))
+		copy(*out, *in)
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = new(TaskResults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]TaskAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -723,6 +1077,28 @@ func (in *TaskList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskResults) DeepCopyInto(out *TaskResults) {
+	*out = *in
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskResults.
+func (in *TaskResults) DeepCopy() *TaskResults {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskResults)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskSpec) DeepCopyInto(out *TaskSpec) {
 	*out = *in
@@ -748,6 +1124,21 @@ func (in *TaskSpec) DeepCopyInto(out *TaskSpec) {
 		*out = new(AgentReference)
 		**out = **in
 	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SessionRef != nil {
+		in, out := &in.SessionRef, &out.SessionRef
+		*out = new(SessionReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSpec.
@@ -869,6 +1260,11 @@ func (in *TaskTemplateSpec) DeepCopyInto(out *TaskTemplateSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskTemplateSpec.
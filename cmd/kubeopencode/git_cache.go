@@ -0,0 +1,233 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Environment variable names for the Git mirror cache of git-init
+const (
+	envCacheDir       = "GIT_CACHE_DIR"
+	envCacheMaxSize   = "GIT_CACHE_MAX_SIZE"
+	envCacheMaxUnused = "GIT_CACHE_MAX_UNUSED_SECONDS"
+)
+
+// defaultCacheMaxUnused is how long a mirror is kept without being cloned from (7 days).
+const defaultCacheMaxUnused = 7 * 24 * 60 * 60
+
+// mirrorUsedFile is touched in a mirror whenever it is cloned from; its modification
+// time decides which mirrors are evicted.
+const mirrorUsedFile = "kubeopencode-last-used"
+
+// gitMirror is a locked bare mirror of a repository in the Git mirror cache.
+type gitMirror struct {
+	dir  string
+	lock *os.File
+	// hit reports whether the mirror existed before this clone
+	hit bool
+	// fetchedBytes is how much the mirror grew when it was brought up to date
+	fetchedBytes int64
+}
+
+// mirrorDir returns the directory of the mirror of repo in cacheDir. Mirrors are keyed
+// by the repository URL, ignoring a trailing slash or .git suffix.
+func mirrorDir(cacheDir, repo string) string {
+	key := strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:16])+".git")
+}
+
+// openMirror brings the mirror of repo in cacheDir up to date with the branches and
+// tags of the remote, creating it if needed. Only the objects missing from the mirror
+// are fetched. The mirror is returned locked for reading, so it is not evicted while
+// the clone uses it; Close releases it.
+func openMirror(cacheDir, repo string) (*gitMirror, error) {
+	m := &gitMirror{dir: mirrorDir(cacheDir, repo)}
+
+	if err := os.MkdirAll(cacheDir, 0777); err != nil { //nolint:gosec // Shared by git-init containers of any UID
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Mirrors are updated by one git-init at a time, and read by many
+	lock, err := os.OpenFile(m.dir+".lock", os.O_CREATE|os.O_RDWR, 0666) //nolint:gosec // Lock files are shared by git-init containers of any UID
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mirror lock: %w", err)
+	}
+	m.lock = lock
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		m.Close()
+		return nil, fmt.Errorf("failed to lock mirror: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(m.dir, "HEAD")); err == nil {
+		m.hit = true
+	} else if err := os.RemoveAll(m.dir); err != nil {
+		// A mirror whose creation was interrupted
+		m.Close()
+		return nil, fmt.Errorf("failed to remove incomplete mirror: %w", err)
+	}
+
+	sizeBefore := dirSize(m.dir)
+	if err := updateMirror(m.dir, repo, m.hit); err != nil {
+		if !m.hit {
+			_ = os.RemoveAll(m.dir)
+		}
+		m.Close()
+		return nil, err
+	}
+	m.fetchedBytes = max(0, dirSize(m.dir)-sizeBefore)
+
+	// Let git-init containers running as other UIDs update the mirror as well
+	chmodCmd := exec.Command("chmod", "-R", "a+rwX", m.dir) //nolint:gosec // m.dir is derived from controlled env vars
+	if err := chmodCmd.Run(); err != nil {
+		fmt.Printf("git-init: Warning: could not set mirror permissions: %v\n", err)
+	}
+	now := time.Now()
+	if err := os.WriteFile(filepath.Join(m.dir, mirrorUsedFile), nil, 0666); err == nil { //nolint:gosec // Shared by git-init containers of any UID
+		_ = os.Chtimes(filepath.Join(m.dir, mirrorUsedFile), now, now)
+	}
+
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_SH); err != nil {
+		m.Close()
+		return nil, fmt.Errorf("failed to lock mirror: %w", err)
+	}
+	return m, nil
+}
+
+// updateMirror fetches the branches and tags of repo into the bare repository in dir,
+// creating it if exists is false.
+func updateMirror(dir, repo string, exists bool) error {
+	var steps [][]string
+	if exists {
+		steps = append(steps, []string{"remote", "set-url", "origin", repo})
+	} else {
+		if err := os.MkdirAll(dir, 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
+			return fmt.Errorf("failed to create mirror directory: %w", err)
+		}
+		steps = append(steps,
+			[]string{"init", "--quiet", "--bare"},
+			[]string{"remote", "add", "origin", repo},
+		)
+	}
+	steps = append(steps, []string{"fetch", "--quiet", "--prune", "origin", "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"})
+	return runGitSteps(dir, steps)
+}
+
+// Close releases the lock of the mirror.
+func (m *gitMirror) Close() {
+	if m.lock != nil {
+		_ = m.lock.Close()
+		m.lock = nil
+	}
+}
+
+// borrowObjects makes the repository in targetDir use the objects of the mirror, so
+// fetches into it only transfer the objects missing from the mirror.
+func (m *gitMirror) borrowObjects(targetDir string) error {
+	alternates := filepath.Join(targetDir, ".git", "objects", "info", "alternates")
+	if err := os.MkdirAll(filepath.Dir(alternates), 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
+		return fmt.Errorf("failed to create objects directory: %w", err)
+	}
+	if err := os.WriteFile(alternates, []byte(filepath.Join(m.dir, "objects")+"\n"), 0644); err != nil { //nolint:gosec // Part of the repository, readable by the agent
+		return fmt.Errorf("failed to write alternates: %w", err)
+	}
+	return nil
+}
+
+// dissociate copies the objects the repository in targetDir borrows from the mirror
+// into the repository itself, so that it no longer needs the mirror: the agent
+// container does not mount the cache. Returns the size of the repository's objects.
+func dissociate(targetDir string) (int64, error) {
+	if err := runGitSteps(targetDir, [][]string{{"repack", "-a", "-d", "-q"}}); err != nil {
+		return 0, err
+	}
+	if err := os.Remove(filepath.Join(targetDir, ".git", "objects", "info", "alternates")); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to remove alternates: %w", err)
+	}
+	return dirSize(filepath.Join(targetDir, ".git", "objects")), nil
+}
+
+// evictMirrors removes the mirrors in cacheDir that have not been used for maxUnused,
+// and then the least recently used mirrors while the cache is larger than maxSize
+// (if positive). Mirrors in use are skipped.
+func evictMirrors(cacheDir string, maxSize int64, maxUnused time.Duration) {
+	dirs, err := filepath.Glob(filepath.Join(cacheDir, "*.git"))
+	if err != nil {
+		return
+	}
+
+	type mirror struct {
+		dir      string
+		lastUsed time.Time
+		size     int64
+	}
+	mirrors := make([]mirror, 0, len(dirs))
+	var total int64
+	for _, dir := range dirs {
+		info, err := os.Stat(filepath.Join(dir, mirrorUsedFile))
+		if err != nil {
+			if info, err = os.Stat(dir); err != nil {
+				continue
+			}
+		}
+		m := mirror{dir: dir, lastUsed: info.ModTime(), size: dirSize(dir)}
+		mirrors = append(mirrors, m)
+		total += m.size
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].lastUsed.Before(mirrors[j].lastUsed) })
+
+	for _, m := range mirrors {
+		expired := time.Since(m.lastUsed) > maxUnused
+		if !expired && (maxSize <= 0 || total <= maxSize) {
+			break
+		}
+		if evictMirror(m.dir) {
+			fmt.Printf("git-init: Evicted mirror %s (%d bytes, last used %s)\n", filepath.Base(m.dir), m.size, m.lastUsed.Format(time.RFC3339))
+			total -= m.size
+		}
+	}
+}
+
+// evictMirror removes the mirror in dir unless it is in use. Returns whether it was
+// removed.
+func evictMirror(dir string) bool {
+	lock, err := os.OpenFile(dir+".lock", os.O_CREATE|os.O_RDWR, 0666) //nolint:gosec // Lock files are shared by git-init containers of any UID
+	if err != nil {
+		return false
+	}
+	defer func() { _ = lock.Close() }()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return false
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		fmt.Printf("git-init: Warning: could not evict mirror %s: %v\n", dir, err)
+		return false
+	}
+	return true
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -3,12 +3,18 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -17,7 +23,12 @@ import (
 const (
 	envRepo        = "GIT_REPO"
 	envRef         = "GIT_REF"
+	envBaseRef     = "GIT_BASE_REF"
 	envDepth       = "GIT_DEPTH"
+	envSubmodules  = "GIT_SUBMODULES"
+	envLFS         = "GIT_LFS"
+	envLFSInclude  = "GIT_LFS_INCLUDE"
+	envSparse      = "GIT_SPARSE_CHECKOUT"
 	envRoot        = "GIT_ROOT"
 	envLink        = "GIT_LINK"
 	envUsername    = "GIT_USERNAME"
@@ -34,6 +45,23 @@ const (
 	defaultLink  = "repo"
 )
 
+// baseRefName is the local ref GIT_BASE_REF is fetched to.
+const baseRefName = "refs/kubeopencode/base"
+
+// commitSHAPattern matches full SHA-1 and SHA-256 commit IDs.
+var commitSHAPattern = regexp.MustCompile(`^([0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`)
+
+// gitInitResult is written to the termination message of git-init, so that the
+// controller can tell which commits were checked out.
+type gitInitResult struct {
+	Commit     string `json:"commit"`
+	BaseCommit string `json:"baseCommit,omitempty"`
+	// Cache is "hit" or "miss" when the clone used the Git mirror cache
+	Cache string `json:"cache,omitempty"`
+	// CacheBytesSaved estimates the bytes cloned from the cache instead of the remote
+	CacheBytesSaved int64 `json:"cacheBytesSaved,omitempty"`
+}
+
 func init() {
 	rootCmd.AddCommand(gitInitCmd)
 }
@@ -45,24 +73,50 @@ var gitInitCmd = &cobra.Command{
 
 It supports:
   - Shallow clones (configurable depth)
-  - Branch/tag/commit reference
+  - Branch/tag reference (cloned directly)
+  - Commit SHA, ref (e.g., refs/pull/123/head) or refspec (fetched and checked out)
+  - A base reference fetched to refs/kubeopencode/base, e.g. for pull request diffs
   - HTTPS authentication (username/password)
   - SSH authentication (private key)
+  - Refreshing an existing clone in place (fetch and reset), e.g. on a persistent volume
+  - A shared cache of bare mirrors, so only objects missing from the mirror are fetched
+  - Submodules, Git LFS objects, and sparse checkouts of selected directories
 
 Environment variables:
   GIT_REPO            Repository URL (required)
-  GIT_REF             Git reference (branch/tag/commit), default: HEAD
+  GIT_REF             Git reference (branch/tag/full commit SHA/ref/refspec), default: HEAD
+  GIT_BASE_REF        Base reference (branch/tag/full commit SHA/ref) to fetch alongside GIT_REF
   GIT_DEPTH           Clone depth, default: 1
+  GIT_SUBMODULES      Submodules to check out at depth 1: "shallow" (top-level) or "recursive"
+  GIT_LFS             Set to "true" to fetch Git LFS objects
+  GIT_LFS_INCLUDE     Comma-separated patterns of the LFS files to fetch, default: all
+  GIT_SPARSE_CHECKOUT Newline-separated directories to check out (cone mode), default: all
   GIT_ROOT            Root directory for clone, default: /git
   GIT_LINK            Subdirectory name, default: repo
   GIT_USERNAME        HTTPS username
   GIT_PASSWORD        HTTPS password/token
   GIT_SSH_KEY         SSH private key (content or file path)
-  GIT_SSH_KNOWN_HOSTS Known hosts content for SSH verification`,
+  GIT_SSH_KNOWN_HOSTS Known hosts content for SSH verification
+  GIT_CACHE_DIR       Directory of the Git mirror cache (cache disabled if unset)
+  GIT_CACHE_MAX_SIZE  Cache size in bytes above which least recently used mirrors are evicted
+  GIT_CACHE_MAX_UNUSED_SECONDS
+                      Age of unused mirrors to evict, default: 604800 (7 days)`,
 	RunE: runGitInit,
 }
 
 func runGitInit(cmd *cobra.Command, args []string) error {
+	if err := initRepository(); err != nil {
+		// Best effort: record the failure reason for the Task status
+		msg := fmt.Sprintf("failed to clone %s: %v", os.Getenv(envRepo), err)
+		_ = os.WriteFile(terminationLogPath, []byte(msg), 0644) //nolint:gosec // Termination log must be readable by the kubelet
+		return err
+	}
+	return nil
+}
+
+// initRepository clones or refreshes the repository configured by the environment
+// variables and records the checked out commit in the termination log.
+func initRepository() error {
 	// Get required environment variable
 	repo := os.Getenv(envRepo)
 	if repo == "" {
@@ -76,9 +130,20 @@ func runGitInit(cmd *cobra.Command, args []string) error {
 
 	// Get optional environment variables with defaults
 	ref := getEnvOrDefault(envRef, defaultRef)
+	baseRef := os.Getenv(envBaseRef)
 	depth := getEnvIntOrDefault(envDepth, defaultDepth)
 	root := getEnvOrDefault(envRoot, defaultRoot)
 	link := getEnvOrDefault(envLink, defaultLink)
+	submodules := os.Getenv(envSubmodules)
+	lfs := os.Getenv(envLFS) == "true"
+	lfsInclude := os.Getenv(envLFSInclude)
+	sparse := splitLines(os.Getenv(envSparse))
+
+	switch submodules {
+	case "", "none", "shallow", "recursive":
+	default:
+		return fmt.Errorf("invalid %s %q: must be none, shallow or recursive", envSubmodules, submodules)
+	}
 
 	// Target directory
 	targetDir := filepath.Join(root, link)
@@ -86,7 +151,13 @@ func runGitInit(cmd *cobra.Command, args []string) error {
 	fmt.Println("git-init: Cloning repository...")
 	fmt.Printf("  Repository: %s\n", repo)
 	fmt.Printf("  Ref: %s\n", ref)
+	if baseRef != "" {
+		fmt.Printf("  Base ref: %s\n", baseRef)
+	}
 	fmt.Printf("  Depth: %d\n", depth)
+	if len(sparse) > 0 {
+		fmt.Printf("  Sparse checkout: %s\n", strings.Join(sparse, ", "))
+	}
 	fmt.Printf("  Target: %s\n", targetDir)
 
 	// Setup authentication
@@ -102,23 +173,125 @@ func runGitInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create root directory: %w", err)
 	}
 
-	// Build git clone command
-	cloneArgs := []string{"clone", "--depth", strconv.Itoa(depth), "--single-branch"}
+	// Server replicas sharing a persistent workspace clone into the same target, so
+	// only one git-init at a time may clone or refresh it
+	lock, err := lockTarget(targetDir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Close() }()
+
+	// The target may already hold a clone from a previous run when it lives on a
+	// persistent volume (Server-mode Agents with a persistent workspace).
+	// Refresh it in place instead of cloning again.
+	refreshed := false
+	if _, err := os.Stat(filepath.Join(targetDir, ".git")); err == nil {
+		fmt.Println("git-init: Refreshing existing repository...")
+		if err := refreshRepository(repo, ref, depth, targetDir, sparse); err != nil {
+			fmt.Printf("git-init: Warning: could not refresh existing repository, cloning again: %v\n", err)
+			if err := os.RemoveAll(targetDir); err != nil {
+				return fmt.Errorf("failed to remove existing repository: %w", err)
+			}
+		} else {
+			refreshed = true
+		}
+	}
+
+	// Borrow the objects of the repository's mirror in the Git cache, if enabled.
+	// The cache only speeds up the clone, so it is skipped when it cannot be used.
+	var mirror *gitMirror
+	cacheDir := os.Getenv(envCacheDir)
+	if cacheDir != "" && !refreshed {
+		fmt.Println("git-init: Updating mirror in the Git cache...")
+		m, err := openMirror(cacheDir, repo)
+		if err != nil {
+			fmt.Printf("git-init: Warning: not using the Git cache: %v\n", err)
+		} else {
+			mirror = m
+			defer mirror.Close()
+		}
+	}
+
+	if !refreshed {
+		if isFetchRef(ref) {
+			// git clone --branch only takes branch and tag names
+			fmt.Println("git-init: Fetching ref into a new repository...")
+			if err := fetchRepository(repo, ref, depth, targetDir, mirror, sparse); err != nil {
+				return err
+			}
+		} else {
+			// Build git clone command
+			cloneArgs := []string{"clone", "--depth", strconv.Itoa(depth), "--single-branch"}
+
+			// Add branch flag if not HEAD
+			if ref != "HEAD" {
+				cloneArgs = append(cloneArgs, "--branch", ref)
+			}
+
+			if mirror != nil {
+				cloneArgs = append(cloneArgs, "--reference", mirror.dir)
+			}
+
+			// Only check out the files at the root until the sparse directories are set
+			if len(sparse) > 0 {
+				cloneArgs = append(cloneArgs, "--sparse")
+				if mirror == nil {
+					cloneArgs = append(cloneArgs, sparseFilter)
+				}
+			}
+
+			cloneArgs = append(cloneArgs, repo, targetDir)
+
+			// Execute git clone
+			cloneCmd := exec.Command("git", cloneArgs...) //nolint:gosec // args are constructed from controlled inputs
+			if err := runGit(cloneCmd); err != nil {
+				return fmt.Errorf("git clone failed: %w", err)
+			}
 
-	// Add branch flag if not HEAD
-	if ref != "HEAD" {
-		cloneArgs = append(cloneArgs, "--branch", ref)
+			if len(sparse) > 0 {
+				if err := runGitSteps(targetDir, [][]string{sparseCheckoutArgs(sparse)}); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
-	cloneArgs = append(cloneArgs, repo, targetDir)
+	if baseRef != "" {
+		fmt.Printf("git-init: Fetching base ref to %s...\n", baseRefName)
+		if err := fetchBaseRef(baseRef, depth, targetDir); err != nil {
+			return err
+		}
+	}
 
-	// Execute git clone
-	cloneCmd := exec.Command("git", cloneArgs...) //nolint:gosec // args are constructed from controlled inputs
-	cloneCmd.Stdout = os.Stdout
-	cloneCmd.Stderr = os.Stderr
+	// The agent container does not mount the cache: copy the borrowed objects
+	var result gitInitResult
+	if mirror != nil {
+		size, err := dissociate(targetDir)
+		if err != nil {
+			return fmt.Errorf("failed to dissociate from the Git cache: %w", err)
+		}
+		result.Cache = "miss"
+		if mirror.hit {
+			result.Cache = "hit"
+			result.CacheBytesSaved = max(0, size-mirror.fetchedBytes)
+		}
+		fmt.Printf("git-init: Git cache %s\n", result.Cache)
+		maxUnused := time.Duration(getEnvIntOrDefault(envCacheMaxUnused, defaultCacheMaxUnused)) * time.Second
+		evictMirrors(cacheDir, int64(getEnvIntOrDefault(envCacheMaxSize, 0)), maxUnused)
+	}
 
-	if err := cloneCmd.Run(); err != nil {
-		return fmt.Errorf("git clone failed: %w", err)
+	if submodules == "shallow" || submodules == "recursive" {
+		fmt.Printf("git-init: Checking out submodules (%s)...\n", submodules)
+		if err := updateSubmodules(targetDir, submodules == "recursive"); err != nil {
+			return err
+		}
+	}
+
+	if lfs {
+		fmt.Println("git-init: Fetching Git LFS objects...")
+		if err := pullLFS(targetDir, lfsInclude); err != nil {
+			return err
+		}
 	}
 
 	// Verify clone was successful
@@ -145,14 +318,25 @@ func runGitInit(cmd *cobra.Command, args []string) error {
 		fmt.Printf("git-init: Set write permissions for all users on %s\n", targetDir)
 	}
 
-	// Get and print commit hash
-	commitCmd := exec.Command("git", "-C", targetDir, "rev-parse", "HEAD") //nolint:gosec // targetDir is constructed from controlled inputs
-	commitOutput, err := commitCmd.Output()
+	// Get and report the commit hash
+	commit, err := revParse(targetDir, "HEAD")
 	if err != nil {
 		fmt.Println("git-init: Clone successful! (could not get commit hash)")
 	} else {
+		result.Commit = commit
 		fmt.Printf("git-init: Clone successful!\n")
-		fmt.Printf("  Commit: %s\n", strings.TrimSpace(string(commitOutput)))
+		fmt.Printf("  Commit: %s\n", commit)
+	}
+	if baseRef != "" {
+		if result.BaseCommit, err = revParse(targetDir, baseRefName); err == nil {
+			fmt.Printf("  Base commit: %s\n", result.BaseCommit)
+		}
+	}
+	if result.Commit != "" {
+		// Best effort: the Pod still starts without it
+		if data, err := json.Marshal(result); err == nil {
+			_ = os.WriteFile(terminationLogPath, data, 0644) //nolint:gosec // Termination log must be readable by the kubelet
+		}
 	}
 
 	// Clean up credentials file after successful clone
@@ -161,6 +345,174 @@ func runGitInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// refreshRepository updates an existing clone in targetDir to the latest commit of ref:
+// it fetches ref from repo and hard-resets the working tree to it. Untracked files are
+// removed, but ignored files (build outputs, dependency caches) are kept. The sparse
+// checkout directories are updated to sparse, or sparse checkout is disabled if empty.
+func refreshRepository(repo, ref string, depth int, targetDir string, sparse []string) error {
+	steps := [][]string{
+		{"remote", "set-url", "origin", repo},
+		{"fetch", "--depth", strconv.Itoa(depth), "origin", ref},
+		{"reset", "--hard", "FETCH_HEAD"},
+	}
+	if len(sparse) > 0 {
+		steps = append(steps, sparseCheckoutArgs(sparse))
+	} else if isSparseCheckout(targetDir) {
+		steps = append(steps, []string{"sparse-checkout", "disable"})
+	}
+	steps = append(steps, []string{"clean", "-fd"})
+	return runGitSteps(targetDir, steps)
+}
+
+// lockTarget takes an exclusive lock on the clone in targetDir, which is held until
+// the returned file is closed.
+func lockTarget(targetDir string) (*os.File, error) {
+	lock, err := os.OpenFile(targetDir+".lock", os.O_CREATE|os.O_RDWR, 0666) //nolint:gosec // Lock files are shared by git-init containers of any UID
+	if err != nil {
+		return nil, fmt.Errorf("failed to open clone lock: %w", err)
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		_ = lock.Close()
+		return nil, fmt.Errorf("failed to lock clone: %w", err)
+	}
+	return lock, nil
+}
+
+// isFetchRef reports whether ref has to be fetched rather than cloned: a full commit
+// SHA, a ref such as refs/pull/123/head, or a refspec such as
+// +refs/pull/123/head:refs/remotes/pr/123. Abbreviated SHAs cannot be fetched from a
+// remote, so they are not supported: they are cloned as branch or tag names.
+func isFetchRef(ref string) bool {
+	return commitSHAPattern.MatchString(ref) ||
+		strings.HasPrefix(strings.TrimPrefix(ref, "+"), "refs/") ||
+		strings.Contains(ref, ":")
+}
+
+// fetchRepository creates a repository in targetDir, fetches ref from repo, and
+// checks it out as a detached HEAD. If mirror is not nil, the repository borrows its
+// objects. If sparse is not empty, only those directories are checked out.
+func fetchRepository(repo, ref string, depth int, targetDir string, mirror *gitMirror, sparse []string) error {
+	if err := os.MkdirAll(targetDir, 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
+		return fmt.Errorf("failed to create target directory: %w", err)
+	}
+	if err := runGitSteps(targetDir, [][]string{{"init", "--quiet"}}); err != nil {
+		return err
+	}
+	if mirror != nil {
+		if err := mirror.borrowObjects(targetDir); err != nil {
+			return err
+		}
+	}
+	fetchArgs := []string{"fetch", "--depth", strconv.Itoa(depth)}
+	if len(sparse) > 0 && mirror == nil {
+		fetchArgs = append(fetchArgs, sparseFilter)
+	}
+	steps := [][]string{
+		{"remote", "add", "origin", repo},
+		append(fetchArgs, "origin", ref),
+	}
+	if len(sparse) > 0 {
+		// Set before the checkout, so only the sparse directories are materialized
+		steps = append(steps, sparseCheckoutArgs(sparse))
+	}
+	steps = append(steps, []string{"checkout", "--quiet", "--detach", "FETCH_HEAD"})
+	return runGitSteps(targetDir, steps)
+}
+
+// sparseFilter makes a sparse clone skip the file contents outside of the sparse
+// checkout directories; Git fetches them on demand if they are needed later. It is not
+// used with the Git cache, whose mirrors hold all file contents.
+const sparseFilter = "--filter=blob:none"
+
+// sparseCheckoutArgs returns the git arguments restricting the working tree of a
+// repository to the directories in sparse.
+func sparseCheckoutArgs(sparse []string) []string {
+	return append([]string{"sparse-checkout", "set", "--cone"}, sparse...)
+}
+
+// isSparseCheckout reports whether sparse checkout is enabled in the repository in dir.
+func isSparseCheckout(dir string) bool {
+	cmd := exec.Command("git", "-c", "safe.directory=*", "-C", dir, "config", "--bool", "core.sparseCheckout") //nolint:gosec // args are constructed from controlled inputs
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// updateSubmodules checks out the submodules of the repository in targetDir at depth 1,
+// including nested submodules if recursive is true.
+func updateSubmodules(targetDir string, recursive bool) error {
+	args := []string{"submodule", "update", "--init", "--depth", "1"}
+	if recursive {
+		args = append(args, "--recursive")
+	}
+	return runGitSteps(targetDir, [][]string{args})
+}
+
+// pullLFS fetches the Git LFS objects of the checked out commit in targetDir and
+// replaces the pointer files with them, limited to the comma-separated include
+// patterns if not empty. The LFS filters are configured in the repository, so that
+// the agent commits LFS files as pointers.
+func pullLFS(targetDir, include string) error {
+	pullArgs := []string{"lfs", "pull"}
+	if include != "" {
+		pullArgs = append(pullArgs, "--include", include)
+	}
+	return runGitSteps(targetDir, [][]string{
+		{"lfs", "install", "--local"},
+		pullArgs,
+	})
+}
+
+// fetchBaseRef fetches baseRef from origin to baseRefName.
+func fetchBaseRef(baseRef string, depth int, targetDir string) error {
+	if strings.Contains(baseRef, ":") {
+		return fmt.Errorf("invalid %s %q: refspecs are not supported", envBaseRef, baseRef)
+	}
+	refspec := "+" + strings.TrimPrefix(baseRef, "+") + ":" + baseRefName
+	return runGitSteps(targetDir, [][]string{
+		{"fetch", "--depth", strconv.Itoa(depth), "origin", refspec},
+	})
+}
+
+// revParse returns the commit SHA rev resolves to in the repository in dir.
+func revParse(dir, rev string) (string, error) {
+	cmd := exec.Command("git", "-c", "safe.directory=*", "-C", dir, "rev-parse", rev) //nolint:gosec // args are constructed from controlled inputs
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runGitSteps runs git commands in the repository in targetDir.
+func runGitSteps(targetDir string, steps [][]string) error {
+	for _, step := range steps {
+		// The clone may be owned by the UID of a previous server Pod
+		args := append([]string{"-c", "safe.directory=*", "-C", targetDir}, step...)
+		cmd := exec.Command("git", args...) //nolint:gosec // args are constructed from controlled inputs
+		if err := runGit(cmd); err != nil {
+			return fmt.Errorf("git %s failed: %w", step[0], err)
+		}
+	}
+	return nil
+}
+
+// runGit runs a git command, passing its output through to the container log. On
+// failure, the error is the last line git wrote to stderr (e.g. "fatal: Remote branch
+// main not found in upstream origin"), or the exit status if it wrote none.
+func runGit(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if err := cmd.Run(); err != nil {
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+			return fmt.Errorf("%s", last)
+		}
+		return err
+	}
+	return nil
+}
+
 func cleanupCredentials() {
 	username := os.Getenv(envUsername)
 	password := os.Getenv(envPassword)
@@ -279,6 +631,17 @@ func extractHost(repoURL string) string {
 	return url
 }
 
+// splitLines returns the non-empty, trimmed lines of s.
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
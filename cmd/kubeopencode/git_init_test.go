@@ -0,0 +1,150 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestIsFetchRef(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		want bool
+	}{
+		{name: "branch", ref: "main"},
+		{name: "branch with slash", ref: "feature/retry"},
+		{name: "tag", ref: "v1.2.3"},
+		{name: "HEAD", ref: "HEAD"},
+		{name: "SHA-1", ref: "0123456789abcdef0123456789abcdef01234567", want: true},
+		{name: "SHA-256", ref: strings.Repeat("0123456789ABCDEF", 4), want: true},
+		// Abbreviated SHAs are not supported and are cloned like branch names
+		{name: "short SHA", ref: "0123456"},
+		{name: "SHA of invalid length", ref: "0123456789abcdef0123456789abcdef012345678"},
+		{name: "pull request ref", ref: "refs/pull/123/head", want: true},
+		{name: "forced ref", ref: "+refs/heads/main", want: true},
+		{name: "refspec", ref: "+refs/pull/123/head:refs/remotes/pr/123", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFetchRef(tt.ref); got != tt.want {
+				t.Errorf("isFetchRef(%q) = %v, want %v", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchBaseRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", gitPushAuthorName)
+	t.Setenv("GIT_AUTHOR_EMAIL", gitPushAuthorEmail)
+	t.Setenv("GIT_COMMITTER_NAME", gitPushAuthorName)
+	t.Setenv("GIT_COMMITTER_EMAIL", gitPushAuthorEmail)
+
+	// A remote with a branch, a tag, a pull request ref, and an unreferenced commit
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote")
+	git(t, dir, "init", "--quiet", remote)
+	commit := func(message string) string {
+		git(t, remote, "commit", "--quiet", "--allow-empty", "-m", message)
+		return git(t, remote, "rev-parse", "HEAD")
+	}
+	first := commit("First")
+	git(t, remote, "tag", "v1")
+	second := commit("Second")
+	git(t, remote, "branch", "-M", "main")
+	git(t, remote, "checkout", "--quiet", "-b", "pr")
+	pull := commit("Pull request")
+	git(t, remote, "update-ref", "refs/pull/1/head", pull)
+	git(t, remote, "checkout", "--quiet", "main")
+	git(t, remote, "branch", "-D", "pr")
+
+	tests := []struct {
+		name    string
+		baseRef string
+		want    string
+		wantErr bool
+	}{
+		{name: "branch", baseRef: "main", want: second},
+		{name: "tag", baseRef: "v1", want: first},
+		{name: "HEAD", baseRef: "HEAD", want: second},
+		{name: "full SHA", baseRef: first, want: first},
+		{name: "pull request ref", baseRef: "refs/pull/1/head", want: pull},
+		{name: "forced ref", baseRef: "+refs/heads/main", want: second},
+		{name: "refspec", baseRef: "+refs/pull/1/head:refs/remotes/pr/1", wantErr: true},
+		{name: "short SHA", baseRef: first[:7], wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "repo")
+			git(t, filepath.Dir(target), "init", "--quiet", target)
+			git(t, target, "remote", "add", "origin", remote)
+
+			err := fetchBaseRef(tt.baseRef, 1, target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchBaseRef(%q) error = %v, wantErr %v", tt.baseRef, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, err := revParse(target, baseRefName); err != nil || got != tt.want {
+				t.Errorf("%s = %q (%v), want %q", baseRefName, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunGitSteps_Error(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote")
+	git(t, dir, "init", "--quiet", remote)
+	target := filepath.Join(dir, "repo")
+	git(t, dir, "init", "--quiet", target)
+	git(t, target, "remote", "add", "origin", remote)
+
+	// The error is git's message, which git-init reports as the termination message
+	err := runGitSteps(target, [][]string{{"fetch", "origin", "missing"}})
+	if err == nil {
+		t.Fatal("runGitSteps() error = nil, want an error for the missing ref")
+	}
+	if got := err.Error(); !strings.HasPrefix(got, "git fetch failed: fatal: ") || !strings.Contains(got, "missing") {
+		t.Errorf("runGitSteps() error = %q, want the git error", got)
+	}
+}
+
+func TestLockTarget(t *testing.T) {
+	targetDir := filepath.Join(t.TempDir(), "repo")
+	lock, err := lockTarget(targetDir)
+	if err != nil {
+		t.Fatalf("lockTarget() error = %v", err)
+	}
+
+	// A second git-init, e.g. of another server replica, has to wait
+	other, err := os.OpenFile(targetDir+".lock", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = other.Close() }()
+	if err := syscall.Flock(int(other.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == nil {
+		t.Fatal("clone lock acquired twice")
+	}
+
+	_ = lock.Close()
+	if err := syscall.Flock(int(other.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Errorf("clone lock not released: %v", err)
+	}
+}
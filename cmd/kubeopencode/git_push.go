@@ -0,0 +1,208 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// Environment variable names for git-push
+const (
+	envPushBranch    = "GIT_PUSH_BRANCH"
+	envPushMessage   = "GIT_PUSH_MESSAGE"
+	envPushForce     = "GIT_PUSH_FORCE"
+	envAgentExitCode = "AGENT_EXIT_CODE_FILE"
+)
+
+// Identity of the commits git-push creates
+const (
+	gitPushAuthorName  = "KubeOpenCode"
+	gitPushAuthorEmail = "kubeopencode@users.noreply.github.com"
+)
+
+// gitPushResult is written to the termination message of git-push when it pushed
+// changes, so that the controller can record them in the Task status.
+type gitPushResult struct {
+	Branch string `json:"branch"`
+	Commit string `json:"commit"`
+}
+
+func init() {
+	rootCmd.AddCommand(gitPushCmd)
+}
+
+var gitPushCmd = &cobra.Command{
+	Use:   "git-push",
+	Short: "Push the agent's changes back to a Git repository",
+	Long: `git-push pushes the changes the agent made to a Git context back to its repository.
+
+It runs as a sidecar next to the agent and waits until it is stopped (SIGTERM),
+which the kubelet does once the agent has exited. If the agent succeeded,
+uncommitted changes are committed and the commits are pushed to a branch.
+Nothing is pushed if the agent failed or made no changes.
+
+Environment variables:
+  GIT_REPO             Repository URL (required)
+  GIT_ROOT             Root directory of the clone, default: /git
+  GIT_LINK             Subdirectory name of the clone, default: repo
+  GIT_PUSH_BRANCH      Branch to push to (required)
+  GIT_PUSH_MESSAGE     Message of the commit holding uncommitted changes
+  GIT_PUSH_FORCE       Set to "true" to force the push
+  AGENT_EXIT_CODE_FILE File the agent's exit code is recorded in (required)
+  GIT_USERNAME         HTTPS username
+  GIT_PASSWORD         HTTPS password/token
+  GIT_SSH_KEY          SSH private key (content or file path)
+  GIT_SSH_KNOWN_HOSTS  Known hosts content for SSH verification`,
+	RunE: runGitPush,
+}
+
+func runGitPush(cmd *cobra.Command, args []string) error {
+	targetDir := filepath.Join(getEnvOrDefault(envRoot, defaultRoot), getEnvOrDefault(envLink, defaultLink))
+
+	// Startup errors are reported once the agent has exited: exiting now would
+	// only restart the sidecar
+	startCommit, startErr := prepareGitPush(targetDir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Println("git-push: Waiting for the agent to exit...")
+	<-ctx.Done()
+
+	result, err := pushChanges(targetDir, startCommit, startErr)
+	cleanupCredentials()
+	// Best effort: record the pushed branch and commit, or the failure reason, for the Task status
+	if msg := gitPushTerminationMessage(result, err); msg != nil {
+		_ = os.WriteFile(terminationLogPath, msg, 0644) //nolint:gosec // Termination log must be readable by the kubelet
+	}
+	return err
+}
+
+// gitPushTerminationMessage returns the termination message recording the outcome of
+// pushChanges: the failure reason, or the pushed branch and commit as JSON. Returns
+// nil if nothing was pushed.
+func gitPushTerminationMessage(result *gitPushResult, err error) []byte {
+	if err != nil {
+		return []byte(fmt.Sprintf("failed to push to %s: %v", os.Getenv(envRepo), err))
+	}
+	if result == nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// prepareGitPush validates the configuration, sets up authentication, and returns
+// the commit checked out before the agent started.
+func prepareGitPush(targetDir string) (string, error) {
+	repo := os.Getenv(envRepo)
+	if repo == "" {
+		return "", fmt.Errorf("%s environment variable is required", envRepo)
+	}
+	if err := validateRepoURL(repo); err != nil {
+		return "", err
+	}
+	if os.Getenv(envPushBranch) == "" {
+		return "", fmt.Errorf("%s environment variable is required", envPushBranch)
+	}
+	if os.Getenv(envAgentExitCode) == "" {
+		return "", fmt.Errorf("%s environment variable is required", envAgentExitCode)
+	}
+	if err := setupAuth(); err != nil {
+		return "", fmt.Errorf("failed to setup authentication: %w", err)
+	}
+	commit, err := revParse(targetDir, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the checked out commit: %w", err)
+	}
+	return commit, nil
+}
+
+// pushChanges commits the changes in targetDir and pushes them if the agent
+// succeeded. Returns nil if there was nothing to push.
+func pushChanges(targetDir, startCommit string, startErr error) (*gitPushResult, error) {
+	exitCode, err := os.ReadFile(os.Getenv(envAgentExitCode))
+	if err != nil || strings.TrimSpace(string(exitCode)) != "0" {
+		fmt.Println("git-push: The agent did not succeed, not pushing")
+		return nil, nil
+	}
+	if startErr != nil {
+		return nil, startErr
+	}
+
+	// Commit as KubeOpenCode unless an identity is set in the environment
+	for key, value := range map[string]string{
+		"GIT_AUTHOR_NAME":     gitPushAuthorName,
+		"GIT_AUTHOR_EMAIL":    gitPushAuthorEmail,
+		"GIT_COMMITTER_NAME":  gitPushAuthorName,
+		"GIT_COMMITTER_EMAIL": gitPushAuthorEmail,
+	} {
+		if os.Getenv(key) == "" {
+			_ = os.Setenv(key, value)
+		}
+	}
+
+	if err := runGitSteps(targetDir, [][]string{{"add", "--all"}}); err != nil {
+		return nil, err
+	}
+	staged, err := hasStagedChanges(targetDir)
+	if err != nil {
+		return nil, err
+	}
+	if staged {
+		fmt.Println("git-push: Committing changes...")
+		if err := runGitSteps(targetDir, [][]string{{"commit", "--quiet", "-m", getEnvOrDefault(envPushMessage, "KubeOpenCode")}}); err != nil {
+			return nil, err
+		}
+	}
+
+	commit, err := revParse(targetDir, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the commit to push: %w", err)
+	}
+	if commit == startCommit {
+		fmt.Println("git-push: No changes to push")
+		return nil, nil
+	}
+
+	branch := os.Getenv(envPushBranch)
+	pushArgs := []string{"push"}
+	if os.Getenv(envPushForce) == "true" {
+		pushArgs = append(pushArgs, "--force")
+	}
+	pushArgs = append(pushArgs, "origin", "HEAD:refs/heads/"+strings.TrimPrefix(branch, "refs/heads/"))
+
+	fmt.Printf("git-push: Pushing %s to branch %s...\n", commit, branch)
+	if err := runGitSteps(targetDir, [][]string{pushArgs}); err != nil {
+		return nil, err
+	}
+	fmt.Println("git-push: Push successful!")
+	return &gitPushResult{Branch: branch, Commit: commit}, nil
+}
+
+// hasStagedChanges reports whether the index of the repository in dir differs from HEAD.
+func hasStagedChanges(dir string) (bool, error) {
+	cmd := exec.Command("git", "-c", "safe.directory=*", "-C", dir, "diff", "--cached", "--quiet") //nolint:gosec // args are constructed from controlled inputs
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("git diff failed: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,287 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs a git command in dir and returns its trimmed output.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// remoteBranch returns the commit of branch in the bare repository remote, or "" if
+// the branch does not exist.
+func remoteBranch(t *testing.T, remote, branch string) string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", remote, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// setupGitPush creates a bare remote with one commit on main and a clone of it, and
+// configures git-push to push to branch. The agent's exit code is recorded as
+// exitCode. Returns the remote and the clone.
+func setupGitPush(t *testing.T, branch, exitCode string) (remote, clone string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for key, value := range map[string]string{
+		"GIT_AUTHOR_NAME":     gitPushAuthorName,
+		"GIT_AUTHOR_EMAIL":    gitPushAuthorEmail,
+		"GIT_COMMITTER_NAME":  gitPushAuthorName,
+		"GIT_COMMITTER_EMAIL": gitPushAuthorEmail,
+		"GIT_CONFIG_GLOBAL":   os.DevNull,
+		envUsername:           "",
+		envPassword:           "",
+		envSSHKey:             "",
+	} {
+		t.Setenv(key, value)
+	}
+
+	dir := t.TempDir()
+	remote = filepath.Join(dir, "remote.git")
+	git(t, dir, "init", "--quiet", "--bare", remote)
+	git(t, remote, "symbolic-ref", "HEAD", "refs/heads/main")
+
+	seed := filepath.Join(dir, "seed")
+	git(t, dir, "init", "--quiet", seed)
+	if err := os.WriteFile(filepath.Join(seed, "README.md"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, seed, "add", "README.md")
+	git(t, seed, "commit", "--quiet", "-m", "Initial commit")
+	git(t, seed, "push", "--quiet", remote, "HEAD:refs/heads/main")
+
+	clone = filepath.Join(dir, "clone")
+	git(t, dir, "clone", "--quiet", remote, clone)
+
+	exitCodeFile := filepath.Join(dir, "exit-code")
+	if exitCode != "" {
+		if err := os.WriteFile(exitCodeFile, []byte(exitCode+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The repository URL is only validated; pushes go to the clone's origin
+	t.Setenv(envRepo, "https://github.com/org/repo.git")
+	t.Setenv(envPushBranch, branch)
+	t.Setenv(envPushMessage, "KubeOpenCode Task default/task")
+	t.Setenv(envPushForce, "")
+	t.Setenv(envAgentExitCode, exitCodeFile)
+	return remote, clone
+}
+
+func TestPushChanges(t *testing.T) {
+	remote, clone := setupGitPush(t, "kubeopencode/task", "0")
+	startCommit, err := prepareGitPush(clone)
+	if err != nil {
+		t.Fatalf("prepareGitPush() error = %v", err)
+	}
+
+	// The agent edits a file and commits another one
+	if err := os.WriteFile(filepath.Join(clone, "README.md"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(clone, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, clone, "add", "main.go")
+	git(t, clone, "commit", "--quiet", "-m", "Add main.go")
+
+	result, err := pushChanges(clone, startCommit, nil)
+	if err != nil {
+		t.Fatalf("pushChanges() error = %v", err)
+	}
+	head := git(t, clone, "rev-parse", "HEAD")
+	if result == nil || result.Branch != "kubeopencode/task" || result.Commit != head {
+		t.Fatalf("pushChanges() = %+v, want branch kubeopencode/task at %s", result, head)
+	}
+	if got := remoteBranch(t, remote, "kubeopencode/task"); got != head {
+		t.Errorf("remote branch = %q, want %q", got, head)
+	}
+	if got := git(t, clone, "log", "-1", "--format=%s"); got != "KubeOpenCode Task default/task" {
+		t.Errorf("commit message = %q, want the GIT_PUSH_MESSAGE", got)
+	}
+	if got := git(t, clone, "rev-list", "--count", startCommit+"..HEAD"); got != "2" {
+		t.Errorf("pushed %s commits, want the agent's commit and the uncommitted changes", got)
+	}
+	if got := remoteBranch(t, remote, "main"); got != startCommit {
+		t.Errorf("main = %q, want it unchanged at %q", got, startCommit)
+	}
+
+	var message gitPushResult
+	if err := json.Unmarshal(gitPushTerminationMessage(result, nil), &message); err != nil {
+		t.Fatalf("termination message is not JSON: %v", err)
+	}
+	if message.Branch != "kubeopencode/task" || message.Commit != head {
+		t.Errorf("termination message = %+v, want branch kubeopencode/task at %s", message, head)
+	}
+}
+
+func TestPushChanges_NothingToPush(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode string
+		change   bool
+	}{
+		{name: "no changes", exitCode: "0"},
+		{name: "agent failed", exitCode: "1", change: true},
+		{name: "agent exit code not recorded", change: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, clone := setupGitPush(t, "kubeopencode/task", tt.exitCode)
+			startCommit, err := prepareGitPush(clone)
+			if err != nil {
+				t.Fatalf("prepareGitPush() error = %v", err)
+			}
+			if tt.change {
+				if err := os.WriteFile(filepath.Join(clone, "README.md"), []byte("changed\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := pushChanges(clone, startCommit, nil)
+			if err != nil || result != nil {
+				t.Fatalf("pushChanges() = %+v, %v; want nothing pushed", result, err)
+			}
+			if got := remoteBranch(t, remote, "kubeopencode/task"); got != "" {
+				t.Errorf("remote branch = %q, want it not to exist", got)
+			}
+			if msg := gitPushTerminationMessage(result, err); msg != nil {
+				t.Errorf("termination message = %q, want none", msg)
+			}
+		})
+	}
+}
+
+func TestPushChanges_ExistingBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		force   string
+		wantErr bool
+	}{
+		{name: "not forced", wantErr: true},
+		{name: "forced", force: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, clone := setupGitPush(t, "kubeopencode/task", "0")
+			t.Setenv(envPushForce, tt.force)
+			startCommit, err := prepareGitPush(clone)
+			if err != nil {
+				t.Fatalf("prepareGitPush() error = %v", err)
+			}
+
+			// The branch exists on the remote and has diverged from the clone
+			other := filepath.Join(t.TempDir(), "other")
+			git(t, filepath.Dir(other), "clone", "--quiet", remote, other)
+			if err := os.WriteFile(filepath.Join(other, "other.txt"), []byte("other\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			git(t, other, "add", "other.txt")
+			git(t, other, "commit", "--quiet", "-m", "Diverge")
+			git(t, other, "push", "--quiet", "origin", "HEAD:refs/heads/kubeopencode/task")
+			existing := git(t, other, "rev-parse", "HEAD")
+
+			if err := os.WriteFile(filepath.Join(clone, "README.md"), []byte("changed\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := pushChanges(clone, startCommit, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("pushChanges() = %+v, want an error for the diverged branch", result)
+				}
+				if got := remoteBranch(t, remote, "kubeopencode/task"); got != existing {
+					t.Errorf("remote branch = %q, want it unchanged at %q", got, existing)
+				}
+				if msg := string(gitPushTerminationMessage(result, err)); !strings.HasPrefix(msg, "failed to push to https://github.com/org/repo.git") {
+					t.Errorf("termination message = %q, want the failure reason", msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pushChanges() error = %v", err)
+			}
+			head := git(t, clone, "rev-parse", "HEAD")
+			if result == nil || result.Commit != head {
+				t.Fatalf("pushChanges() = %+v, want %s pushed", result, head)
+			}
+			if got := remoteBranch(t, remote, "kubeopencode/task"); got != head {
+				t.Errorf("remote branch = %q, want it overwritten with %q", got, head)
+			}
+		})
+	}
+}
+
+func TestPushChanges_StartupError(t *testing.T) {
+	_, clone := setupGitPush(t, "kubeopencode/task", "0")
+	startErr := errors.New("failed to setup authentication")
+	if _, err := pushChanges(clone, "", startErr); !errors.Is(err, startErr) {
+		t.Errorf("pushChanges() error = %v, want the startup error once the agent succeeded", err)
+	}
+}
+
+func TestPrepareGitPush_Validation(t *testing.T) {
+	_, clone := setupGitPush(t, "kubeopencode/task", "0")
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{name: "missing repository", key: envRepo},
+		{name: "unsupported repository URL", key: envRepo, value: "file:///tmp/repo"},
+		{name: "missing branch", key: envPushBranch},
+		{name: "missing exit code file", key: envAgentExitCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			if _, err := prepareGitPush(clone); err == nil {
+				t.Error("prepareGitPush() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestHasStagedChanges(t *testing.T) {
+	_, clone := setupGitPush(t, "kubeopencode/task", "0")
+	if staged, err := hasStagedChanges(clone); err != nil || staged {
+		t.Fatalf("hasStagedChanges() on a clean clone = %v, %v; want false", staged, err)
+	}
+
+	// Unstaged changes do not count
+	if err := os.WriteFile(filepath.Join(clone, "README.md"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if staged, err := hasStagedChanges(clone); err != nil || staged {
+		t.Fatalf("hasStagedChanges() with unstaged changes = %v, %v; want false", staged, err)
+	}
+
+	git(t, clone, "add", "README.md")
+	if staged, err := hasStagedChanges(clone); err != nil || !staged {
+		t.Errorf("hasStagedChanges() with staged changes = %v, %v; want true", staged, err)
+	}
+
+	if _, err := hasStagedChanges(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("hasStagedChanges() outside a repository error = nil, want an error")
+	}
+}
@@ -8,6 +8,8 @@
 //   - git-init:      Clone Git repositories for Git Context
 //   - context-init:  Copy ConfigMap content to workspace
 //   - url-fetch:     Fetch content from remote URLs for URL Context
+//   - workspace-sync:   Serve per-Task workspaces in Server-mode Agents
+//   - workspace-upload: Upload a Task workspace to a Server-mode Agent
 package main
 
 import (
@@ -27,6 +29,8 @@ This unified binary provides:
   git-init       Clone Git repositories for Git Context
   context-init   Copy ConfigMap content to workspace
   url-fetch      Fetch content from remote URLs for URL Context
+  workspace-sync    Serve per-Task workspaces in Server-mode Agents
+  workspace-upload  Upload a Task workspace to a Server-mode Agent
 
 Examples:
   # Start the controller
@@ -3,7 +3,9 @@
 package main
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,6 +25,10 @@ const (
 	envURLHeaders  = "URL_HEADERS"
 	envURLTimeout  = "URL_TIMEOUT"
 	envURLInsecure = "URL_INSECURE"
+	// Append mode: write content to the shared context file wrapped in XML tags
+	envURLAppend           = "URL_APPEND"
+	envURLContextName      = "URL_CONTEXT_NAME"
+	envURLContextNamespace = "URL_CONTEXT_NAMESPACE"
 	// Auth credentials from Secret (mounted as env vars)
 	envURLToken    = "URL_AUTH_TOKEN"    //nolint:gosec // This is an env var name, not a credential
 	envURLUsername = "URL_AUTH_USERNAME" //nolint:gosec // This is an env var name, not a credential
@@ -32,6 +38,9 @@ const (
 // Default values for url-fetch
 const (
 	defaultURLTimeout = 30 // seconds
+	// terminationLogPath is where the failure reason is written so that the
+	// controller can surface it in the Task status
+	terminationLogPath = "/dev/termination-log"
 )
 
 func init() {
@@ -51,6 +60,11 @@ Environment variables:
   URL_HEADERS       JSON object of HTTP headers to include, e.g., {"X-Custom": "value"}
   URL_TIMEOUT       Request timeout in seconds (default: 30)
   URL_INSECURE      Set to "true" to skip TLS certificate verification
+  URL_APPEND        Set to "true" to append content to URL_TARGET wrapped in
+                    <context> XML tags instead of overwriting it
+  URL_CONTEXT_NAME  Context name for the XML tag (append mode)
+  URL_CONTEXT_NAMESPACE
+                    Context namespace for the XML tag (append mode)
   URL_AUTH_TOKEN    Bearer token for Authorization header (from Secret)
   URL_AUTH_USERNAME Username for HTTP Basic auth (from Secret)
   URL_AUTH_PASSWORD Password for HTTP Basic auth (from Secret)
@@ -70,12 +84,34 @@ Example:
 }
 
 func runURLFetch(cmd *cobra.Command, args []string) error {
+	digest, err := fetchURL()
+	if err != nil {
+		// Best effort: record the failure reason for the Task status
+		msg := fmt.Sprintf("failed to fetch %s: %v", os.Getenv(envURLSource), err)
+		_ = os.WriteFile(terminationLogPath, []byte(msg), 0644) //nolint:gosec // Termination log must be readable by the kubelet
+		return err
+	}
+	// Best effort: record the digest of the content for the Task status
+	if data, err := json.Marshal(urlFetchResult{Digest: digest}); err == nil {
+		_ = os.WriteFile(terminationLogPath, data, 0644) //nolint:gosec // Termination log must be readable by the kubelet
+	}
+	return nil
+}
+
+// urlFetchResult is written to the termination message of url-fetch when it succeeds.
+type urlFetchResult struct {
+	Digest string `json:"digest"`
+}
+
+// fetchURL fetches the URL context and returns the SHA-256 digest of its content.
+func fetchURL() (string, error) {
 	// Get configuration from environment variables
 	source := os.Getenv(envURLSource)
 	target := os.Getenv(envURLTarget)
 	headersJSON := os.Getenv(envURLHeaders)
 	timeoutStr := os.Getenv(envURLTimeout)
 	insecureStr := os.Getenv(envURLInsecure)
+	appendMode := os.Getenv(envURLAppend) == "true"
 
 	// Auth credentials (from mounted Secret)
 	authToken := os.Getenv(envURLToken)
@@ -84,10 +120,10 @@ func runURLFetch(cmd *cobra.Command, args []string) error {
 
 	// Validate required fields
 	if source == "" {
-		return fmt.Errorf("URL_SOURCE environment variable is required")
+		return "", fmt.Errorf("URL_SOURCE environment variable is required")
 	}
 	if target == "" {
-		return fmt.Errorf("URL_TARGET environment variable is required")
+		return "", fmt.Errorf("URL_TARGET environment variable is required")
 	}
 
 	fmt.Println("url-fetch: Fetching content from URL...")
@@ -99,7 +135,7 @@ func runURLFetch(cmd *cobra.Command, args []string) error {
 	if timeoutStr != "" {
 		parsed, err := strconv.Atoi(timeoutStr)
 		if err != nil {
-			return fmt.Errorf("invalid URL_TIMEOUT value: %w", err)
+			return "", fmt.Errorf("invalid URL_TIMEOUT value: %w", err)
 		}
 		timeout = parsed
 	}
@@ -115,7 +151,7 @@ func runURLFetch(cmd *cobra.Command, args []string) error {
 	headers := make(map[string]string)
 	if headersJSON != "" {
 		if err := json.Unmarshal([]byte(headersJSON), &headers); err != nil {
-			return fmt.Errorf("failed to parse URL_HEADERS: %w", err)
+			return "", fmt.Errorf("failed to parse URL_HEADERS: %w", err)
 		}
 		fmt.Printf("  Custom headers: %d\n", len(headers))
 	}
@@ -133,7 +169,7 @@ func runURLFetch(cmd *cobra.Command, args []string) error {
 	// Create request
 	req, err := http.NewRequest("GET", source, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add custom headers
@@ -159,14 +195,14 @@ func runURLFetch(cmd *cobra.Command, args []string) error {
 	fmt.Println("url-fetch: Executing request...")
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return "", fmt.Errorf("request failed: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	// Check response status
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
 	}
 
 	fmt.Printf("url-fetch: Response status: %s\n", resp.Status)
@@ -175,23 +211,69 @@ func runURLFetch(cmd *cobra.Command, args []string) error {
 	targetDir := filepath.Dir(target)
 	if targetDir != "" && targetDir != "." {
 		if err := os.MkdirAll(targetDir, 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
-			return fmt.Errorf("failed to create target directory: %w", err)
+			return "", fmt.Errorf("failed to create target directory: %w", err)
 		}
 	}
 
+	// Append mode: add content to the shared context file with XML tags
+	if appendMode {
+		content, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read content: %w", err)
+		}
+		if err := appendContext(target, os.Getenv(envURLContextName), os.Getenv(envURLContextNamespace), string(content)); err != nil {
+			return "", err
+		}
+		fmt.Printf("url-fetch: Appended %d bytes to %s\n", len(content), target)
+		fmt.Println("url-fetch: Done!")
+		return contentDigest(content), nil
+	}
+
 	// Write content to target file
 	file, err := os.Create(target) //nolint:gosec // target is a controlled path from Task spec
 	if err != nil {
-		return fmt.Errorf("failed to create target file: %w", err)
+		return "", fmt.Errorf("failed to create target file: %w", err)
 	}
 	defer func() { _ = file.Close() }()
 
-	written, err := io.Copy(file, resp.Body)
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hash), resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to write content: %w", err)
+		return "", fmt.Errorf("failed to write content: %w", err)
 	}
 
 	fmt.Printf("url-fetch: Written %d bytes to %s\n", written, target)
 	fmt.Println("url-fetch: Done!")
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contentDigest returns the SHA-256 digest of content as "sha256:<hex>".
+func contentDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// appendContext appends content to the context file wrapped in XML tags,
+// matching the format the controller uses for inline contexts.
+func appendContext(target, name, namespace, content string) error {
+	file, err := os.OpenFile(target, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) //nolint:gosec // target is a controlled path from Task spec
+	if err != nil {
+		return fmt.Errorf("failed to open target file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat target file: %w", err)
+	}
+
+	var separator string
+	if info.Size() > 0 {
+		separator = "\n\n"
+	}
+	block := fmt.Sprintf("%s<context name=%q namespace=%q type=\"URL\">\n%s\n</context>", separator, name, namespace, content)
+	if _, err := file.WriteString(block); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
 	return nil
 }
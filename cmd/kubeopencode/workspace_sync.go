@@ -0,0 +1,462 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Environment variable names for workspace-sync and workspace-upload
+const (
+	envWorkspaceSyncPort  = "WORKSPACE_SYNC_PORT"
+	envWorkspaceSyncURL   = "WORKSPACE_SYNC_URL"
+	envWorkspaceSyncToken = "WORKSPACE_SYNC_TOKEN"
+	envOpenCodeServerURL  = "OPENCODE_SERVER_URL"
+)
+
+// Default values for workspace-sync
+const (
+	defaultWorkspaceSyncPort = "4097"
+	// taskWorkspacesRelPath is where per-Task workspaces live inside the server's
+	// workspace. Must match ServerTaskWorkspaceDir in the controller.
+	taskWorkspacesRelPath = ".kubeopencode/tasks"
+	// uploadTimeout bounds a single workspace upload
+	uploadTimeout = 10 * time.Minute
+	// drainPollInterval is how often /drain checks for busy sessions
+	drainPollInterval = 5 * time.Second
+)
+
+func init() {
+	rootCmd.AddCommand(workspaceSyncCmd)
+	rootCmd.AddCommand(workspaceUploadCmd)
+}
+
+var workspaceSyncCmd = &cobra.Command{
+	Use:   "workspace-sync",
+	Short: "Serve per-Task workspaces inside a Server-mode Agent's workspace",
+	Long: `workspace-sync runs as a sidecar of the OpenCode server of a Server-mode Agent.
+
+Task Pods of the Agent upload their staged contexts to it, and it extracts them into a
+per-Task directory of the shared workspace, where the attached session runs:
+
+  PUT    /workspaces/{namespace}/{name}   Replace the Task workspace with a tar.gz archive
+  DELETE /workspaces/{namespace}/{name}   Remove the Task workspace
+  GET    /drain                           Wait until no session in a Task workspace is busy
+
+PUT and DELETE require the Agent's token in an "Authorization: Bearer" header.
+Task workspaces are created under ${WORKSPACE_DIR}/.kubeopencode/tasks/{namespace}/{name}.
+The server Pod's containers call /drain in their preStop hook, so a terminating server
+finishes the sessions of running Tasks before it stops.
+
+Environment variables:
+  WORKSPACE_DIR        Server workspace directory, default: /workspace
+  WORKSPACE_SYNC_PORT  Port to listen on, default: 4097
+  WORKSPACE_SYNC_TOKEN Token authorizing uploads and deletions (required)
+  OPENCODE_SERVER_URL  URL of the OpenCode server to drain, e.g. http://localhost:4096
+                       (without it, /drain returns immediately)`,
+	RunE: runWorkspaceSync,
+}
+
+var workspaceUploadCmd = &cobra.Command{
+	Use:   "workspace-upload",
+	Short: "Upload a Task workspace to a Server-mode Agent",
+	Long: `workspace-upload archives the Task workspace staged by context-init, git-init and
+url-fetch, and uploads it to the workspace-sync sidecar of the Agent's OpenCode server.
+
+This command is used as the last init container of Server-mode Task Pods.
+
+Environment variables:
+  WORKSPACE_DIR       Task workspace directory to upload, default: /workspace
+  WORKSPACE_SYNC_URL  URL of the Task workspace on the sidecar (required), e.g.
+                      http://my-agent.ns.svc.cluster.local:4097/workspaces/default/my-task
+  WORKSPACE_SYNC_TOKEN
+                      Token of the Agent authorizing the upload (required)`,
+	RunE: runWorkspaceUpload,
+}
+
+func runWorkspaceSync(cmd *cobra.Command, args []string) error {
+	workspaceDir := getEnvOrDefault(envWorkspaceDir, defaultWorkspaceDir)
+	port := getEnvOrDefault(envWorkspaceSyncPort, defaultWorkspaceSyncPort)
+	serverURL := os.Getenv(envOpenCodeServerURL)
+	token := os.Getenv(envWorkspaceSyncToken)
+	if token == "" {
+		return fmt.Errorf("%s environment variable is required", envWorkspaceSyncToken)
+	}
+	root := filepath.Join(workspaceDir, taskWorkspacesRelPath)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /workspaces/{namespace}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		dir, err := taskWorkspaceDir(root, r.PathValue("namespace"), r.PathValue("name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := replaceWorkspace(dir, r.Body); err != nil {
+			fmt.Printf("workspace-sync: Failed to extract %s: %v\n", dir, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("workspace-sync: Extracted %s\n", dir)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /workspaces/{namespace}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		dir, err := taskWorkspaceDir(root, r.PathValue("namespace"), r.PathValue("name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("workspace-sync: Removed %s\n", dir)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /drain", func(w http.ResponseWriter, r *http.Request) {
+		if serverURL != "" {
+			fmt.Println("workspace-sync: Draining sessions...")
+			if err := drainSessions(r.Context(), serverURL, root); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Println("workspace-sync: No busy sessions left")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("workspace-sync: Serving %s on :%s\n", root, port)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("workspace-sync server failed: %w", err)
+	}
+	return nil
+}
+
+// authorized reports whether the request carries token as its bearer token.
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// drainSessions waits until no OpenCode session in the Task workspaces below root is
+// busy on the server at serverURL, or ctx is done. A server that cannot be reached
+// has no sessions left to wait for.
+func drainSessions(ctx context.Context, serverURL, root string) error {
+	for {
+		busy, err := busySessions(ctx, serverURL, root)
+		if err != nil {
+			fmt.Printf("workspace-sync: Unable to get session status, not waiting: %v\n", err)
+			return nil
+		}
+		if busy == 0 {
+			return nil
+		}
+		fmt.Printf("workspace-sync: Waiting for %d busy session(s)\n", busy)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(drainPollInterval):
+		}
+	}
+}
+
+// busySessions returns the number of sessions that are not idle in the Task workspaces
+// below root. OpenCode reports session status per directory.
+func busySessions(ctx context.Context, serverURL, root string) (int, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "*", "*"))
+	if err != nil {
+		return 0, err
+	}
+	busy := 0
+	for _, dir := range dirs {
+		if strings.HasSuffix(dir, ".upload") {
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			serverURL+"/session/status?"+url.Values{"directory": {dir}}.Encode(), nil)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := http.DefaultClient.Do(req) //nolint:gosec // URL is set by the controller
+		if err != nil {
+			return 0, err
+		}
+		var status map[string]struct {
+			Type string `json:"type"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("GET /session/status: HTTP %d", resp.StatusCode)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode session status: %w", err)
+		}
+		for _, s := range status {
+			if s.Type != "idle" {
+				busy++
+			}
+		}
+	}
+	return busy, nil
+}
+
+// taskWorkspaceDir returns the directory of a Task workspace below root, rejecting
+// path segments that could escape it.
+func taskWorkspaceDir(root, namespace, name string) (string, error) {
+	for _, segment := range []string{namespace, name} {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("invalid task workspace %q/%q", namespace, name)
+		}
+	}
+	return filepath.Join(root, namespace, name), nil
+}
+
+// replaceWorkspace extracts the tar.gz archive into dir, replacing its previous content.
+func replaceWorkspace(dir string, archive io.Reader) error {
+	// Extract next to the target and swap, so a failed upload does not leave a partial workspace
+	tmpDir := dir + ".upload"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if err := extractTarGz(archive, tmpDir); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, dir)
+}
+
+// extractTarGz extracts a tar.gz stream into dir. Entries must stay within dir, and
+// symlinks must be relative and point within dir. Symlinks are created after all
+// files, so no entry can be written through one.
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip stream: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	// Use 0755 for environments where containers run with random UIDs
+	if err := os.MkdirAll(dir, 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
+		return err
+	}
+
+	type symlink struct{ target, path string }
+	var symlinks []symlink
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar stream: %w", err)
+		}
+
+		path := filepath.Join(dir, hdr.Name) //nolint:gosec // Checked below to stay within dir
+		if path != dir && !strings.HasPrefix(path, dir+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %q escapes the workspace", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(hdr.Mode).Perm()) //nolint:gosec // Path is checked to stay within dir
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil { //nolint:gosec // Upload size is bounded by the Task workspace
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkSymlinkTarget(dir, path, hdr.Linkname); err != nil {
+				return fmt.Errorf("archive entry %q: %w", hdr.Name, err)
+			}
+			symlinks = append(symlinks, symlink{target: hdr.Linkname, path: path})
+		}
+	}
+
+	for _, link := range symlinks {
+		if err := os.MkdirAll(filepath.Dir(link.path), 0755); err != nil { //nolint:gosec // Needs group/others access for random UID environments
+			return err
+		}
+		if err := os.Symlink(link.target, link.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSymlinkTarget checks that a symlink at path with the given target resolves
+// within dir: the agent works in dir, so links must not expose the rest of the server.
+func checkSymlinkTarget(dir, path, target string) error {
+	if filepath.IsAbs(target) {
+		return fmt.Errorf("symlink target %q is absolute", target)
+	}
+	resolved := filepath.Join(filepath.Dir(path), target)
+	if resolved != dir && !strings.HasPrefix(resolved, dir+string(os.PathSeparator)) {
+		return fmt.Errorf("symlink target %q escapes the workspace", target)
+	}
+	return nil
+}
+
+func runWorkspaceUpload(cmd *cobra.Command, args []string) error {
+	workspaceDir := getEnvOrDefault(envWorkspaceDir, defaultWorkspaceDir)
+	syncURL := os.Getenv(envWorkspaceSyncURL)
+	if syncURL == "" {
+		return fmt.Errorf("WORKSPACE_SYNC_URL environment variable is required")
+	}
+
+	fmt.Println("workspace-upload: Uploading Task workspace...")
+	fmt.Printf("  Workspace: %s\n", workspaceDir)
+	fmt.Printf("  Target: %s\n", syncURL)
+
+	// Stream the archive to the sidecar while it is being written
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTarGz(pw, workspaceDir))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, syncURL, pr)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	req.Header.Set("Authorization", "Bearer "+os.Getenv(envWorkspaceSyncToken))
+
+	resp, err := http.DefaultClient.Do(req) //nolint:gosec // URL is set by the controller
+	if err != nil {
+		msg := fmt.Sprintf("failed to upload workspace to %s: %v", syncURL, err)
+		_ = os.WriteFile(terminationLogPath, []byte(msg), 0644) //nolint:gosec // Termination log must be readable by the kubelet
+		return fmt.Errorf("%s", msg)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		msg := fmt.Sprintf("failed to upload workspace to %s: HTTP %d: %s", syncURL, resp.StatusCode, strings.TrimSpace(string(body)))
+		_ = os.WriteFile(terminationLogPath, []byte(msg), 0644) //nolint:gosec // Termination log must be readable by the kubelet
+		return fmt.Errorf("%s", msg)
+	}
+
+	fmt.Println("workspace-upload: Done!")
+	return nil
+}
+
+// writeTarGz writes the content of dir as a tar.gz archive with paths relative to dir.
+func writeTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+			// workspace-sync rejects links leaving the workspace
+			if err := checkSymlinkTarget(dir, path, link); err != nil {
+				fmt.Printf("workspace-upload: Warning: skipping %s: %v\n", rel, err)
+				return nil
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path) //nolint:gosec // Path comes from walking the workspace
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
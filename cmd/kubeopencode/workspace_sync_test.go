@@ -0,0 +1,117 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarGzWithSymlink returns a tar.gz archive holding a file and a symlink to target.
+func tarGzWithSymlink(t *testing.T, target string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := []byte("hello")
+	if err := tw.WriteHeader(&tar.Header{Name: "docs/readme.md", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "docs/link", Typeflag: tar.TypeSymlink, Linkname: target}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTarGz_Symlinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{name: "relative within the workspace", target: "readme.md"},
+		{name: "relative to the workspace root", target: "../docs/readme.md"},
+		{name: "absolute", target: "/etc/passwd", wantErr: true},
+		{name: "escaping the workspace", target: "../../etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "workspace")
+			err := extractTarGz(tarGzWithSymlink(t, tt.target), dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTarGz() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, err := os.Readlink(filepath.Join(dir, "docs", "link")); err != nil || got != tt.target {
+				t.Errorf("link target = %q (%v), want %q", got, err, tt.target)
+			}
+		})
+	}
+}
+
+func TestWriteTarGz_SkipsEscapingSymlinks(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "task.md"), []byte("task"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("task.md", filepath.Join(src, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(src, "outside")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeTarGz(&buf, src); err != nil {
+		t.Fatalf("writeTarGz() error = %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "workspace")
+	if err := extractTarGz(&buf, dst); err != nil {
+		t.Fatalf("extractTarGz() error = %v", err)
+	}
+	if _, err := os.Readlink(filepath.Join(dst, "inside")); err != nil {
+		t.Errorf("symlink within the workspace was not uploaded: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "outside")); !os.IsNotExist(err) {
+		t.Errorf("symlink leaving the workspace was uploaded: %v", err)
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "Bearer s3cret", want: true},
+		{header: "Bearer wrong"},
+		{header: "s3cret"},
+		{header: ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PUT", "/workspaces/default/task", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		if got := authorized(r, "s3cret"); got != tt.want {
+			t.Errorf("authorized(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
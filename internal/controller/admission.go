@@ -0,0 +1,386 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// admissionState is the state of a Task in its Agent's admission accounting.
+type admissionState int
+
+const (
+	// admissionQueued means the Task is waiting for a slot
+	admissionQueued admissionState = iota
+	// admissionReserved means the Task was admitted and holds a slot,
+	// but is not yet observed as Running
+	admissionReserved
+	// admissionRunning means the Task is Running and holds a slot
+	admissionRunning
+)
+
+// admissionEntry is the admission accounting of a single Task.
+type admissionEntry struct {
+	agent types.NamespacedName
+	state admissionState
+	// quotaBlocked is true for Queued Tasks waiting for quota (or for the Task
+	// their sessionRef continues, or for their Agent's server to be ready) rather than a slot.
+	// They are not ahead of other Tasks in the queue.
+	quotaBlocked bool
+	// task holds the fields used for queue ordering (see queuedBefore)
+	task *kubeopenv1alpha1.Task
+}
+
+// holdsSlot reports whether the Task counts against its Agent's maxConcurrentTasks.
+func (e *admissionEntry) holdsSlot() bool {
+	return e.state == admissionReserved || e.state == admissionRunning
+}
+
+// admissionLimits are the concurrency limits of an Agent.
+type admissionLimits struct {
+	// maxConcurrent is the Agent's maxConcurrentTasks (0 = unlimited)
+	maxConcurrent int32
+	// fairShare holds the per-namespace limits (nil = none)
+	fairShare *kubeopenv1alpha1.FairShareConfig
+	// activeSessions is the number of active sessions on the server of a Server-mode
+	// Agent that counts against maxConcurrent, if it exceeds the Tasks holding a slot
+	activeSessions int32
+}
+
+// agentAdmissionLimits returns the concurrency limits of the Agent configuration.
+func agentAdmissionLimits(cfg agentConfig) admissionLimits {
+	limits := admissionLimits{fairShare: cfg.fairShare, activeSessions: cfg.activeSessions}
+	if cfg.maxConcurrentTasks != nil && *cfg.maxConcurrentTasks > 0 {
+		limits.maxConcurrent = *cfg.maxConcurrentTasks
+	}
+	return limits
+}
+
+// agentHolding returns the number of slots of the Agent in use, given the number of
+// Tasks holding a slot.
+func (l admissionLimits) agentHolding(holding int32) int32 {
+	return max(holding, l.activeSessions)
+}
+
+// enabled reports whether any concurrency limit applies to Tasks from the namespace.
+func (l admissionLimits) enabled(namespace string) bool {
+	return l.maxConcurrent > 0 || l.namespaceMax(namespace) > 0
+}
+
+// namespaceMax returns the concurrency limit for Tasks from the namespace (0 = unlimited).
+func (l admissionLimits) namespaceMax(namespace string) int32 {
+	maxConcurrent, _ := namespaceShare(l.fairShare, namespace)
+	return maxConcurrent
+}
+
+// admissionCheck is the result of a capacity check.
+type admissionCheck struct {
+	// admitted is true if the Task can take a slot
+	admitted bool
+	// namespaceLimited is true if the Task's namespace used up its fair share
+	namespaceLimited bool
+	// position is the Task's 1-based queue position if not admitted: among the
+	// Queued Tasks of its namespace if namespaceLimited, otherwise of the Agent
+	position int32
+	// holding is the number of other Tasks holding a slot of the Agent
+	holding int32
+}
+
+// taskAdmission tracks, per Agent, which Tasks hold a concurrency slot and which
+// are queued, so capacity checks do not need to List Tasks.
+//
+// It is fed by Task informer events, so it is rebuilt from the cache when the
+// controller starts (including after leader failover). The reconciler additionally
+// reserves a slot when it admits a Task, because the informer only observes the
+// Task as Running after its status update round-trips through the API server.
+type taskAdmission struct {
+	mu    sync.Mutex
+	tasks map[types.NamespacedName]*admissionEntry
+	// limits are the last limits seen for each Agent
+	limits map[types.NamespacedName]admissionLimits
+}
+
+// newTaskAdmission creates an empty taskAdmission.
+func newTaskAdmission() *taskAdmission {
+	return &taskAdmission{
+		tasks:  make(map[types.NamespacedName]*admissionEntry),
+		limits: make(map[types.NamespacedName]admissionLimits),
+	}
+}
+
+// taskAgentKey returns the Agent a Task is accounted against. This is the resolved
+// status.agentRef, so Tasks in every namespace that share an Agent are counted
+// against the same limit. Tasks without status.agentRef fall back to the Agent named
+// by their agent label in the Task's namespace. Returns false if neither is set.
+func taskAgentKey(task *kubeopenv1alpha1.Task) (types.NamespacedName, bool) {
+	if ref := task.Status.AgentRef; ref != nil && ref.Name != "" {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = task.Namespace
+		}
+		return types.NamespacedName{Namespace: namespace, Name: ref.Name}, true
+	}
+	agentName := task.Labels[AgentLabelKey]
+	if agentName == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: task.Namespace, Name: agentName}, true
+}
+
+// isQuotaBlocked reports whether a Queued Task is waiting for the Agent's (or its
+// namespace's) quota, for the Task its sessionRef continues, or for the Agent's server
+// to be ready, rather than for a concurrency slot.
+func isQuotaBlocked(task *kubeopenv1alpha1.Task) bool {
+	cond := meta.FindStatusCondition(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+	return cond != nil && cond.Status == metav1.ConditionTrue &&
+		(cond.Reason == kubeopenv1alpha1.ReasonQuotaExceeded || cond.Reason == kubeopenv1alpha1.ReasonNamespaceQuotaExceeded ||
+			cond.Reason == kubeopenv1alpha1.ReasonWaitingForSession || cond.Reason == kubeopenv1alpha1.ReasonServerStarting ||
+			cond.Reason == kubeopenv1alpha1.ReasonServerNotReady || cond.Reason == kubeopenv1alpha1.ReasonServerDraining)
+}
+
+// queueOrderFields returns a copy of the Task holding only the fields used by queuedBefore.
+func queueOrderFields(task *kubeopenv1alpha1.Task) *kubeopenv1alpha1.Task {
+	return &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              task.Name,
+			Namespace:         task.Namespace,
+			UID:               task.UID,
+			CreationTimestamp: task.CreationTimestamp,
+		},
+		Spec: kubeopenv1alpha1.TaskSpec{Priority: task.Spec.Priority},
+	}
+}
+
+// observe updates the accounting of a Task from its latest observed state.
+// Returns the Agent the Task was accounted against and whether the Task released a slot.
+func (a *taskAdmission) observe(task *kubeopenv1alpha1.Task) (types.NamespacedName, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: task.Namespace, Name: task.Name}
+	prev := a.tasks[key]
+
+	var state admissionState
+	agent, ok := taskAgentKey(task)
+	switch {
+	case !ok:
+		return a.removeLocked(key)
+	case task.Status.Phase == kubeopenv1alpha1.TaskPhaseRunning:
+		state = admissionRunning
+	case task.Status.Phase == kubeopenv1alpha1.TaskPhaseQueued:
+		state = admissionQueued
+	case task.Status.Phase == "" && isAdmittedFromQueue(task):
+		state = admissionReserved
+	case task.Status.Phase == "" && prev != nil && prev.state == admissionReserved:
+		// Reserved by the reconciler, not yet observed as Running
+		state = admissionReserved
+	default:
+		// New, retrying (Pending), or finished Tasks do not hold a slot
+		return a.removeLocked(key)
+	}
+
+	a.tasks[key] = &admissionEntry{
+		agent:        agent,
+		state:        state,
+		quotaBlocked: state == admissionQueued && isQuotaBlocked(task),
+		task:         queueOrderFields(task),
+	}
+	if prev == nil {
+		return agent, false
+	}
+	return prev.agent, prev.holdsSlot() && (prev.agent != agent || state == admissionQueued)
+}
+
+// forget removes a deleted Task. Returns the Agent it was accounted against
+// and whether it released a slot.
+func (a *taskAdmission) forget(key types.NamespacedName) (types.NamespacedName, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.removeLocked(key)
+}
+
+func (a *taskAdmission) removeLocked(key types.NamespacedName) (types.NamespacedName, bool) {
+	prev, ok := a.tasks[key]
+	if !ok {
+		return types.NamespacedName{}, false
+	}
+	delete(a.tasks, key)
+	return prev.agent, prev.holdsSlot()
+}
+
+// check reports whether the Task can be admitted to the Agent: Tasks holding a slot
+// plus Queued Tasks ahead of it (see queuedBefore) must leave a free slot, and its
+// namespace must be within its fair share. Queued Tasks whose namespace is at its
+// fair share do not take slots, so they are not counted as ahead.
+// The Task itself is not counted.
+func (a *taskAdmission) check(task *kubeopenv1alpha1.Task, agent types.NamespacedName, limits admissionLimits) admissionCheck {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.checkLocked(task, agent, limits)
+}
+
+func (a *taskAdmission) checkLocked(task *kubeopenv1alpha1.Task, agent types.NamespacedName, limits admissionLimits) admissionCheck {
+	a.limits[agent] = limits
+
+	key := types.NamespacedName{Namespace: task.Namespace, Name: task.Name}
+	holding := int32(0)
+	namespaceHolding := make(map[string]int32)
+	var queued []*kubeopenv1alpha1.Task
+	for k, e := range a.tasks {
+		if e.agent != agent || k == key {
+			continue
+		}
+		switch {
+		case e.holdsSlot():
+			holding++
+			namespaceHolding[e.task.Namespace]++
+		case e.quotaBlocked:
+			// Waits for quota, not for a slot
+		case queuedBefore(e.task, task):
+			queued = append(queued, e.task)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool { return queuedBefore(queued[i], queued[j]) })
+
+	// Walk the queue ahead of the Task in admission order
+	ahead := int32(0)
+	namespaceWaiting := int32(0)
+	for _, t := range queued {
+		if m := limits.namespaceMax(t.Namespace); m > 0 && namespaceHolding[t.Namespace] >= m {
+			if t.Namespace == task.Namespace {
+				namespaceWaiting++
+			}
+			continue
+		}
+		namespaceHolding[t.Namespace]++
+		ahead++
+	}
+
+	if m := limits.namespaceMax(task.Namespace); m > 0 && namespaceHolding[task.Namespace] >= m {
+		return admissionCheck{namespaceLimited: true, position: namespaceWaiting + 1, holding: holding}
+	}
+	if limits.maxConcurrent > 0 && limits.agentHolding(holding)+ahead >= limits.maxConcurrent {
+		return admissionCheck{position: ahead + 1, holding: holding}
+	}
+	return admissionCheck{admitted: true, holding: holding}
+}
+
+// tryReserve atomically re-checks capacity and, if the Task can be admitted, records
+// that the reconciler admitted it, so it holds a slot of the Agent until the informer
+// observes it as Running (or leaving the queue). Checking and reserving under one lock
+// keeps the limits from being exceeded by concurrent reconciles.
+// Returns false if the slot was taken since the Task's capacity check.
+func (a *taskAdmission) tryReserve(task *kubeopenv1alpha1.Task, agent types.NamespacedName, limits admissionLimits) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: task.Namespace, Name: task.Name}
+	if e, ok := a.tasks[key]; ok && e.agent == agent && e.holdsSlot() {
+		return true
+	}
+	if !a.checkLocked(task, agent, limits).admitted {
+		return false
+	}
+	a.tasks[key] = &admissionEntry{agent: agent, state: admissionReserved, task: queueOrderFields(task)}
+	return true
+}
+
+// next returns the Queued Tasks of the Agent that can take the currently free slots,
+// in admission order, skipping Tasks whose namespace is at its fair share.
+// If the Agent's limits are not known yet, only the head is returned.
+func (a *taskAdmission) next(agent types.NamespacedName) []types.NamespacedName {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	holding := int32(0)
+	namespaceHolding := make(map[string]int32)
+	var queued []*kubeopenv1alpha1.Task
+	for _, e := range a.tasks {
+		if e.agent != agent {
+			continue
+		}
+		if e.holdsSlot() {
+			holding++
+			namespaceHolding[e.task.Namespace]++
+		} else if !e.quotaBlocked {
+			queued = append(queued, e.task)
+		}
+	}
+	if len(queued) == 0 {
+		return nil
+	}
+	sort.Slice(queued, func(i, j int) bool { return queuedBefore(queued[i], queued[j]) })
+
+	limits, ok := a.limits[agent]
+	if !ok {
+		head := queued[0]
+		return []types.NamespacedName{{Namespace: head.Namespace, Name: head.Name}}
+	}
+
+	holding = limits.agentHolding(holding)
+	var keys []types.NamespacedName
+	for _, t := range queued {
+		if limits.maxConcurrent > 0 && holding >= limits.maxConcurrent {
+			break
+		}
+		if m := limits.namespaceMax(t.Namespace); m > 0 && namespaceHolding[t.Namespace] >= m {
+			continue
+		}
+		holding++
+		namespaceHolding[t.Namespace]++
+		keys = append(keys, types.NamespacedName{Namespace: t.Namespace, Name: t.Name})
+	}
+	return keys
+}
+
+// admissionEventHandler feeds Task events into taskAdmission and wakes the next
+// Queued Tasks of an Agent as soon as a Running Task releases its slot
+// (completes, fails, is stopped, or is deleted).
+type admissionEventHandler struct {
+	admission *taskAdmission
+}
+
+var _ handler.TypedEventHandler[*kubeopenv1alpha1.Task, reconcile.Request] = &admissionEventHandler{}
+
+// Create implements handler.TypedEventHandler.
+func (h *admissionEventHandler) Create(_ context.Context, evt event.TypedCreateEvent[*kubeopenv1alpha1.Task], _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	h.admission.observe(evt.Object)
+}
+
+// Update implements handler.TypedEventHandler.
+func (h *admissionEventHandler) Update(_ context.Context, evt event.TypedUpdateEvent[*kubeopenv1alpha1.Task], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if agent, released := h.admission.observe(evt.ObjectNew); released {
+		h.wake(agent, q)
+	}
+}
+
+// Delete implements handler.TypedEventHandler.
+func (h *admissionEventHandler) Delete(_ context.Context, evt event.TypedDeleteEvent[*kubeopenv1alpha1.Task], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	key := types.NamespacedName{Namespace: evt.Object.Namespace, Name: evt.Object.Name}
+	if agent, released := h.admission.forget(key); released {
+		h.wake(agent, q)
+	}
+}
+
+// Generic implements handler.TypedEventHandler.
+func (h *admissionEventHandler) Generic(context.Context, event.TypedGenericEvent[*kubeopenv1alpha1.Task], workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+}
+
+// wake enqueues the Queued Tasks that can take the Agent's free slots.
+func (h *admissionEventHandler) wake(agent types.NamespacedName, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	for _, key := range h.admission.next(agent) {
+		q.Add(reconcile.Request{NamespacedName: key})
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// admissionTestTask returns a Task for agent "agent" in namespace "default".
+func admissionTestTask(name string, phase kubeopenv1alpha1.TaskPhase, priority int32, created time.Time) *kubeopenv1alpha1.Task {
+	return &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			UID:               types.UID(name),
+			CreationTimestamp: metav1.NewTime(created),
+			Labels:            map[string]string{AgentLabelKey: "agent"},
+		},
+		Spec:   kubeopenv1alpha1.TaskSpec{Priority: priority},
+		Status: kubeopenv1alpha1.TaskExecutionStatus{Phase: phase},
+	}
+}
+
+func TestTaskAdmission_CheckAndNext(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "default", Name: "agent"}
+	base := time.Now()
+
+	a := newTaskAdmission()
+	running := admissionTestTask("running", kubeopenv1alpha1.TaskPhaseRunning, 0, base)
+	low := admissionTestTask("low", kubeopenv1alpha1.TaskPhaseQueued, 0, base.Add(time.Second))
+	high := admissionTestTask("high", kubeopenv1alpha1.TaskPhaseQueued, 10, base.Add(2*time.Second))
+	for _, task := range []*kubeopenv1alpha1.Task{running, low, high} {
+		a.observe(task)
+	}
+
+	// At capacity: the high-priority Task is first in line
+	if c := a.check(high, agent, admissionLimits{maxConcurrent: 1}); c.admitted || c.position != 1 {
+		t.Errorf("check(high) = %v, position %d; want false, 1", c.admitted, c.position)
+	}
+	if c := a.check(low, agent, admissionLimits{maxConcurrent: 1}); c.admitted || c.position != 2 {
+		t.Errorf("check(low) = %v, position %d; want false, 2", c.admitted, c.position)
+	}
+	// A new Task does not jump the queue
+	newTask := admissionTestTask("new", "", 0, base.Add(3*time.Second))
+	if c := a.check(newTask, agent, admissionLimits{maxConcurrent: 1}); c.admitted || c.position != 3 {
+		t.Errorf("check(new) = %v, position %d; want false, 3", c.admitted, c.position)
+	}
+
+	// Completing the running Task releases its slot and wakes the high-priority Task
+	completed := running.DeepCopy()
+	completed.Status.Phase = kubeopenv1alpha1.TaskPhaseCompleted
+	gotAgent, released := a.observe(completed)
+	if !released || gotAgent != agent {
+		t.Fatalf("observe(completed) = %v, %v; want %v, true", gotAgent, released, agent)
+	}
+	next := a.next(agent)
+	if len(next) != 1 || next[0].Name != "high" {
+		t.Fatalf("next() = %v, want [high]", next)
+	}
+
+	// Once admitted, the reservation holds the slot until the Task is Running
+	if !a.tryReserve(high, agent, admissionLimits{maxConcurrent: 1}) {
+		t.Fatalf("tryReserve(high) = false, want true")
+	}
+	if a.check(low, agent, admissionLimits{maxConcurrent: 1}).admitted {
+		t.Errorf("check(low) = true while high holds the reservation, want false")
+	}
+	if next := a.next(agent); len(next) != 0 {
+		t.Errorf("next() = %v while at capacity, want none", next)
+	}
+
+	// An admitted Task observed with an empty phase keeps its reservation
+	initializing := high.DeepCopy()
+	initializing.Status.Phase = ""
+	if _, released := a.observe(initializing); released {
+		t.Errorf("observe(initializing) released the reservation")
+	}
+
+	// Deleting the reserved Task releases its slot
+	if _, released := a.forget(types.NamespacedName{Namespace: "default", Name: "high"}); !released {
+		t.Errorf("forget(high) did not release a slot")
+	}
+	if !a.check(low, agent, admissionLimits{maxConcurrent: 1}).admitted {
+		t.Errorf("check(low) = false after slots were released, want true")
+	}
+}
+
+func TestTaskAdmission_RebuildFromObservedTasks(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "default", Name: "agent"}
+	base := time.Now()
+
+	// A Task admitted from the queue by a previous leader still holds a slot
+	admitted := admissionTestTask("admitted", "", 0, base)
+	admitted.Status.Conditions = []metav1.Condition{{
+		Type:   kubeopenv1alpha1.ConditionTypeQueued,
+		Status: metav1.ConditionFalse,
+		Reason: kubeopenv1alpha1.ReasonCapacityAvailable,
+	}}
+	queued := admissionTestTask("queued", kubeopenv1alpha1.TaskPhaseQueued, 0, base.Add(time.Second))
+
+	a := newTaskAdmission()
+	a.observe(admitted)
+	a.observe(queued)
+
+	if c := a.check(queued, agent, admissionLimits{maxConcurrent: 1}); c.admitted || c.position != 1 || c.holding != 1 {
+		t.Errorf("check(queued) = %v, position %d, holding %d; want false, 1, 1", c.admitted, c.position, c.holding)
+	}
+}
+
+func TestTaskAdmission_CrossNamespace(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "platform", Name: "shared"}
+	base := time.Now()
+
+	// Tasks in tenant namespaces reference the shared Agent through status.agentRef
+	tenantTask := func(namespace, name string, phase kubeopenv1alpha1.TaskPhase, created time.Time) *kubeopenv1alpha1.Task {
+		task := admissionTestTask(name, phase, 0, created)
+		task.Namespace = namespace
+		task.Labels[AgentLabelKey] = agent.Name
+		task.Status.AgentRef = &kubeopenv1alpha1.AgentReference{Name: agent.Name, Namespace: agent.Namespace}
+		return task
+	}
+
+	a := newTaskAdmission()
+	a.observe(tenantTask("team-a", "task-a", kubeopenv1alpha1.TaskPhaseRunning, base))
+	a.observe(tenantTask("team-b", "task-b", kubeopenv1alpha1.TaskPhaseRunning, base))
+
+	newTask := tenantTask("team-c", "task-c", "", base.Add(time.Second))
+	if c := a.check(newTask, agent, admissionLimits{maxConcurrent: 2}); c.admitted || c.position != 1 || c.holding != 2 {
+		t.Errorf("check(task-c) = %v, position %d, holding %d; want false, 1, 2", c.admitted, c.position, c.holding)
+	}
+	if !a.check(newTask, agent, admissionLimits{maxConcurrent: 3}).admitted {
+		t.Errorf("check(task-c) with a free slot = false, want true")
+	}
+}
+
+func TestTaskAdmission_TryReserveHoldsLimit(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "default", Name: "agent"}
+	base := time.Now()
+
+	a := newTaskAdmission()
+	first := admissionTestTask("first", "", 0, base)
+	second := admissionTestTask("second", "", 0, base.Add(time.Second))
+
+	// Both Tasks pass the capacity check before either reserves the slot
+	if !a.check(first, agent, admissionLimits{maxConcurrent: 1}).admitted {
+		t.Fatalf("check(first) = false, want true")
+	}
+	if !a.check(second, agent, admissionLimits{maxConcurrent: 1}).admitted {
+		t.Fatalf("check(second) = false, want true")
+	}
+
+	// Only one of them can reserve it
+	if !a.tryReserve(first, agent, admissionLimits{maxConcurrent: 1}) {
+		t.Fatalf("tryReserve(first) = false, want true")
+	}
+	if a.tryReserve(second, agent, admissionLimits{maxConcurrent: 1}) {
+		t.Errorf("tryReserve(second) = true, want false")
+	}
+	// Reserving again is idempotent
+	if !a.tryReserve(first, agent, admissionLimits{maxConcurrent: 1}) {
+		t.Errorf("tryReserve(first) again = false, want true")
+	}
+}
+
+func TestTaskAdmission_FairShare(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "platform", Name: "shared"}
+	base := time.Now()
+	perNamespace := int32(1)
+	limits := admissionLimits{
+		maxConcurrent: 3,
+		fairShare:     &kubeopenv1alpha1.FairShareConfig{MaxConcurrentTasksPerNamespace: &perNamespace},
+	}
+
+	tenantTask := func(namespace, name string, phase kubeopenv1alpha1.TaskPhase, created time.Time) *kubeopenv1alpha1.Task {
+		task := admissionTestTask(name, phase, 0, created)
+		task.Namespace = namespace
+		task.Status.AgentRef = &kubeopenv1alpha1.AgentReference{Name: agent.Name, Namespace: agent.Namespace}
+		return task
+	}
+
+	// team-a floods the Agent: one Running, two Queued ahead of everyone else
+	a := newTaskAdmission()
+	a.observe(tenantTask("team-a", "a-1", kubeopenv1alpha1.TaskPhaseRunning, base))
+	a.observe(tenantTask("team-a", "a-2", kubeopenv1alpha1.TaskPhaseQueued, base.Add(time.Second)))
+	a.observe(tenantTask("team-a", "a-3", kubeopenv1alpha1.TaskPhaseQueued, base.Add(2*time.Second)))
+
+	// team-a's next Task waits for its own namespace share
+	a4 := tenantTask("team-a", "a-4", "", base.Add(3*time.Second))
+	if c := a.check(a4, agent, limits); c.admitted || !c.namespaceLimited || c.position != 3 {
+		t.Errorf("check(a-4) = %+v; want namespace-limited at position 3", c)
+	}
+
+	// team-b is not blocked by team-a's queue
+	b1 := tenantTask("team-b", "b-1", "", base.Add(4*time.Second))
+	if c := a.check(b1, agent, limits); !c.admitted {
+		t.Errorf("check(b-1) = %+v; want admitted", c)
+	}
+	if !a.tryReserve(b1, agent, limits) {
+		t.Fatalf("tryReserve(b-1) = false, want true")
+	}
+
+	// Only team-a's head is woken when its Running Task completes
+	completed := tenantTask("team-a", "a-1", kubeopenv1alpha1.TaskPhaseCompleted, base)
+	if _, released := a.observe(completed); !released {
+		t.Fatalf("observe(completed) released = false, want true")
+	}
+	next := a.next(agent)
+	if len(next) != 1 || next[0].Name != "a-2" {
+		t.Errorf("next() = %v, want [a-2]", next)
+	}
+
+	// A namespace override lifts the share of team-a
+	override := int32(0)
+	limits.fairShare.Namespaces = []kubeopenv1alpha1.NamespaceShare{{Namespace: "team-*", MaxConcurrentTasks: &override}}
+	a.check(a4, agent, limits)
+	next = a.next(agent)
+	if len(next) != 2 || next[0].Name != "a-2" || next[1].Name != "a-3" {
+		t.Errorf("next() with override = %v, want [a-2 a-3]", next)
+	}
+}
+
+func TestTaskAdmission_QuotaBlockedNotAhead(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "default", Name: "agent"}
+	base := time.Now()
+
+	a := newTaskAdmission()
+	blocked := admissionTestTask("blocked", kubeopenv1alpha1.TaskPhaseQueued, 0, base)
+	blocked.Status.Conditions = []metav1.Condition{{
+		Type:   kubeopenv1alpha1.ConditionTypeQueued,
+		Status: metav1.ConditionTrue,
+		Reason: kubeopenv1alpha1.ReasonNamespaceQuotaExceeded,
+	}}
+	a.observe(blocked)
+
+	// A Task waiting for quota does not take the only slot from a newer Task
+	newTask := admissionTestTask("new", "", 0, base.Add(time.Second))
+	if c := a.check(newTask, agent, admissionLimits{maxConcurrent: 1}); !c.admitted {
+		t.Errorf("check(new) = %+v; want admitted", c)
+	}
+	if next := a.next(agent); len(next) != 0 {
+		t.Errorf("next() = %v, want none", next)
+	}
+}
+
+func TestTaskAdmission_WaitingTasksNotAhead(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "default", Name: "agent"}
+	base := time.Now()
+
+	// Follow-up Tasks waiting for their sessionRef, and Tasks waiting for their
+	// Agent's server to be ready, do not hold back newer Tasks
+	for _, reason := range []string{kubeopenv1alpha1.ReasonWaitingForSession, kubeopenv1alpha1.ReasonServerStarting, kubeopenv1alpha1.ReasonServerNotReady} {
+		a := newTaskAdmission()
+		waiting := admissionTestTask("waiting", kubeopenv1alpha1.TaskPhaseQueued, 0, base)
+		waiting.Status.Conditions = []metav1.Condition{{
+			Type:   kubeopenv1alpha1.ConditionTypeQueued,
+			Status: metav1.ConditionTrue,
+			Reason: reason,
+		}}
+		a.observe(waiting)
+
+		newTask := admissionTestTask("new", "", 0, base.Add(time.Second))
+		if c := a.check(newTask, agent, admissionLimits{maxConcurrent: 1}); !c.admitted {
+			t.Errorf("%s: check(new) = %+v; want admitted", reason, c)
+		}
+	}
+}
+
+func TestTaskAdmission_ActiveSessions(t *testing.T) {
+	agent := types.NamespacedName{Namespace: "default", Name: "agent"}
+	base := time.Now()
+
+	a := newTaskAdmission()
+	running := admissionTestTask("running", kubeopenv1alpha1.TaskPhaseRunning, 0, base)
+	queued := admissionTestTask("queued", kubeopenv1alpha1.TaskPhaseQueued, 0, base.Add(time.Second))
+	for _, task := range []*kubeopenv1alpha1.Task{running, queued} {
+		a.observe(task)
+	}
+
+	// Fewer active sessions than Tasks holding a slot: the Tasks count
+	if c := a.check(queued, agent, admissionLimits{maxConcurrent: 2, activeSessions: 1}); !c.admitted {
+		t.Errorf("check(queued) with 1 active session = %v, want admitted", c.admitted)
+	}
+	// Sessions started on the server directly take the remaining slot
+	if c := a.check(queued, agent, admissionLimits{maxConcurrent: 2, activeSessions: 2}); c.admitted || c.position != 1 {
+		t.Errorf("check(queued) with 2 active sessions = %v, position %d; want false, 1", c.admitted, c.position)
+	}
+	if got := a.next(agent); len(got) != 0 {
+		t.Errorf("next() = %v, want none while the sessions use all slots", got)
+	}
+
+	// The sessions finish: the next check frees the slot
+	if c := a.check(queued, agent, admissionLimits{maxConcurrent: 2}); !c.admitted {
+		t.Errorf("check(queued) without active sessions = %v, want admitted", c.admitted)
+	}
+	if got := a.next(agent); len(got) != 1 || got[0].Name != "queued" {
+		t.Errorf("next() = %v, want [queued]", got)
+	}
+}
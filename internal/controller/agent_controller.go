@@ -4,7 +4,12 @@ package controller
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -16,6 +21,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
@@ -29,6 +35,9 @@ const (
 	// In the Pod-based approach, this is based on Deployment readiness rather than HTTP health checks.
 	AgentConditionServerHealthy = "ServerHealthy"
 
+	// AgentReasonScaledToZero is the ServerReady reason of a server scaled down after being idle.
+	AgentReasonScaledToZero = "ScaledToZero"
+
 	// DefaultServerReconcileInterval is how often to reconcile Server-mode Agents.
 	DefaultServerReconcileInterval = 30 * time.Second
 )
@@ -38,12 +47,22 @@ const (
 type AgentReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// OpenCode is used to collect session statistics from the servers of
+	// Server-mode Agents. Defaults to the OpenCode HTTP API when nil.
+	OpenCode OpenCodeClient
 }
 
 // +kubebuilder:rbac:groups=kubeopencode.io,resources=agents,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=kubeopencode.io,resources=agents/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=kubeopencode.io,resources=kubeopencodeconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kubeopencode.io,resources=tasks,verbs=get;list;watch
 
 // Reconcile handles Agent reconciliation.
 // For Server-mode Agents, it ensures the Deployment and Service exist and are up-to-date.
@@ -55,6 +74,7 @@ func (r *AgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	if err := r.Get(ctx, req.NamespacedName, &agent); err != nil {
 		if apierrors.IsNotFound(err) {
 			// Agent was deleted, nothing to do (Deployment/Service will be garbage collected)
+			deleteServerSessionMetrics(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		logger.Error(err, "Failed to get Agent")
@@ -74,14 +94,69 @@ func (r *AgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	logger.Info("Reconciling Server-mode Agent", "agent", agent.Name)
 
 	// Resolve agent configuration
+	// The server Pod runs in the Agent's namespace, so system configuration and
+	// contexts are resolved from there, like for Pod-mode Tasks of this Agent
 	agentCfg := r.resolveAgentConfig(&agent)
-	sysCfg := systemConfig{
-		systemImage:           DefaultKubeOpenCodeImage,
-		systemImagePullPolicy: corev1.PullIfNotPresent,
+	sysCfg := getSystemConfig(ctx, r, agent.Namespace)
+
+	// Invalid configuration is surfaced in status until the Agent is fixed
+	if err := validateServerWorkspace(agent.Spec.ServerConfig); err != nil {
+		logger.Error(err, "Invalid server workspace")
+		setAgentCondition(&agent, AgentConditionServerReady, metav1.ConditionFalse, "WorkspaceError", err.Error())
+		return ctrl.Result{}, r.Status().Update(ctx, &agent)
+	}
+
+	contexts, err := r.resolveServerContexts(ctx, &agent, agentCfg)
+	if err != nil {
+		// Context errors are user configuration issues, surface them and wait for a fix
+		logger.Error(err, "Failed to resolve Agent contexts")
+		setAgentCondition(&agent, AgentConditionServerReady, metav1.ConditionFalse, "ContextError", err.Error())
+		if updateErr := r.Status().Update(ctx, &agent); updateErr != nil {
+			return ctrl.Result{}, updateErr
+		}
+		return ctrl.Result{RequeueAfter: DefaultServerReconcileInterval}, nil
+	}
+
+	// Reconcile the context ConfigMap
+	if err := r.reconcileContextConfigMap(ctx, &agent, contexts.configMap); err != nil {
+		logger.Error(err, "Failed to reconcile context ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	configHash, err := r.serverConfigHash(ctx, &agent, contexts.configMap)
+	if err != nil {
+		logger.Error(err, "Failed to compute server config hash")
+		return ctrl.Result{}, err
+	}
+
+	// Reconcile the persistent workspace before the Deployment that mounts it
+	if err := r.reconcileWorkspacePVC(ctx, &agent); err != nil {
+		logger.Error(err, "Failed to reconcile workspace PersistentVolumeClaim")
+		return ctrl.Result{}, err
+	}
+	if len(contexts.gitMounts) > 0 {
+		if err := ensureGitCachePVC(ctx, r.Client, agent.Namespace, sysCfg.gitCache); err != nil {
+			logger.Error(err, "Failed to ensure Git cache PersistentVolumeClaim")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The workspace-sync sidecar and Task Pods read the token from the Secret
+	if err := r.reconcileSyncTokenSecret(ctx, &agent); err != nil {
+		logger.Error(err, "Failed to reconcile workspace-sync token Secret")
+		return ctrl.Result{}, err
+	}
+
+	// Scale idle servers down to zero, and back up when Tasks arrive
+	replicas, idleRequeue, err := r.serverReplicas(ctx, &agent)
+	if err != nil {
+		logger.Error(err, "Failed to determine server replicas")
+		return ctrl.Result{}, err
 	}
 
 	// Reconcile the Deployment
-	if err := r.reconcileDeployment(ctx, &agent, agentCfg, sysCfg); err != nil {
+	drainRequeue, err := r.reconcileDeployment(ctx, &agent, agentCfg, sysCfg, contexts, configHash, replicas)
+	if err != nil {
 		logger.Error(err, "Failed to reconcile Deployment")
 		return ctrl.Result{}, err
 	}
@@ -93,18 +168,26 @@ func (r *AgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	}
 
 	// Update Agent status
-	if err := r.updateAgentStatus(ctx, &agent); err != nil {
+	if err := r.updateAgentStatus(ctx, &agent, replicas); err != nil {
 		logger.Error(err, "Failed to update Agent status")
 		return ctrl.Result{}, err
 	}
 
-	// Requeue periodically to check server health
-	return ctrl.Result{RequeueAfter: DefaultServerReconcileInterval}, nil
+	// Requeue periodically to check server health, or earlier to scale down an idle
+	// server or to end a drain
+	requeue := DefaultServerReconcileInterval
+	for _, d := range []time.Duration{idleRequeue, drainRequeue} {
+		if d > 0 && d < requeue {
+			requeue = d
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
 }
 
 // resolveAgentConfig extracts configuration from the Agent spec.
 func (r *AgentReconciler) resolveAgentConfig(agent *kubeopenv1alpha1.Agent) agentConfig {
 	cfg := agentConfig{
+		name:               agent.Name,
 		agentImage:         agent.Spec.AgentImage,
 		executorImage:      agent.Spec.ExecutorImage,
 		attachImage:        agent.Spec.AttachImage,
@@ -117,6 +200,7 @@ func (r *AgentReconciler) resolveAgentConfig(agent *kubeopenv1alpha1.Agent) agen
 		serviceAccountName: agent.Spec.ServiceAccountName,
 		maxConcurrentTasks: agent.Spec.MaxConcurrentTasks,
 		quota:              agent.Spec.Quota,
+		fairShare:          agent.Spec.FairShare,
 	}
 
 	// Apply defaults
@@ -133,12 +217,72 @@ func (r *AgentReconciler) resolveAgentConfig(agent *kubeopenv1alpha1.Agent) agen
 	return cfg
 }
 
-// reconcileDeployment ensures the Deployment exists and is up-to-date.
-func (r *AgentReconciler) reconcileDeployment(ctx context.Context, agent *kubeopenv1alpha1.Agent, agentCfg agentConfig, sysCfg systemConfig) error {
+// resolveServerContexts resolves the Agent's contexts and OpenCode config for the
+// server Pod. Content is collected into the ConfigMap returned in serverContexts
+// (nil if there is none), which context-init copies into the workspace.
+func (r *AgentReconciler) resolveServerContexts(ctx context.Context, agent *kubeopenv1alpha1.Agent, agentCfg agentConfig) (serverContexts, error) {
+	resolved, dirMounts, gitMounts, urlMounts, err := resolveContextItems(ctx, r, "Agent", agentCfg.contexts, agent.Namespace, agentCfg.workspaceDir)
+	if err != nil {
+		return serverContexts{}, err
+	}
+	useServerRuntimePrompt(resolved)
+
+	// The server has no task description; Tasks send their prompt through --attach
+	configMapData, fileMounts, err := buildContextFiles(resolved, "", agentCfg)
+	if err != nil {
+		return serverContexts{}, err
+	}
+
+	if err := validateMountPathConflicts(fileMounts, dirMounts, gitMounts, urlMounts); err != nil {
+		return serverContexts{}, err
+	}
+
+	var configMap *corev1.ConfigMap
+	if len(configMapData) > 0 {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ServerContextConfigMapName(agent.Name),
+				Namespace: agent.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/name":       "kubeopencode-server",
+					"app.kubernetes.io/instance":   agent.Name,
+					"app.kubernetes.io/component":  "server",
+					"app.kubernetes.io/managed-by": "kubeopencode",
+				},
+			},
+			Data: configMapData,
+		}
+	}
+
+	return serverContexts{
+		configMap:  configMap,
+		fileMounts: fileMounts,
+		dirMounts:  dirMounts,
+		gitMounts:  gitMounts,
+		urlMounts:  urlMounts,
+	}, nil
+}
+
+// reconcileContextConfigMap ensures the context ConfigMap of the server matches the
+// desired content, and deletes it when the Agent no longer has any.
+func (r *AgentReconciler) reconcileContextConfigMap(ctx context.Context, agent *kubeopenv1alpha1.Agent, desired *corev1.ConfigMap) error {
 	logger := log.FromContext(ctx)
 
-	desired := BuildServerDeployment(agent, agentCfg, sysCfg)
+	var existing corev1.ConfigMap
+	key := client.ObjectKey{Namespace: agent.Namespace, Name: ServerContextConfigMapName(agent.Name)}
+	err := r.Get(ctx, key, &existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get context ConfigMap: %w", err)
+	}
+	exists := err == nil
+
 	if desired == nil {
+		if exists {
+			logger.Info("Deleting context ConfigMap for Server-mode Agent", "configmap", key.Name)
+			if err := r.Delete(ctx, &existing); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete context ConfigMap: %w", err)
+			}
+		}
 		return nil
 	}
 
@@ -147,29 +291,247 @@ func (r *AgentReconciler) reconcileDeployment(ctx context.Context, agent *kubeop
 		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
+	if !exists {
+		logger.Info("Creating context ConfigMap for Server-mode Agent", "configmap", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create context ConfigMap: %w", err)
+		}
+		return nil
+	}
+
+	existing.Data = desired.Data
+	existing.Labels = desired.Labels
+	if err := r.Update(ctx, &existing); err != nil {
+		return fmt.Errorf("failed to update context ConfigMap: %w", err)
+	}
+	return nil
+}
+
+// serverConfigHash returns a hash of the content the server Pod is built from that
+// is not part of the Deployment spec: the context ConfigMap and the Secrets and
+// ConfigMaps referenced by the Agent. Missing objects are hashed as missing, so the
+// server is rolled out again once they are created.
+func (r *AgentReconciler) serverConfigHash(ctx context.Context, agent *kubeopenv1alpha1.Agent, contextConfigMap *corev1.ConfigMap) (string, error) {
+	hash := sha256.New()
+	writeData := func(kind, name string, data map[string]string) {
+		fmt.Fprintf(hash, "%s/%s\n", kind, name)
+		keys := slices.Sorted(maps.Keys(data))
+		for _, k := range keys {
+			fmt.Fprintf(hash, "%s=%q\n", k, data[k])
+		}
+	}
+
+	if contextConfigMap != nil {
+		writeData("context", contextConfigMap.Name, contextConfigMap.Data)
+	}
+
+	secrets, configMaps := serverAgentReferences(agent)
+	for _, name := range secrets {
+		var secret corev1.Secret
+		if err := r.Get(ctx, client.ObjectKey{Namespace: agent.Namespace, Name: name}, &secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return "", fmt.Errorf("failed to get Secret %q: %w", name, err)
+			}
+			fmt.Fprintf(hash, "secret/%s missing\n", name)
+			continue
+		}
+		data := make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+		writeData("secret", name, data)
+	}
+	for _, name := range configMaps {
+		var cm corev1.ConfigMap
+		if err := r.Get(ctx, client.ObjectKey{Namespace: agent.Namespace, Name: name}, &cm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return "", fmt.Errorf("failed to get ConfigMap %q: %w", name, err)
+			}
+			fmt.Fprintf(hash, "configmap/%s missing\n", name)
+			continue
+		}
+		writeData("configmap", name, cm.Data)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// serverAgentReferences returns the sorted names of the Secrets (credentials, git and
+// URL context secrets) and ConfigMaps (ConfigMap contexts) an Agent references.
+func serverAgentReferences(agent *kubeopenv1alpha1.Agent) ([]string, []string) {
+	secrets := make(map[string]bool)
+	configMaps := make(map[string]bool)
+
+	for _, cred := range agent.Spec.Credentials {
+		secrets[cred.SecretRef.Name] = true
+	}
+	for _, item := range agent.Spec.Contexts {
+		switch {
+		case item.ConfigMap != nil && item.ConfigMap.Name != "":
+			configMaps[item.ConfigMap.Name] = true
+		case item.Git != nil && item.Git.SecretRef != nil:
+			secrets[item.Git.SecretRef.Name] = true
+		case item.URL != nil && item.URL.SecretRef != nil:
+			secrets[item.URL.SecretRef.Name] = true
+		}
+	}
+
+	return slices.Sorted(maps.Keys(secrets)), slices.Sorted(maps.Keys(configMaps))
+}
+
+// reconcileDeployment ensures the Deployment exists, is up-to-date, and runs the
+// given number of replicas. A change to the server Pod is held back while the Agent
+// drains its Running Tasks (see drainServer); the returned duration is when to check
+// the drain again, or zero if the Deployment is up-to-date.
+func (r *AgentReconciler) reconcileDeployment(ctx context.Context, agent *kubeopenv1alpha1.Agent, agentCfg agentConfig, sysCfg systemConfig, contexts serverContexts, configHash string, replicas int32) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	desired := BuildServerDeployment(agent, agentCfg, sysCfg, contexts, configHash)
+	if desired == nil {
+		return 0, nil
+	}
+	desired.Spec.Replicas = &replicas
+
+	templateHash, err := podTemplateHash(&desired.Spec.Template)
+	if err != nil {
+		return 0, err
+	}
+	desired.Annotations = map[string]string{ServerTemplateHashAnnotation: templateHash}
+
+	// Set owner reference for garbage collection
+	if err := controllerutil.SetControllerReference(agent, desired, r.Scheme); err != nil {
+		return 0, fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
 	// Check if Deployment exists
 	var existing appsv1.Deployment
-	err := r.Get(ctx, client.ObjectKey{Namespace: desired.Namespace, Name: desired.Name}, &existing)
+	err = r.Get(ctx, client.ObjectKey{Namespace: desired.Namespace, Name: desired.Name}, &existing)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// Create the Deployment
 			logger.Info("Creating Deployment for Server-mode Agent", "deployment", desired.Name)
 			if err := r.Create(ctx, desired); err != nil {
-				return fmt.Errorf("failed to create Deployment: %w", err)
+				return 0, fmt.Errorf("failed to create Deployment: %w", err)
 			}
-			return nil
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get Deployment: %w", err)
+	}
+
+	if existing.Annotations[ServerTemplateHashAnnotation] != templateHash {
+		// Replacing the server Pods ends the sessions of Running Tasks, so the change
+		// waits for them to finish. Scaling does not replace Pods and is not held back.
+		running, err := r.runningServerTasks(ctx, agent)
+		if err != nil {
+			return 0, err
 		}
-		return fmt.Errorf("failed to get Deployment: %w", err)
+		if rollout, requeue := drainServer(agent, running, time.Now()); !rollout {
+			logger.Info("Draining Server-mode Agent before rolling out changes", "deployment", desired.Name, "runningTasks", running)
+			existing.Spec.Replicas = desired.Spec.Replicas
+			if err := r.Update(ctx, &existing); err != nil {
+				return 0, fmt.Errorf("failed to update Deployment: %w", err)
+			}
+			return requeue, nil
+		}
+		logger.Info("Rolling out changes to Server-mode Agent", "deployment", desired.Name)
+	} else if meta.IsStatusConditionTrue(agent.Status.Conditions, AgentConditionDraining) {
+		// The change was reverted while draining
+		setAgentCondition(agent, AgentConditionDraining, metav1.ConditionFalse, AgentReasonDrained, "No server change left to roll out")
 	}
 
-	// Update the Deployment if needed
-	// For now, we do a simple update of the spec
 	existing.Spec = desired.Spec
 	existing.Labels = desired.Labels
+	if existing.Annotations == nil {
+		existing.Annotations = make(map[string]string)
+	}
+	existing.Annotations[ServerTemplateHashAnnotation] = templateHash
 	if err := r.Update(ctx, &existing); err != nil {
-		return fmt.Errorf("failed to update Deployment: %w", err)
+		return 0, fmt.Errorf("failed to update Deployment: %w", err)
 	}
 
+	return 0, nil
+}
+
+// reconcileWorkspacePVC ensures the PersistentVolumeClaim of a persistent server
+// workspace exists, and deletes it when the Agent no longer has one. The claim spec is
+// immutable except for the requested size, which is only ever increased.
+func (r *AgentReconciler) reconcileWorkspacePVC(ctx context.Context, agent *kubeopenv1alpha1.Agent) error {
+	logger := log.FromContext(ctx)
+
+	var existing corev1.PersistentVolumeClaim
+	key := client.ObjectKey{Namespace: agent.Namespace, Name: ServerWorkspacePVCName(agent.Name)}
+	err := r.Get(ctx, key, &existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get workspace PersistentVolumeClaim: %w", err)
+	}
+	exists := err == nil
+
+	desired := BuildServerWorkspacePVC(agent)
+	if desired == nil {
+		if exists && metav1.IsControlledBy(&existing, agent) {
+			logger.Info("Deleting workspace PersistentVolumeClaim for Server-mode Agent", "pvc", key.Name)
+			if err := r.Delete(ctx, &existing); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete workspace PersistentVolumeClaim: %w", err)
+			}
+		}
+		return nil
+	}
+
+	// Set owner reference for garbage collection
+	if err := controllerutil.SetControllerReference(agent, desired, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	if !exists {
+		logger.Info("Creating workspace PersistentVolumeClaim for Server-mode Agent", "pvc", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create workspace PersistentVolumeClaim: %w", err)
+		}
+		return nil
+	}
+
+	desiredSize := desired.Spec.Resources.Requests[corev1.ResourceStorage]
+	currentSize := existing.Spec.Resources.Requests[corev1.ResourceStorage]
+	if desiredSize.Cmp(currentSize) <= 0 {
+		return nil
+	}
+	logger.Info("Expanding workspace PersistentVolumeClaim for Server-mode Agent", "pvc", key.Name, "size", desiredSize.String())
+	if existing.Spec.Resources.Requests == nil {
+		existing.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	existing.Spec.Resources.Requests[corev1.ResourceStorage] = desiredSize
+	if err := r.Update(ctx, &existing); err != nil {
+		return fmt.Errorf("failed to expand workspace PersistentVolumeClaim: %w", err)
+	}
+	return nil
+}
+
+// reconcileSyncTokenSecret creates the Secret holding the token that authorizes
+// uploads and deletions of Task workspaces on the workspace-sync sidecar, if it does
+// not exist yet. The token is generated once and kept for the lifetime of the Agent.
+func (r *AgentReconciler) reconcileSyncTokenSecret(ctx context.Context, agent *kubeopenv1alpha1.Agent) error {
+	var existing corev1.Secret
+	key := client.ObjectKey{Namespace: agent.Namespace, Name: ServerSyncTokenSecretName(agent.Name)}
+	err := r.Get(ctx, key, &existing)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get workspace-sync token Secret: %w", err)
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return fmt.Errorf("failed to generate workspace-sync token: %w", err)
+	}
+	desired := BuildServerSyncTokenSecret(agent, hex.EncodeToString(token))
+	if err := controllerutil.SetControllerReference(agent, desired, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+	log.FromContext(ctx).Info("Creating workspace-sync token Secret for Server-mode Agent", "secret", desired.Name)
+	if err := r.Create(ctx, desired); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create workspace-sync token Secret: %w", err)
+	}
 	return nil
 }
 
@@ -214,10 +576,12 @@ func (r *AgentReconciler) reconcileService(ctx context.Context, agent *kubeopenv
 	return nil
 }
 
-// updateAgentStatus updates the Agent's status with server information.
-// Health is determined by Deployment readiness (liveness/readiness probes on the Deployment
-// already check the server's /session/status endpoint).
-func (r *AgentReconciler) updateAgentStatus(ctx context.Context, agent *kubeopenv1alpha1.Agent) error {
+// updateAgentStatus updates the Agent's status with server information and session
+// statistics. Health is determined by Deployment readiness (liveness/readiness probes on
+// the Deployment already check the server's /session/status endpoint). replicas is the
+// desired number of replicas; a server being scaled down to zero is reported as not ready
+// right away, so no new Task attaches to it.
+func (r *AgentReconciler) updateAgentStatus(ctx context.Context, agent *kubeopenv1alpha1.Agent, replicas int32) error {
 	// Get the Deployment to check ready replicas
 	var deployment appsv1.Deployment
 	deploymentName := ServerDeploymentName(agent.Name)
@@ -225,11 +589,16 @@ func (r *AgentReconciler) updateAgentStatus(ctx context.Context, agent *kubeopen
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// Deployment not found yet, set status to pending
+			var lastActiveTime *metav1.Time
+			if agent.Status.ServerStatus != nil {
+				lastActiveTime = agent.Status.ServerStatus.LastActiveTime
+			}
 			agent.Status.ServerStatus = &kubeopenv1alpha1.ServerStatus{
 				DeploymentName: deploymentName,
 				ServiceName:    ServerServiceName(agent.Name),
 				URL:            ServerURL(agent.Name, agent.Namespace, GetServerPort(agent)),
 				ReadyReplicas:  0,
+				LastActiveTime: lastActiveTime,
 			}
 		} else {
 			return fmt.Errorf("failed to get Deployment: %w", err)
@@ -243,6 +612,9 @@ func (r *AgentReconciler) updateAgentStatus(ctx context.Context, agent *kubeopen
 		agent.Status.ServerStatus.ServiceName = ServerServiceName(agent.Name)
 		agent.Status.ServerStatus.URL = ServerURL(agent.Name, agent.Namespace, GetServerPort(agent))
 		agent.Status.ServerStatus.ReadyReplicas = deployment.Status.ReadyReplicas
+		if replicas == 0 {
+			agent.Status.ServerStatus.ReadyReplicas = 0
+		}
 
 		// Server health is determined by Deployment readiness
 		// The Deployment's readiness probe checks /session/status endpoint
@@ -254,10 +626,15 @@ func (r *AgentReconciler) updateAgentStatus(ctx context.Context, agent *kubeopen
 	// Set ServerReady condition based on ready replicas
 	if agent.Status.ServerStatus.ReadyReplicas > 0 {
 		setAgentCondition(agent, AgentConditionServerReady, metav1.ConditionTrue, "DeploymentReady", "Server deployment has ready replicas")
+	} else if replicas == 0 {
+		setAgentCondition(agent, AgentConditionServerReady, metav1.ConditionFalse, AgentReasonScaledToZero, "Server scaled down after being idle")
 	} else {
 		setAgentCondition(agent, AgentConditionServerReady, metav1.ConditionFalse, "DeploymentNotReady", "Server deployment has no ready replicas")
 	}
 
+	// Poll the server for its sessions
+	r.updateSessionStats(ctx, agent)
+
 	// Update observed generation
 	agent.Status.ObservedGeneration = agent.Generation
 
@@ -269,7 +646,9 @@ func (r *AgentReconciler) updateAgentStatus(ctx context.Context, agent *kubeopen
 	return nil
 }
 
-// cleanupServerResources removes Deployment and Service if they exist.
+// cleanupServerResources removes the server resources (Deployment, Service, context
+// ConfigMap, workspace-sync token Secret, and workspace PersistentVolumeClaim) if they
+// exist.
 // This is called when an Agent is changed from Server-mode to Pod-mode.
 func (r *AgentReconciler) cleanupServerResources(ctx context.Context, agent *kubeopenv1alpha1.Agent) error {
 	logger := log.FromContext(ctx)
@@ -284,6 +663,26 @@ func (r *AgentReconciler) cleanupServerResources(ctx context.Context, agent *kub
 		}
 	}
 
+	// Delete context ConfigMap if exists
+	if err := r.reconcileContextConfigMap(ctx, agent, nil); err != nil {
+		return err
+	}
+
+	// Delete the persistent workspace if exists
+	if err := r.reconcileWorkspacePVC(ctx, agent); err != nil {
+		return err
+	}
+
+	// Delete the workspace-sync token if exists
+	var tokenSecret corev1.Secret
+	tokenKey := client.ObjectKey{Namespace: agent.Namespace, Name: ServerSyncTokenSecretName(agent.Name)}
+	if err := r.Get(ctx, tokenKey, &tokenSecret); err == nil && metav1.IsControlledBy(&tokenSecret, agent) {
+		logger.Info("Cleaning up stale workspace-sync token Secret", "secret", tokenKey.Name)
+		if err := r.Delete(ctx, &tokenSecret); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete workspace-sync token Secret: %w", err)
+		}
+	}
+
 	// Delete Service if exists
 	serviceName := ServerServiceName(agent.Name)
 	var service corev1.Service
@@ -295,6 +694,7 @@ func (r *AgentReconciler) cleanupServerResources(ctx context.Context, agent *kub
 	}
 
 	// Clear server status if present
+	deleteServerSessionMetrics(agent.Namespace, agent.Name)
 	if agent.Status.ServerStatus != nil {
 		agent.Status.ServerStatus = nil
 		if err := r.Status().Update(ctx, agent); err != nil {
@@ -316,11 +716,53 @@ func setAgentCondition(agent *kubeopenv1alpha1.Agent, conditionType string, stat
 	})
 }
 
+// serverAgentsForObject maps a Secret, ConfigMap, or KubeOpenCodeConfig to the
+// Server-mode Agents in its namespace whose server Pod is built from it.
+func (r *AgentReconciler) serverAgentsForObject(ctx context.Context, obj client.Object) []ctrl.Request {
+	logger := log.FromContext(ctx).WithName("serverAgentsForObject")
+
+	var agents kubeopenv1alpha1.AgentList
+	if err := r.List(ctx, &agents, client.InNamespace(obj.GetNamespace())); err != nil {
+		logger.Error(err, "Failed to list Agents", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for i := range agents.Items {
+		agent := &agents.Items[i]
+		if !IsServerMode(agent) {
+			continue
+		}
+		secrets, configMaps := serverAgentReferences(agent)
+		switch obj.(type) {
+		case *corev1.Secret:
+			if !slices.Contains(secrets, obj.GetName()) {
+				continue
+			}
+		case *corev1.ConfigMap:
+			if !slices.Contains(configMaps, obj.GetName()) {
+				continue
+			}
+		}
+		requests = append(requests, ctrl.Request{
+			NamespacedName: client.ObjectKeyFromObject(agent),
+		})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *AgentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kubeopenv1alpha1.Agent{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		// Scale idle servers up for new Tasks and down after the last one finishes
+		Watches(&kubeopenv1alpha1.Task{}, handler.EnqueueRequestsFromMapFunc(r.agentForTask)).
+		// Roll out the server when referenced Secrets, ConfigMaps, or the system configuration change
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.serverAgentsForObject)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.serverAgentsForObject)).
+		Watches(&kubeopenv1alpha1.KubeOpenCodeConfig{}, handler.EnqueueRequestsFromMapFunc(r.serverAgentsForObject)).
 		Complete(r)
 }
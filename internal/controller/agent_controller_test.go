@@ -11,6 +11,8 @@ import (
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -205,6 +207,305 @@ var _ = Describe("AgentController", func() {
 		})
 	})
 
+	Context("When a Server-mode Agent has credentials and contexts", func() {
+		It("Should mount them and roll out the server when a referenced Secret changes", func() {
+			agentName := "test-server-context-agent"
+			secretName := "test-server-context-secret"
+
+			By("Creating the credential Secret")
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: agentNamespace,
+				},
+				StringData: map[string]string{"token": "v1"},
+			}
+			Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
+
+			By("Creating a Server-mode Agent with a credential and a Text context")
+			envName := "API_TOKEN"
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: agentNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ExecutorImage:      "quay.io/kubeopencode/kubeopencode-agent-devbox:latest",
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					Credentials: []kubeopenv1alpha1.Credential{
+						{
+							Name:      "api-token",
+							SecretRef: kubeopenv1alpha1.SecretReference{Name: secretName, Key: stringPtr("token")},
+							Env:       &envName,
+						},
+					},
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Type:      kubeopenv1alpha1.ContextTypeText,
+							Text:      "# Coding Standards",
+							MountPath: "guides/standards.md",
+						},
+					},
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			By("Expecting the context ConfigMap to be created")
+			Eventually(func() error {
+				var cm corev1.ConfigMap
+				return k8sClient.Get(ctx, types.NamespacedName{
+					Name:      ServerContextConfigMapName(agentName),
+					Namespace: agentNamespace,
+				}, &cm)
+			}, timeout, interval).Should(Succeed())
+
+			By("Expecting the Deployment to carry the credential and a config hash")
+			deploymentKey := types.NamespacedName{Name: ServerDeploymentName(agentName), Namespace: agentNamespace}
+			var initialHash string
+			Eventually(func() string {
+				var deployment appsv1.Deployment
+				if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil {
+					return ""
+				}
+				initialHash = deployment.Spec.Template.Annotations[ServerConfigHashAnnotation]
+				return initialHash
+			}, timeout, interval).ShouldNot(BeEmpty())
+
+			var deployment appsv1.Deployment
+			Expect(k8sClient.Get(ctx, deploymentKey, &deployment)).Should(Succeed())
+			Expect(deployment.Spec.Template.Spec.InitContainers).NotTo(BeEmpty())
+			var foundEnv bool
+			for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == envName && env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+					foundEnv = env.ValueFrom.SecretKeyRef.Name == secretName
+				}
+			}
+			Expect(foundEnv).To(BeTrue())
+
+			By("Updating the referenced Secret")
+			var updatedSecret corev1.Secret
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: secretName, Namespace: agentNamespace}, &updatedSecret)).Should(Succeed())
+			updatedSecret.Data = map[string][]byte{"token": []byte("v2")}
+			Expect(k8sClient.Update(ctx, &updatedSecret)).Should(Succeed())
+
+			By("Expecting the config hash to change")
+			Eventually(func() string {
+				var deployment appsv1.Deployment
+				if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil {
+					return initialHash
+				}
+				return deployment.Spec.Template.Annotations[ServerConfigHashAnnotation]
+			}, timeout, interval).ShouldNot(Equal(initialHash))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, &updatedSecret)).Should(Succeed())
+		})
+	})
+
+	Context("When a Server-mode Agent has a persistent workspace", func() {
+		It("Should provision, expand and delete the workspace PersistentVolumeClaim", func() {
+			agentName := "test-persistent-workspace-agent"
+
+			By("Creating a Server-mode Agent with a persistent workspace")
+			storageClass := "fast"
+			size := resource.MustParse("5Gi")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: agentNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						Workspace: &kubeopenv1alpha1.ServerWorkspace{
+							StorageClassName: &storageClass,
+							Size:             &size,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			By("Expecting the PersistentVolumeClaim to be created and owned by the Agent")
+			pvcKey := types.NamespacedName{Name: ServerWorkspacePVCName(agentName), Namespace: agentNamespace}
+			var pvc corev1.PersistentVolumeClaim
+			Eventually(func() error {
+				return k8sClient.Get(ctx, pvcKey, &pvc)
+			}, timeout, interval).Should(Succeed())
+			Expect(pvc.Spec.StorageClassName).To(Equal(&storageClass))
+			Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("5Gi"))
+			Expect(pvc.OwnerReferences).To(HaveLen(1))
+			Expect(pvc.OwnerReferences[0].Name).To(Equal(agentName))
+
+			By("Expecting the Deployment to mount the claim as workspace")
+			deploymentKey := types.NamespacedName{Name: ServerDeploymentName(agentName), Namespace: agentNamespace}
+			var deployment appsv1.Deployment
+			Eventually(func() error {
+				return k8sClient.Get(ctx, deploymentKey, &deployment)
+			}, timeout, interval).Should(Succeed())
+			Expect(deployment.Spec.Strategy.Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
+			var claimName string
+			for _, v := range deployment.Spec.Template.Spec.Volumes {
+				if v.Name == "workspace" && v.PersistentVolumeClaim != nil {
+					claimName = v.PersistentVolumeClaim.ClaimName
+				}
+			}
+			Expect(claimName).To(Equal(pvcKey.Name))
+
+			By("Increasing the workspace size")
+			var updatedAgent kubeopenv1alpha1.Agent
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: agentName, Namespace: agentNamespace}, &updatedAgent)).Should(Succeed())
+			newSize := resource.MustParse("20Gi")
+			updatedAgent.Spec.ServerConfig.Workspace.Size = &newSize
+			Expect(k8sClient.Update(ctx, &updatedAgent)).Should(Succeed())
+
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, pvcKey, &pvc); err != nil {
+					return ""
+				}
+				return pvc.Spec.Resources.Requests.Storage().String()
+			}, timeout, interval).Should(Equal("20Gi"))
+
+			By("Removing the persistent workspace")
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: agentName, Namespace: agentNamespace}, &updatedAgent)).Should(Succeed())
+			updatedAgent.Spec.ServerConfig.Workspace = nil
+			Expect(k8sClient.Update(ctx, &updatedAgent)).Should(Succeed())
+
+			// The claim may be held by the pvc-protection finalizer, which no controller removes in envtest
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, pvcKey, &pvc)
+				return apierrors.IsNotFound(err) || (err == nil && pvc.DeletionTimestamp != nil)
+			}, timeout, interval).Should(BeTrue())
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil {
+					return false
+				}
+				for _, v := range deployment.Spec.Template.Spec.Volumes {
+					if v.Name == "workspace" {
+						return v.EmptyDir != nil
+					}
+				}
+				return false
+			}, timeout, interval).Should(BeTrue())
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, &updatedAgent)).Should(Succeed())
+		})
+
+		It("Should require ReadWriteMany for a workspace shared by several replicas", func() {
+			agentName := "test-shared-workspace-agent"
+
+			By("Creating a Server-mode Agent with two replicas and a ReadWriteOnce workspace")
+			replicas := int32(2)
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: agentNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						Replicas:  &replicas,
+						Workspace: &kubeopenv1alpha1.ServerWorkspace{},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			By("Expecting the Agent to report the invalid workspace and no Deployment")
+			agentKey := types.NamespacedName{Name: agentName, Namespace: agentNamespace}
+			Eventually(func() string {
+				var a kubeopenv1alpha1.Agent
+				if err := k8sClient.Get(ctx, agentKey, &a); err != nil {
+					return ""
+				}
+				for _, c := range a.Status.Conditions {
+					if c.Type == AgentConditionServerReady && c.Status == metav1.ConditionFalse {
+						return c.Reason
+					}
+				}
+				return ""
+			}, timeout, interval).Should(Equal("WorkspaceError"))
+			deploymentKey := types.NamespacedName{Name: ServerDeploymentName(agentName), Namespace: agentNamespace}
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, deploymentKey, &appsv1.Deployment{}))).To(BeTrue())
+
+			By("Switching the workspace to ReadWriteMany")
+			var updatedAgent kubeopenv1alpha1.Agent
+			Expect(k8sClient.Get(ctx, agentKey, &updatedAgent)).Should(Succeed())
+			updatedAgent.Spec.ServerConfig.Workspace.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+			Expect(k8sClient.Update(ctx, &updatedAgent)).Should(Succeed())
+
+			By("Expecting the Deployment with both replicas")
+			Eventually(func() int32 {
+				var deployment appsv1.Deployment
+				if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil || deployment.Spec.Replicas == nil {
+					return 0
+				}
+				return *deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(replicas))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, &updatedAgent)).Should(Succeed())
+		})
+	})
+
+	Context("When a Server-mode Agent has sessions", func() {
+		It("Should report the session statistics of the server in status", func() {
+			agentName := "test-session-stats-agent"
+			agentKey := types.NamespacedName{Name: agentName, Namespace: agentNamespace}
+			serverURL := ServerURL(agentName, agentNamespace, DefaultServerPort)
+
+			By("Creating a ready Server-mode Agent with a busy and an idle session")
+			fakeOpenCode.addSession(serverURL, "/workspace", "ses_stats_busy")
+			fakeOpenCode.addSession(serverURL, "/workspace", "ses_stats_idle")
+			fakeOpenCode.setBusy("ses_stats_busy", true)
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: agentNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig:       &kubeopenv1alpha1.ServerConfig{},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+			markServerReady(agentName, agentNamespace)
+
+			sessionCounts := func() []int32 {
+				var a kubeopenv1alpha1.Agent
+				if err := k8sClient.Get(ctx, agentKey, &a); err != nil || a.Status.ServerStatus == nil {
+					return nil
+				}
+				return []int32{a.Status.ServerStatus.ActiveSessions, a.Status.ServerStatus.IdleSessions}
+			}
+			Eventually(sessionCounts, timeout, interval).Should(Equal([]int32{1, 1}))
+
+			By("Expecting the counts to follow the server after the session finishes")
+			fakeOpenCode.setBusy("ses_stats_busy", false)
+			Eventually(func() error {
+				var a kubeopenv1alpha1.Agent
+				if err := k8sClient.Get(ctx, agentKey, &a); err != nil {
+					return err
+				}
+				// Trigger a reconcile instead of waiting for the periodic one
+				a.Annotations = map[string]string{"test.kubeopencode.io/poke": "1"}
+				return k8sClient.Update(ctx, &a)
+			}, timeout, interval).Should(Succeed())
+			Eventually(sessionCounts, timeout, interval).Should(Equal([]int32{0, 2}))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+	})
+
 	Context("When switching from Server-mode to Pod-mode", func() {
 		It("Should clean up Deployment and Service", func() {
 			agentName := "test-mode-switch-agent"
@@ -352,7 +653,7 @@ var _ = Describe("DeploymentBuilder", func() {
 			}
 			sysCfg := systemConfig{}
 
-			deployment := BuildServerDeployment(agent, cfg, sysCfg)
+			deployment := BuildServerDeployment(agent, cfg, sysCfg, serverContexts{}, "")
 			Expect(deployment).To(BeNil())
 		})
 
@@ -375,13 +676,150 @@ var _ = Describe("DeploymentBuilder", func() {
 			}
 			sysCfg := systemConfig{}
 
-			deployment := BuildServerDeployment(agent, cfg, sysCfg)
+			deployment := BuildServerDeployment(agent, cfg, sysCfg, serverContexts{}, "")
 			Expect(deployment).NotTo(BeNil())
 			Expect(deployment.Name).To(Equal("test-server-agent-server"))
 			Expect(deployment.Namespace).To(Equal("default"))
 			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
-			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(2))
 			Expect(deployment.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort).To(Equal(int32(4096)))
+			Expect(deployment.Spec.Template.Spec.Containers[1].Name).To(Equal(WorkspaceSyncContainerName))
+		})
+
+		It("Should mount contexts and set the config hash annotation", func() {
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-context-server-agent",
+					Namespace: "default",
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{},
+				},
+			}
+			cfg := agentConfig{
+				executorImage: "test-executor-image",
+				agentImage:    "test-agent-image",
+				workspaceDir:  "/workspace",
+			}
+			contexts := serverContexts{
+				configMap: &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: ServerContextConfigMapName(agent.Name)},
+					Data:       map[string]string{"workspace-guides-standards.md": "# Coding Standards"},
+				},
+				fileMounts: []fileMount{{filePath: "/workspace/guides/standards.md"}},
+			}
+
+			deployment := BuildServerDeployment(agent, cfg, systemConfig{}, contexts, "abc123")
+			Expect(deployment).NotTo(BeNil())
+			Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(ServerConfigHashAnnotation, "abc123"))
+
+			var initNames []string
+			for _, c := range deployment.Spec.Template.Spec.InitContainers {
+				initNames = append(initNames, c.Name)
+			}
+			Expect(initNames).To(ContainElement("context-init"))
+
+			var hasContextVolume bool
+			for _, v := range deployment.Spec.Template.Spec.Volumes {
+				if v.ConfigMap != nil && v.ConfigMap.Name == ServerContextConfigMapName(agent.Name) {
+					hasContextVolume = true
+				}
+			}
+			Expect(hasContextVolume).To(BeTrue())
+		})
+
+		It("Should keep the workspace and Git contexts on the persistent workspace volume", func() {
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-persistent-server-agent",
+					Namespace: "default",
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						Workspace: &kubeopenv1alpha1.ServerWorkspace{},
+					},
+				},
+			}
+			cfg := agentConfig{
+				executorImage: "test-executor-image",
+				agentImage:    "test-agent-image",
+				workspaceDir:  "/workspace",
+			}
+			contexts := serverContexts{
+				gitMounts: []gitMount{{repository: "https://github.com/org/repo.git", mountPath: "/workspace/repo", repoPath: "docs"}},
+			}
+
+			deployment := BuildServerDeployment(agent, cfg, systemConfig{}, contexts, "")
+			Expect(deployment).NotTo(BeNil())
+			podSpec := deployment.Spec.Template.Spec
+
+			var volumeNames []string
+			for _, v := range podSpec.Volumes {
+				volumeNames = append(volumeNames, v.Name)
+				if v.Name == "workspace" {
+					Expect(v.PersistentVolumeClaim).NotTo(BeNil())
+					Expect(v.PersistentVolumeClaim.ClaimName).To(Equal(ServerWorkspacePVCName(agent.Name)))
+				}
+			}
+			Expect(volumeNames).To(ContainElement("workspace"))
+			Expect(volumeNames).NotTo(ContainElement("git-context-0"))
+
+			Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name:      "workspace",
+				MountPath: "/workspace/repo",
+				SubPath:   ServerGitRelPath + "/0/repo/docs",
+			}))
+
+			var gitInit *corev1.Container
+			for i := range podSpec.InitContainers {
+				if podSpec.InitContainers[i].Name == "git-init-0" {
+					gitInit = &podSpec.InitContainers[i]
+				}
+			}
+			Expect(gitInit).NotTo(BeNil())
+			Expect(gitInit.VolumeMounts).To(ConsistOf(corev1.VolumeMount{
+				Name:      "workspace",
+				MountPath: DefaultGitRoot,
+				SubPath:   ServerGitRelPath + "/0",
+			}))
+		})
+
+		It("Should run the configured replicas and drain sessions before stopping", func() {
+			replicas := int32(3)
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-replicated-server-agent",
+					Namespace: "default",
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						Port:     4096,
+						Replicas: &replicas,
+					},
+				},
+			}
+			cfg := agentConfig{
+				executorImage: "test-executor-image",
+				agentImage:    "test-agent-image",
+				workspaceDir:  "/workspace",
+			}
+
+			deployment := BuildServerDeployment(agent, cfg, systemConfig{}, serverContexts{}, "")
+			Expect(deployment).NotTo(BeNil())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(3)))
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.TerminationGracePeriodSeconds).NotTo(BeNil())
+			Expect(*podSpec.TerminationGracePeriodSeconds).To(Equal(ServerTerminationGracePeriodSeconds))
+
+			for _, c := range podSpec.Containers {
+				Expect(c.Lifecycle).NotTo(BeNil(), "container %s", c.Name)
+				Expect(c.Lifecycle.PreStop.HTTPGet.Path).To(Equal(ServerDrainPath))
+				Expect(c.Lifecycle.PreStop.HTTPGet.Port.IntVal).To(Equal(DefaultWorkspaceSyncPort))
+			}
+			Expect(podSpec.Containers[1].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "OPENCODE_SERVER_URL",
+				Value: "http://localhost:4096",
+			}))
 		})
 
 		It("Should use default port when not specified", func() {
@@ -403,12 +841,42 @@ var _ = Describe("DeploymentBuilder", func() {
 			}
 			sysCfg := systemConfig{}
 
-			deployment := BuildServerDeployment(agent, cfg, sysCfg)
+			deployment := BuildServerDeployment(agent, cfg, sysCfg, serverContexts{}, "")
 			Expect(deployment).NotTo(BeNil())
 			Expect(deployment.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort).To(Equal(DefaultServerPort))
 		})
 	})
 
+	Context("BuildServerWorkspacePVC", func() {
+		It("Should return nil without a persistent workspace", func() {
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-agent", Namespace: "default"},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{},
+				},
+			}
+			Expect(BuildServerWorkspacePVC(agent)).To(BeNil())
+		})
+
+		It("Should default the size and access modes", func() {
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-agent", Namespace: "default"},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						Workspace: &kubeopenv1alpha1.ServerWorkspace{},
+					},
+				},
+			}
+
+			pvc := BuildServerWorkspacePVC(agent)
+			Expect(pvc).NotTo(BeNil())
+			Expect(pvc.Name).To(Equal("test-agent-server-workspace"))
+			Expect(pvc.Spec.StorageClassName).To(BeNil())
+			Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal(DefaultServerWorkspaceSize))
+		})
+	})
+
 	Context("BuildServerService", func() {
 		It("Should return nil for Pod-mode Agent", func() {
 			agent := &kubeopenv1alpha1.Agent{
@@ -0,0 +1,113 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// namespaceShare returns the fair share that applies to Tasks from the namespace:
+// the per-namespace concurrency limit (0 = unlimited) and quota (nil = none).
+// The first override in fairShare.namespaces matching the namespace takes precedence
+// over the per-namespace defaults.
+func namespaceShare(fairShare *kubeopenv1alpha1.FairShareConfig, namespace string) (int32, *kubeopenv1alpha1.QuotaConfig) {
+	if fairShare == nil {
+		return 0, nil
+	}
+
+	maxConcurrent := int32(0)
+	if fairShare.MaxConcurrentTasksPerNamespace != nil {
+		maxConcurrent = *fairShare.MaxConcurrentTasksPerNamespace
+	}
+	quota := fairShare.QuotaPerNamespace
+
+	for _, share := range fairShare.Namespaces {
+		matched, err := filepath.Match(share.Namespace, namespace)
+		if err != nil || !matched {
+			continue
+		}
+		if share.MaxConcurrentTasks != nil {
+			maxConcurrent = *share.MaxConcurrentTasks
+		}
+		if share.Quota != nil {
+			quota = share.Quota
+		}
+		break
+	}
+
+	if maxConcurrent < 0 {
+		maxConcurrent = 0
+	}
+	return maxConcurrent, quota
+}
+
+// namespaceQuota returns the per-namespace quota of the Agent for the namespace, if any.
+func namespaceQuota(cfg agentConfig, namespace string) *kubeopenv1alpha1.QuotaConfig {
+	_, quota := namespaceShare(cfg.fairShare, namespace)
+	return quota
+}
+
+// quotaHistoryWindow returns how long Task starts must be kept in the Agent's
+// TaskStartHistory: the longest window of the Agent quota and all per-namespace quotas.
+// Returns 0 if no quota is configured.
+func quotaHistoryWindow(agent *kubeopenv1alpha1.Agent) int32 {
+	window := int32(0)
+	if agent.Spec.Quota != nil {
+		window = agent.Spec.Quota.WindowSeconds
+	}
+	if fairShare := agent.Spec.FairShare; fairShare != nil {
+		if fairShare.QuotaPerNamespace != nil && fairShare.QuotaPerNamespace.WindowSeconds > window {
+			window = fairShare.QuotaPerNamespace.WindowSeconds
+		}
+		for _, share := range fairShare.Namespaces {
+			if share.Quota != nil && share.Quota.WindowSeconds > window {
+				window = share.Quota.WindowSeconds
+			}
+		}
+	}
+	return window
+}
+
+// checkNamespaceQuota checks if a new Task from the namespace can start based on
+// the namespace's quota, counting only the namespace's Task starts.
+// Returns (hasQuota, requeueAfter) like checkAgentQuota.
+func checkNamespaceQuota(agent *kubeopenv1alpha1.Agent, namespace string, quota *kubeopenv1alpha1.QuotaConfig) (bool, time.Duration) {
+	if quota == nil {
+		return true, 0
+	}
+
+	var history []kubeopenv1alpha1.TaskStartRecord
+	for _, record := range agent.Status.TaskStartHistory {
+		if record.TaskNamespace == namespace {
+			history = append(history, record)
+		}
+	}
+
+	activeRecords := pruneTaskStartHistory(history, quota.WindowSeconds)
+	if int32(len(activeRecords)) >= quota.MaxTaskStarts { //nolint:gosec // len() is always non-negative and bounded by slice capacity
+		return false, calculateQuotaRequeueDelay(history, quota.WindowSeconds)
+	}
+	return true, 0
+}
+
+// capacityQueuedCondition returns the reason and message of the Queued condition for
+// a Task that failed its capacity check: either the Agent is at capacity, or the
+// Task's namespace used up its fair share of the Agent.
+func capacityQueuedCondition(agentName, namespace string, limits admissionLimits, check admissionCheck) (string, string) {
+	if check.namespaceLimited {
+		return kubeopenv1alpha1.ReasonNamespaceAtCapacity,
+			fmt.Sprintf("Waiting for namespace %q share of agent %q (max: %d, queue position: %d)",
+				namespace, agentName, limits.namespaceMax(namespace), check.position)
+	}
+	return kubeopenv1alpha1.ReasonAgentAtCapacity, capacityQueuedMessage(agentName, limits.maxConcurrent, check.position)
+}
+
+// namespaceQuotaQueuedMessage describes a Task waiting for its namespace's quota.
+func namespaceQuotaQueuedMessage(agentName, namespace string, quota *kubeopenv1alpha1.QuotaConfig) string {
+	return fmt.Sprintf("Waiting for namespace %q quota of agent %q (max: %d per %ds)",
+		namespace, agentName, quota.MaxTaskStarts, quota.WindowSeconds)
+}
@@ -0,0 +1,96 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestNamespaceShare(t *testing.T) {
+	perNamespace := int32(2)
+	platform := int32(5)
+	unlimited := int32(0)
+	defaultQuota := &kubeopenv1alpha1.QuotaConfig{MaxTaskStarts: 10, WindowSeconds: 3600}
+	sandboxQuota := &kubeopenv1alpha1.QuotaConfig{MaxTaskStarts: 1, WindowSeconds: 600}
+	fairShare := &kubeopenv1alpha1.FairShareConfig{
+		MaxConcurrentTasksPerNamespace: &perNamespace,
+		QuotaPerNamespace:              defaultQuota,
+		Namespaces: []kubeopenv1alpha1.NamespaceShare{
+			{Namespace: "team-platform", MaxConcurrentTasks: &platform},
+			{Namespace: "team-*", MaxConcurrentTasks: &unlimited},
+			{Namespace: "sandbox-*", Quota: sandboxQuota},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		fairShare *kubeopenv1alpha1.FairShareConfig
+		namespace string
+		wantMax   int32
+		wantQuota *kubeopenv1alpha1.QuotaConfig
+	}{
+		{name: "no fair share", fairShare: nil, namespace: "team-a", wantMax: 0, wantQuota: nil},
+		{name: "defaults", fairShare: fairShare, namespace: "dev", wantMax: 2, wantQuota: defaultQuota},
+		{name: "first match wins", fairShare: fairShare, namespace: "team-platform", wantMax: 5, wantQuota: defaultQuota},
+		{name: "override removes limit", fairShare: fairShare, namespace: "team-a", wantMax: 0, wantQuota: defaultQuota},
+		{name: "quota override inherits limit", fairShare: fairShare, namespace: "sandbox-1", wantMax: 2, wantQuota: sandboxQuota},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMax, gotQuota := namespaceShare(tt.fairShare, tt.namespace)
+			if gotMax != tt.wantMax || gotQuota != tt.wantQuota {
+				t.Errorf("namespaceShare() = %d, %v; want %d, %v", gotMax, gotQuota, tt.wantMax, tt.wantQuota)
+			}
+		})
+	}
+}
+
+func TestQuotaHistoryWindow(t *testing.T) {
+	agent := &kubeopenv1alpha1.Agent{}
+	if got := quotaHistoryWindow(agent); got != 0 {
+		t.Errorf("quotaHistoryWindow() without quota = %d, want 0", got)
+	}
+
+	agent.Spec.Quota = &kubeopenv1alpha1.QuotaConfig{MaxTaskStarts: 10, WindowSeconds: 600}
+	agent.Spec.FairShare = &kubeopenv1alpha1.FairShareConfig{
+		Namespaces: []kubeopenv1alpha1.NamespaceShare{
+			{Namespace: "team-*", Quota: &kubeopenv1alpha1.QuotaConfig{MaxTaskStarts: 5, WindowSeconds: 7200}},
+		},
+	}
+	if got := quotaHistoryWindow(agent); got != 7200 {
+		t.Errorf("quotaHistoryWindow() = %d, want 7200", got)
+	}
+}
+
+func TestCheckNamespaceQuota(t *testing.T) {
+	now := time.Now()
+	agent := &kubeopenv1alpha1.Agent{
+		Status: kubeopenv1alpha1.AgentStatus{
+			TaskStartHistory: []kubeopenv1alpha1.TaskStartRecord{
+				{TaskName: "a-1", TaskNamespace: "team-a", StartTime: metav1.NewTime(now.Add(-time.Minute))},
+				{TaskName: "a-2", TaskNamespace: "team-a", StartTime: metav1.NewTime(now.Add(-2 * time.Hour))},
+				{TaskName: "b-1", TaskNamespace: "team-b", StartTime: metav1.NewTime(now.Add(-time.Minute))},
+			},
+		},
+	}
+	quota := &kubeopenv1alpha1.QuotaConfig{MaxTaskStarts: 1, WindowSeconds: 3600}
+
+	if ok, _ := checkNamespaceQuota(agent, "team-a", nil); !ok {
+		t.Errorf("checkNamespaceQuota() without quota = false, want true")
+	}
+	// Only team-a's start within the window counts
+	if ok, delay := checkNamespaceQuota(agent, "team-a", quota); ok || delay < DefaultQuotaRequeueDelay {
+		t.Errorf("checkNamespaceQuota(team-a) = %v, %v; want false with requeue delay", ok, delay)
+	}
+	if ok, _ := checkNamespaceQuota(agent, "team-c", quota); !ok {
+		t.Errorf("checkNamespaceQuota(team-c) = false, want true")
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+const (
+	// GitCachePVCName is the name of the PersistentVolumeClaim holding the Git mirror
+	// cache of a namespace.
+	GitCachePVCName = "kubeopencode-git-cache"
+
+	// DefaultGitCacheHostPath is the node directory holding the Git mirror cache when
+	// no PersistentVolumeClaim is configured.
+	DefaultGitCacheHostPath = "/var/lib/kubeopencode/git-cache"
+
+	// DefaultGitCacheSize is the default size of the Git mirror cache claim.
+	DefaultGitCacheSize = "20Gi"
+
+	// DefaultGitCacheMaxUnused is how long a mirror is kept without being cloned from.
+	DefaultGitCacheMaxUnused = 7 * 24 * time.Hour
+
+	// GitCacheVolumeName is the name of the Git mirror cache volume in Pods.
+	GitCacheVolumeName = "git-cache"
+
+	// GitCacheMountPath is where git-init containers mount the Git mirror cache.
+	GitCacheMountPath = "/git-cache"
+)
+
+// gitCacheConfig holds the resolved Git mirror cache configuration.
+type gitCacheConfig struct {
+	hostPath  string                                // Node directory (if no claim)
+	claim     *kubeopenv1alpha1.GitCacheVolumeClaim // PersistentVolumeClaim settings (nil = hostPath)
+	maxSize   int64                                 // Eviction threshold in bytes (0 = unlimited)
+	maxUnused time.Duration                         // Eviction age of unused mirrors
+}
+
+// newGitCacheConfig resolves the Git mirror cache configuration, or returns nil if
+// the cache is not enabled.
+func newGitCacheConfig(cache *kubeopenv1alpha1.GitCacheConfig) *gitCacheConfig {
+	if cache == nil {
+		return nil
+	}
+	cfg := &gitCacheConfig{
+		hostPath:  cache.HostPath,
+		claim:     cache.PersistentVolumeClaim,
+		maxUnused: DefaultGitCacheMaxUnused,
+	}
+	if cfg.hostPath == "" {
+		cfg.hostPath = DefaultGitCacheHostPath
+	}
+	if cache.MaxSize != nil {
+		cfg.maxSize = cache.MaxSize.Value()
+	}
+	if cache.MaxUnusedSeconds != nil && *cache.MaxUnusedSeconds > 0 {
+		cfg.maxUnused = time.Duration(*cache.MaxUnusedSeconds) * time.Second
+	}
+	return cfg
+}
+
+// volume returns the Git mirror cache volume of a Pod.
+func (g *gitCacheConfig) volume() corev1.Volume {
+	if g.claim != nil {
+		return corev1.Volume{
+			Name: GitCacheVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: GitCachePVCName},
+			},
+		}
+	}
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	return corev1.Volume{
+		Name: GitCacheVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: g.hostPath, Type: &hostPathType},
+		},
+	}
+}
+
+// envVars returns the environment variables configuring the cache in git-init.
+func (g *gitCacheConfig) envVars() []corev1.EnvVar {
+	envVars := []corev1.EnvVar{
+		{Name: "GIT_CACHE_DIR", Value: GitCacheMountPath},
+		{Name: "GIT_CACHE_MAX_UNUSED_SECONDS", Value: strconv.FormatInt(int64(g.maxUnused/time.Second), 10)},
+	}
+	if g.maxSize > 0 {
+		envVars = append(envVars, corev1.EnvVar{Name: "GIT_CACHE_MAX_SIZE", Value: strconv.FormatInt(g.maxSize, 10)})
+	}
+	return envVars
+}
+
+// buildGitCachePVC creates the PersistentVolumeClaim of the Git mirror cache in
+// namespace.
+func buildGitCachePVC(namespace string, claim *kubeopenv1alpha1.GitCacheVolumeClaim) *corev1.PersistentVolumeClaim {
+	size := resource.MustParse(DefaultGitCacheSize)
+	if claim.Size != nil {
+		size = claim.Size.DeepCopy()
+	}
+
+	accessModes := claim.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GitCachePVCName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "kubeopencode-git-cache",
+				"app.kubernetes.io/managed-by": "kubeopencode",
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: claim.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+}
+
+// ensureGitCachePVC creates the PersistentVolumeClaim of the Git mirror cache in
+// namespace if the cache is kept on a claim and it does not exist yet. The claim is
+// shared by all Pods of the namespace, so it has no owner and is never deleted by
+// the controller.
+func ensureGitCachePVC(ctx context.Context, c client.Client, namespace string, cache *gitCacheConfig) error {
+	if cache == nil || cache.claim == nil {
+		return nil
+	}
+
+	var existing corev1.PersistentVolumeClaim
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: GitCachePVCName}, &existing)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get Git cache PersistentVolumeClaim: %w", err)
+	}
+
+	log.FromContext(ctx).Info("Creating Git cache PersistentVolumeClaim", "namespace", namespace, "pvc", GitCachePVCName)
+	if err := c.Create(ctx, buildGitCachePVC(namespace, cache.claim)); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create Git cache PersistentVolumeClaim: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestNewGitCacheConfig(t *testing.T) {
+	if got := newGitCacheConfig(nil); got != nil {
+		t.Errorf("newGitCacheConfig(nil) = %+v, want nil", got)
+	}
+
+	cfg := newGitCacheConfig(&kubeopenv1alpha1.GitCacheConfig{})
+	if cfg.hostPath != DefaultGitCacheHostPath || cfg.maxSize != 0 || cfg.maxUnused != DefaultGitCacheMaxUnused {
+		t.Errorf("default config = %+v", cfg)
+	}
+	volume := cfg.volume()
+	if volume.HostPath == nil || volume.HostPath.Path != DefaultGitCacheHostPath {
+		t.Errorf("default volume = %+v, want hostPath %s", volume.VolumeSource, DefaultGitCacheHostPath)
+	}
+
+	maxSize := resource.MustParse("50Gi")
+	maxUnused := int32(3600)
+	cfg = newGitCacheConfig(&kubeopenv1alpha1.GitCacheConfig{
+		HostPath:              "/mnt/cache",
+		PersistentVolumeClaim: &kubeopenv1alpha1.GitCacheVolumeClaim{},
+		MaxSize:               &maxSize,
+		MaxUnusedSeconds:      &maxUnused,
+	})
+	if cfg.maxSize != maxSize.Value() || cfg.maxUnused != time.Hour {
+		t.Errorf("config = %+v", cfg)
+	}
+	// The claim takes precedence over the host path
+	volume = cfg.volume()
+	if volume.PersistentVolumeClaim == nil || volume.PersistentVolumeClaim.ClaimName != GitCachePVCName {
+		t.Errorf("volume = %+v, want claim %s", volume.VolumeSource, GitCachePVCName)
+	}
+
+	env := make(map[string]string)
+	for _, e := range cfg.envVars() {
+		env[e.Name] = e.Value
+	}
+	if env["GIT_CACHE_DIR"] != GitCacheMountPath || env["GIT_CACHE_MAX_UNUSED_SECONDS"] != "3600" || env["GIT_CACHE_MAX_SIZE"] != "53687091200" {
+		t.Errorf("envVars() = %v", env)
+	}
+}
+
+func TestBuildGitCachePVC(t *testing.T) {
+	pvc := buildGitCachePVC("team-a", &kubeopenv1alpha1.GitCacheVolumeClaim{})
+	if pvc.Name != GitCachePVCName || pvc.Namespace != "team-a" {
+		t.Errorf("PVC = %s/%s", pvc.Namespace, pvc.Name)
+	}
+	if len(pvc.Spec.AccessModes) != 1 || pvc.Spec.AccessModes[0] != corev1.ReadWriteMany {
+		t.Errorf("AccessModes = %v, want [ReadWriteMany]", pvc.Spec.AccessModes)
+	}
+	size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if size.String() != DefaultGitCacheSize {
+		t.Errorf("Size = %s, want %s", size.String(), DefaultGitCacheSize)
+	}
+
+	storageClass := "nfs"
+	custom := resource.MustParse("100Gi")
+	pvc = buildGitCachePVC("team-a", &kubeopenv1alpha1.GitCacheVolumeClaim{
+		StorageClassName: &storageClass,
+		Size:             &custom,
+		AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+	})
+	size = pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if *pvc.Spec.StorageClassName != "nfs" || size.String() != "100Gi" || pvc.Spec.AccessModes[0] != corev1.ReadWriteOnce {
+		t.Errorf("PVC spec = %+v", pvc.Spec)
+	}
+}
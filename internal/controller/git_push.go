@@ -0,0 +1,159 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+const (
+	// DefaultGitPushBranch is the default branch template of a Git context's pushBack.
+	DefaultGitPushBranch = "kubeopencode/{{.TaskName}}"
+
+	// DefaultGitPushCommitMessage is the default commit message template of a Git
+	// context's pushBack.
+	DefaultGitPushCommitMessage = "KubeOpenCode Task {{.TaskNamespace}}/{{.TaskName}}"
+
+	// AgentExitCodeFileRelPath is the relative path (from workspaceDir) where the agent
+	// container records the agent's exit code, so git-push only pushes the changes of
+	// an agent that succeeded.
+	AgentExitCodeFileRelPath = ".kubeopencode/agent-exit-code"
+
+	// GitPushTerminationGracePeriodSeconds is the termination grace period of Pods
+	// pushing changes back: git-push runs when the agent has exited and the kubelet
+	// stops the sidecars, and has this long to commit and push.
+	GitPushTerminationGracePeriodSeconds int64 = 300
+)
+
+// gitPushBack is the pushBack configuration of a Git context, with its templates
+// rendered for the Task.
+type gitPushBack struct {
+	branch        string
+	commitMessage string
+	force         bool
+}
+
+// gitPushTemplateData is the data the pushBack templates are rendered with.
+type gitPushTemplateData struct {
+	TaskName      string
+	TaskNamespace string
+}
+
+// newGitPushBack returns the pushBack configuration of a Git context with the
+// default templates filled in, or nil if it is not configured.
+func newGitPushBack(pushBack *kubeopenv1alpha1.GitPushBack) *gitPushBack {
+	if pushBack == nil {
+		return nil
+	}
+	gp := &gitPushBack{
+		branch:        pushBack.Branch,
+		commitMessage: pushBack.CommitMessage,
+		force:         pushBack.Force,
+	}
+	if gp.branch == "" {
+		gp.branch = DefaultGitPushBranch
+	}
+	if gp.commitMessage == "" {
+		gp.commitMessage = DefaultGitPushCommitMessage
+	}
+	return gp
+}
+
+// renderGitPushBack renders the pushBack templates of the Git mounts for the Task.
+// pushBack needs the agent to run in the Task Pod, so it is rejected for Server-mode
+// Agents.
+func renderGitPushBack(gitMounts []gitMount, task *kubeopenv1alpha1.Task, cfg agentConfig) error {
+	data := gitPushTemplateData{TaskName: task.Name, TaskNamespace: task.Namespace}
+	for i := range gitMounts {
+		gp := gitMounts[i].pushBack
+		if gp == nil {
+			continue
+		}
+		if cfg.serverConfig != nil {
+			return fmt.Errorf("git context %q: pushBack is not supported for Server-mode Agents", gitMounts[i].contextName)
+		}
+		branch, err := renderGitPushTemplate(gp.branch, data)
+		if err != nil {
+			return fmt.Errorf("git context %q: invalid pushBack branch: %w", gitMounts[i].contextName, err)
+		}
+		if branch == "" {
+			return fmt.Errorf("git context %q: pushBack branch %q renders empty", gitMounts[i].contextName, gp.branch)
+		}
+		message, err := renderGitPushTemplate(gp.commitMessage, data)
+		if err != nil {
+			return fmt.Errorf("git context %q: invalid pushBack commitMessage: %w", gitMounts[i].contextName, err)
+		}
+		gitMounts[i].pushBack = &gitPushBack{branch: branch, commitMessage: message, force: gp.force}
+	}
+	return nil
+}
+
+// renderGitPushTemplate renders a pushBack template.
+func renderGitPushTemplate(text string, data gitPushTemplateData) (string, error) {
+	tmpl, err := template.New("pushBack").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// hasGitPushBack reports whether any Git mount pushes changes back.
+func hasGitPushBack(gitMounts []gitMount) bool {
+	for _, gm := range gitMounts {
+		if gm.pushBack != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAgentExitCode wraps the agent command so that it records the agent's exit
+// code in the exit code file before exiting with it.
+func recordAgentExitCode(command []string, exitCodeFile string) []string {
+	dir := exitCodeFile[:strings.LastIndex(exitCodeFile, "/")]
+	script := fmt.Sprintf(`"$@"; rc=$?; mkdir -p %s; echo "$rc" > %s; exit "$rc"`, dir, exitCodeFile)
+	return append([]string{"sh", "-c", script, "sh"}, command...)
+}
+
+// buildGitPushContainer creates the git-push sidecar of a Git mount with pushBack.
+// It runs as a native sidecar (an init container that keeps running) next to the
+// agent: when the agent has exited and the kubelet stops the sidecar, it commits the
+// changes in the clone and pushes them if the agent succeeded.
+func buildGitPushContainer(gm gitMount, volumeName string, index int, workspaceDir string, sysCfg systemConfig) corev1.Container {
+	envVars := []corev1.EnvVar{
+		{Name: "GIT_REPO", Value: gm.repository},
+		{Name: "GIT_ROOT", Value: DefaultGitRoot},
+		{Name: "GIT_LINK", Value: DefaultGitLink},
+		{Name: "GIT_PUSH_BRANCH", Value: gm.pushBack.branch},
+		{Name: "GIT_PUSH_MESSAGE", Value: gm.pushBack.commitMessage},
+		{Name: "AGENT_EXIT_CODE_FILE", Value: workspaceDir + "/" + AgentExitCodeFileRelPath},
+	}
+	if gm.pushBack.force {
+		envVars = append(envVars, corev1.EnvVar{Name: "GIT_PUSH_FORCE", Value: "true"})
+	}
+	envVars = append(envVars, gitCredentialEnv(gm.secretName)...)
+
+	restartPolicy := corev1.ContainerRestartPolicyAlways
+	return corev1.Container{
+		Name:            fmt.Sprintf("git-push-%d", index),
+		Image:           sysCfg.systemImage,
+		ImagePullPolicy: sysCfg.systemImagePullPolicy,
+		Command:         []string{"/kubeopencode", "git-push"},
+		Env:             envVars,
+		RestartPolicy:   &restartPolicy,
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: volumeName, MountPath: DefaultGitRoot},
+			{Name: "workspace", MountPath: workspaceDir},
+		},
+	}
+}
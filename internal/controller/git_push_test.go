@@ -0,0 +1,62 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestRenderGitPushBack(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{ObjectMeta: metav1.ObjectMeta{Name: "fix-bug", Namespace: "team-a"}}
+
+	gitMounts := []gitMount{
+		{contextName: "docs"},
+		{contextName: "repo", pushBack: newGitPushBack(&kubeopenv1alpha1.GitPushBack{})},
+		{contextName: "other", pushBack: newGitPushBack(&kubeopenv1alpha1.GitPushBack{
+			Branch:        "agent/{{.TaskNamespace}}-{{.TaskName}}",
+			CommitMessage: "Automated fix",
+			Force:         true,
+		})},
+	}
+	if err := renderGitPushBack(gitMounts, task, agentConfig{}); err != nil {
+		t.Fatalf("renderGitPushBack() error = %v", err)
+	}
+	if gitMounts[0].pushBack != nil {
+		t.Errorf("pushBack of a Git mount without it = %+v, want nil", gitMounts[0].pushBack)
+	}
+	if got := *gitMounts[1].pushBack; got.branch != "kubeopencode/fix-bug" || got.commitMessage != "KubeOpenCode Task team-a/fix-bug" || got.force {
+		t.Errorf("default pushBack = %+v", got)
+	}
+	if got := *gitMounts[2].pushBack; got.branch != "agent/team-a-fix-bug" || got.commitMessage != "Automated fix" || !got.force {
+		t.Errorf("pushBack = %+v", got)
+	}
+}
+
+func TestRenderGitPushBack_Errors(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{ObjectMeta: metav1.ObjectMeta{Name: "fix-bug", Namespace: "team-a"}}
+
+	tests := []struct {
+		name     string
+		pushBack kubeopenv1alpha1.GitPushBack
+		cfg      agentConfig
+	}{
+		{name: "server mode", cfg: agentConfig{serverConfig: &kubeopenv1alpha1.ServerConfig{}}},
+		{name: "invalid template", pushBack: kubeopenv1alpha1.GitPushBack{Branch: "{{.TaskName"}},
+		{name: "unknown field", pushBack: kubeopenv1alpha1.GitPushBack{CommitMessage: "{{.Agent}}"}},
+		{name: "empty branch", pushBack: kubeopenv1alpha1.GitPushBack{Branch: `{{""}}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitMounts := []gitMount{{contextName: "repo", pushBack: newGitPushBack(&tt.pushBack)}}
+			if err := renderGitPushBack(gitMounts, task, tt.cfg); err == nil {
+				t.Error("renderGitPushBack() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// Session metrics of Server-mode Agents, labeled by the Agent's namespace and name.
+// They are updated whenever the Agent is reconciled.
+var (
+	serverActiveSessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubeopencode_agent_server_active_sessions",
+		Help: "Number of sessions working on a prompt on the server of a Server-mode Agent.",
+	}, []string{"namespace", "agent"})
+
+	serverIdleSessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubeopencode_agent_server_idle_sessions",
+		Help: "Number of idle sessions on the server of a Server-mode Agent.",
+	}, []string{"namespace", "agent"})
+
+	serverOldestActiveSessionAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubeopencode_agent_server_oldest_active_session_age_seconds",
+		Help: "Age of the oldest active session on the server of a Server-mode Agent (0 if none is active).",
+	}, []string{"namespace", "agent"})
+
+	serverLastActivity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubeopencode_agent_server_last_activity_timestamp_seconds",
+		Help: "Unix time a session on the server of a Server-mode Agent was last updated.",
+	}, []string{"namespace", "agent"})
+)
+
+// Git mirror cache metrics, counted from the git-init results of Task Pods.
+var (
+	gitCacheClones = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubeopencode_git_cache_clones_total",
+		Help: "Number of Git context clones using the Git mirror cache, by whether the repository was already cached (hit) or not (miss).",
+	}, []string{"result"})
+
+	gitCacheBytesSaved = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kubeopencode_git_cache_saved_bytes_total",
+		Help: "Estimated bytes of Git objects cloned from the Git mirror cache instead of the remote.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(serverActiveSessions, serverIdleSessions, serverOldestActiveSessionAge, serverLastActivity)
+	metrics.Registry.MustRegister(gitCacheClones, gitCacheBytesSaved)
+}
+
+// recordGitCacheMetrics counts a clone reported by git-init. cache is "hit" or "miss",
+// or empty if the clone did not use the cache.
+func recordGitCacheMetrics(cache string, bytesSaved int64) {
+	if cache == "" {
+		return
+	}
+	gitCacheClones.WithLabelValues(cache).Inc()
+	if bytesSaved > 0 {
+		gitCacheBytesSaved.Add(float64(bytesSaved))
+	}
+}
+
+// recordServerSessionMetrics sets the session metrics of an Agent from its server status.
+func recordServerSessionMetrics(namespace, agent string, status *kubeopenv1alpha1.ServerStatus) {
+	serverActiveSessions.WithLabelValues(namespace, agent).Set(float64(status.ActiveSessions))
+	serverIdleSessions.WithLabelValues(namespace, agent).Set(float64(status.IdleSessions))
+	age := 0.0
+	if status.OldestActiveSessionTime != nil {
+		age = time.Since(status.OldestActiveSessionTime.Time).Seconds()
+	}
+	serverOldestActiveSessionAge.WithLabelValues(namespace, agent).Set(age)
+	if status.LastActivityTime != nil {
+		serverLastActivity.WithLabelValues(namespace, agent).Set(float64(status.LastActivityTime.Unix()))
+	}
+}
+
+// deleteServerSessionMetrics removes the session metrics of an Agent that was deleted
+// or is no longer in Server mode.
+func deleteServerSessionMetrics(namespace, agent string) {
+	for _, gauge := range []*prometheus.GaugeVec{serverActiveSessions, serverIdleSessions, serverOldestActiveSessionAge, serverLastActivity} {
+		gauge.DeleteLabelValues(namespace, agent)
+	}
+}
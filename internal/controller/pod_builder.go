@@ -16,6 +16,7 @@ import (
 
 // agentConfig holds the resolved configuration from Agent
 type agentConfig struct {
+	name               string   // Agent name
 	agentImage         string   // OpenCode init container image (copies binary to /tools)
 	executorImage      string   // Worker container image for task execution
 	attachImage        string   // Lightweight image for Server-mode --attach Pods
@@ -28,7 +29,10 @@ type agentConfig struct {
 	serviceAccountName string
 	maxConcurrentTasks *int32
 	quota              *kubeopenv1alpha1.QuotaConfig
-	serverConfig       *kubeopenv1alpha1.ServerConfig // Server mode configuration (nil = Pod mode)
+	fairShare          *kubeopenv1alpha1.FairShareConfig // Per-namespace limits (nil = none)
+	maxTimeoutSeconds  *int32                            // Ceiling for Task timeoutSeconds (nil = no ceiling)
+	serverConfig       *kubeopenv1alpha1.ServerConfig    // Server mode configuration (nil = Pod mode)
+	activeSessions     int32                             // Active server sessions counted against maxConcurrentTasks
 }
 
 // systemConfig holds resolved system-level configuration from KubeOpenCodeConfig.
@@ -40,6 +44,8 @@ type systemConfig struct {
 	// systemImagePullPolicy is the image pull policy for system containers.
 	// Defaults to IfNotPresent if not specified.
 	systemImagePullPolicy corev1.PullPolicy
+	// gitCache is the shared Git mirror cache git-init clones from (nil = disabled).
+	gitCache *gitCacheConfig
 }
 
 // fileMount represents a file to be mounted at a specific path
@@ -59,11 +65,33 @@ type dirMount struct {
 type gitMount struct {
 	contextName string // Context name (for volume naming)
 	repository  string // Git repository URL
-	ref         string // Git reference (branch, tag, or commit SHA)
+	ref         string // Git reference (branch, tag, commit SHA, ref, or refspec)
+	baseRef     string // Optional base reference fetched alongside ref
 	repoPath    string // Path within the repository to mount
 	mountPath   string // Where to mount in the container
 	depth       int    // Clone depth (1 = shallow, 0 = full)
 	secretName  string // Optional secret name for authentication
+	submodules  string // Submodule checkout mode ("", "shallow" or "recursive")
+	// lfs fetches Git LFS objects, limited to lfsInclude patterns if not empty
+	lfs            bool
+	lfsInclude     []string
+	sparseCheckout []string // Directories to check out (cone mode); empty = everything
+	// pushBack pushes the agent's changes back to the repository (nil if not configured).
+	// Its templates are rendered by processAllContexts.
+	pushBack *gitPushBack
+}
+
+// urlMount represents remote content to be fetched by a url-fetch init container
+type urlMount struct {
+	contextName     string            // Context name (for XML tag when appending to the context file)
+	namespace       string            // Context namespace (for XML tag when appending to the context file)
+	source          string            // HTTP/HTTPS URL to fetch
+	headers         map[string]string // Optional HTTP headers
+	secretName      string            // Optional secret name for authentication
+	insecure        bool              // Skip TLS certificate verification
+	timeout         int32             // Request timeout in seconds
+	targetPath      string            // File the fetched content is written to
+	appendToContext bool              // Append to the context file with XML tags instead of overwriting
 }
 
 // resolvedContext holds a resolved context with its content and metadata
@@ -119,6 +147,21 @@ func sanitizeVolumeName(dirPath string) string {
 	return name
 }
 
+// externalParentDir returns the parent directory of a file outside workspaceDir
+// that needs a shared emptyDir volume, or "" if no extra volume is needed.
+// Files under workspaceDir use the workspace volume, and files directly under
+// /tools use the existing tools volume.
+func externalParentDir(filePath, workspaceDir string) string {
+	if isUnderPath(filePath, workspaceDir) {
+		return ""
+	}
+	parentDir := getParentDir(filePath)
+	if parentDir == ToolsMountPath {
+		return ""
+	}
+	return parentDir
+}
+
 // boolPtr returns a pointer to the given bool value
 func boolPtr(b bool) *bool {
 	return &b
@@ -165,6 +208,11 @@ const (
 	// OpenCode loads this file via the instructions config injected through OPENCODE_CONFIG_CONTENT.
 	ContextFileRelPath = ".kubeopencode/context.md"
 
+	// ResultFileRelPath is the relative path (from workspaceDir) where the agent writes
+	// its structured result. It is the agent container's termination message file,
+	// so the kubelet copies it into the container status when the agent exits.
+	ResultFileRelPath = ".kubeopencode/result.json"
+
 	// DefaultSecretFileMode is the default permission mode for mounted secrets.
 	// 0600 gives read/write access to the owner only.
 	DefaultSecretFileMode int32 = 0600
@@ -181,6 +229,9 @@ const (
 	// DefaultGitLink is the default subdirectory name for Git clones
 	DefaultGitLink = "repo"
 
+	// DefaultURLTimeout is the default request timeout in seconds for URL contexts
+	DefaultURLTimeout int32 = 30
+
 	// DefaultHomeDir is the default HOME directory for SCC compatibility
 	DefaultHomeDir = "/tmp"
 
@@ -229,45 +280,140 @@ func buildGitInitContainer(gm gitMount, volumeName string, index int, sysCfg sys
 		{Name: "GIT_ROOT", Value: DefaultGitRoot},
 		{Name: "GIT_LINK", Value: DefaultGitLink},
 	}
+	if gm.baseRef != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "GIT_BASE_REF", Value: gm.baseRef})
+	}
+	if gm.submodules != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "GIT_SUBMODULES", Value: gm.submodules})
+	}
+	if gm.lfs {
+		envVars = append(envVars, corev1.EnvVar{Name: "GIT_LFS", Value: "true"})
+		if len(gm.lfsInclude) > 0 {
+			envVars = append(envVars, corev1.EnvVar{Name: "GIT_LFS_INCLUDE", Value: strings.Join(gm.lfsInclude, ",")})
+		}
+	}
+	if len(gm.sparseCheckout) > 0 {
+		envVars = append(envVars, corev1.EnvVar{Name: "GIT_SPARSE_CHECKOUT", Value: strings.Join(gm.sparseCheckout, "\n")})
+	}
 
 	volumeMounts := []corev1.VolumeMount{
 		{Name: volumeName, MountPath: DefaultGitRoot},
 	}
 
+	// Clone from the shared Git mirror cache if enabled
+	if sysCfg.gitCache != nil {
+		envVars = append(envVars, sysCfg.gitCache.envVars()...)
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: GitCacheVolumeName, MountPath: GitCacheMountPath})
+	}
+
 	// Add secret environment variables for authentication if specified
-	if gm.secretName != "" {
-		// git-init supports GIT_USERNAME/GIT_PASSWORD for HTTPS
-		envVars = append(envVars,
-			corev1.EnvVar{
-				Name: "GIT_USERNAME",
-				ValueFrom: &corev1.EnvVarSource{
-					SecretKeyRef: &corev1.SecretKeySelector{
-						LocalObjectReference: corev1.LocalObjectReference{Name: gm.secretName},
-						Key:                  "username",
-						Optional:             boolPtr(true),
-					},
+	envVars = append(envVars, gitCredentialEnv(gm.secretName)...)
+
+	return corev1.Container{
+		Name:                     fmt.Sprintf("git-init-%d", index),
+		Image:                    sysCfg.systemImage,
+		ImagePullPolicy:          sysCfg.systemImagePullPolicy,
+		Command:                  []string{"/kubeopencode", "git-init"},
+		Env:                      envVars,
+		VolumeMounts:             volumeMounts,
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}
+}
+
+// gitCredentialEnv returns the environment variables passing the credentials of a Git
+// context's Secret to git-init and git-push (GIT_USERNAME/GIT_PASSWORD for HTTPS).
+func gitCredentialEnv(secretName string) []corev1.EnvVar {
+	if secretName == "" {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name: "GIT_USERNAME",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  "username",
+					Optional:             boolPtr(true),
+				},
+			},
+		},
+		{
+			Name: "GIT_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  "password",
+					Optional:             boolPtr(true),
 				},
 			},
-			corev1.EnvVar{
-				Name: "GIT_PASSWORD",
+		},
+	}
+}
+
+// buildURLFetchContainer creates an init container that fetches a URL context.
+// Failures are reported through the container termination message so that the
+// Task controller can surface them as a Task condition.
+func buildURLFetchContainer(um urlMount, index int, sysCfg systemConfig) corev1.Container {
+	timeout := um.timeout
+	if timeout <= 0 {
+		timeout = DefaultURLTimeout
+	}
+
+	envVars := []corev1.EnvVar{
+		{Name: "URL_SOURCE", Value: um.source},
+		{Name: "URL_TARGET", Value: um.targetPath},
+		{Name: "URL_TIMEOUT", Value: strconv.Itoa(int(timeout))},
+	}
+
+	if len(um.headers) > 0 {
+		headersJSON, _ := json.Marshal(um.headers)
+		envVars = append(envVars, corev1.EnvVar{Name: "URL_HEADERS", Value: string(headersJSON)})
+	}
+
+	if um.insecure {
+		envVars = append(envVars, corev1.EnvVar{Name: "URL_INSECURE", Value: "true"})
+	}
+
+	// Contexts without mountPath are appended to the context file with XML tags,
+	// matching the format the controller uses for other contexts.
+	if um.appendToContext {
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "URL_APPEND", Value: "true"},
+			corev1.EnvVar{Name: "URL_CONTEXT_NAME", Value: um.contextName},
+			corev1.EnvVar{Name: "URL_CONTEXT_NAMESPACE", Value: um.namespace},
+		)
+	}
+
+	// Add secret environment variables for authentication if specified
+	if um.secretName != "" {
+		// url-fetch supports URL_AUTH_TOKEN for Bearer auth and
+		// URL_AUTH_USERNAME/URL_AUTH_PASSWORD for HTTP Basic auth
+		for _, auth := range []struct{ env, key string }{
+			{env: "URL_AUTH_TOKEN", key: "token"},
+			{env: "URL_AUTH_USERNAME", key: "username"},
+			{env: "URL_AUTH_PASSWORD", key: "password"},
+		} {
+			envVars = append(envVars, corev1.EnvVar{
+				Name: auth.env,
 				ValueFrom: &corev1.EnvVarSource{
 					SecretKeyRef: &corev1.SecretKeySelector{
-						LocalObjectReference: corev1.LocalObjectReference{Name: gm.secretName},
-						Key:                  "password",
+						LocalObjectReference: corev1.LocalObjectReference{Name: um.secretName},
+						Key:                  auth.key,
 						Optional:             boolPtr(true),
 					},
 				},
-			},
-		)
+			})
+		}
 	}
 
 	return corev1.Container{
-		Name:            fmt.Sprintf("git-init-%d", index),
-		Image:           sysCfg.systemImage,
-		ImagePullPolicy: sysCfg.systemImagePullPolicy,
-		Command:         []string{"/kubeopencode", "git-init"},
-		Env:             envVars,
-		VolumeMounts:    volumeMounts,
+		Name:                     fmt.Sprintf("url-fetch-%d", index),
+		Image:                    sysCfg.systemImage,
+		ImagePullPolicy:          sysCfg.systemImagePullPolicy,
+		Command:                  []string{"/kubeopencode", "url-fetch"},
+		Env:                      envVars,
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		// VolumeMounts will be added by the caller
 	}
 }
 
@@ -338,13 +484,20 @@ func buildContextInitContainer(workspaceDir string, fileMounts []fileMount, dirM
 	}
 }
 
-// buildPod creates a Pod object for the task with context mounts.
-// The agentNamespace parameter specifies where the Pod will be created (may differ from Task namespace
-// when using cross-namespace Agent reference).
-// The serverURL parameter is used for Server-mode Agents: when non-empty, the Pod will use
-// `opencode run --attach <serverURL>` to connect to an existing OpenCode server instead of
-// running a standalone instance.
-func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string, cfg agentConfig, contextConfigMap *corev1.ConfigMap, fileMounts []fileMount, dirMounts []dirMount, gitMounts []gitMount, sysCfg systemConfig, serverURL string) *corev1.Pod {
+// agentWorkload holds the parts of an agent Pod shared by Task Pods and the
+// Server-mode Deployment: volumes, init containers (OpenCode, context-init, git-init,
+// url-fetch), and the environment and volume mounts of the agent container.
+type agentWorkload struct {
+	volumes        []corev1.Volume
+	volumeMounts   []corev1.VolumeMount
+	envVars        []corev1.EnvVar
+	envFromSources []corev1.EnvFromSource
+	initContainers []corev1.Container
+}
+
+// buildAgentWorkload builds the agent workload from the Agent configuration and resolved
+// contexts. extraEnv is added to the agent container after the base environment variables.
+func buildAgentWorkload(cfg agentConfig, extraEnv []corev1.EnvVar, contextConfigMap *corev1.ConfigMap, fileMounts []fileMount, dirMounts []dirMount, gitMounts []gitMount, urlMounts []urlMount, sysCfg systemConfig) agentWorkload {
 	var volumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
 	var envVars []corev1.EnvVar
@@ -389,10 +542,9 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 	envVars = append(envVars,
 		corev1.EnvVar{Name: "HOME", Value: DefaultHomeDir},
 		corev1.EnvVar{Name: "SHELL", Value: DefaultShell},
-		corev1.EnvVar{Name: "TASK_NAME", Value: task.Name},
-		corev1.EnvVar{Name: "TASK_NAMESPACE", Value: task.Namespace},
 		corev1.EnvVar{Name: "WORKSPACE_DIR", Value: cfg.workspaceDir},
 	)
+	envVars = append(envVars, extraEnv...)
 
 	// If OpenCode config is provided, set OPENCODE_CONFIG env var
 	if cfg.config != nil && *cfg.config != "" {
@@ -416,18 +568,29 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 	// Check if context file is being mounted and inject OPENCODE_CONFIG_CONTENT.
 	// This allows OpenCode to load KubeOpenCode's context file without conflicting
 	// with repository's AGENTS.md. The context file path is relative to workspaceDir.
+	// URL contexts without mountPath are appended to the same context file by url-fetch.
 	contextFilePath := cfg.workspaceDir + "/" + ContextFileRelPath
+	hasContextFile := false
 	for _, fm := range fileMounts {
 		if fm.filePath == contextFilePath {
-			// Inject instructions to load the context file
-			// OpenCode will merge this with OPENCODE_CONFIG (if set)
-			envVars = append(envVars, corev1.EnvVar{
-				Name:  OpenCodeConfigContentEnvVar,
-				Value: `{"instructions":["` + ContextFileRelPath + `"]}`,
-			})
+			hasContextFile = true
+			break
+		}
+	}
+	for _, um := range urlMounts {
+		if um.targetPath == contextFilePath {
+			hasContextFile = true
 			break
 		}
 	}
+	if hasContextFile {
+		// Inject instructions to load the context file
+		// OpenCode will merge this with OPENCODE_CONFIG (if set)
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  OpenCodeConfigContentEnvVar,
+			Value: `{"instructions":["` + ContextFileRelPath + `"]}`,
+		})
+	}
 
 	// envFromSources collects secretRef entries for mounting entire secrets
 	var envFromSources []corev1.EnvFromSource
@@ -572,6 +735,9 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 		})
 	}
 
+	// Parent directories outside /workspace that need a shared emptyDir volume
+	externalDirs := make(map[string]bool)
+
 	// Add context-init container if there are any context files or directories to copy
 	if len(fileMounts) > 0 || len(dirMounts) > 0 {
 		contextInit := buildContextInitContainer(cfg.workspaceDir, fileMounts, dirMounts, sysCfg)
@@ -593,50 +759,54 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 			})
 		}
 
-		// For files outside /workspace, we need to create shared emptyDir volumes
+		// For files outside /workspace, mount the shared emptyDir volumes (created below)
 		// so that the context-init container can write files that persist to the agent container.
-		// Group files by their parent directory to minimize the number of volumes.
-		externalDirs := make(map[string]bool)
+		contextInitDirs := make(map[string]bool)
 		for _, fm := range fileMounts {
-			if !isUnderPath(fm.filePath, cfg.workspaceDir) {
-				parentDir := getParentDir(fm.filePath)
-				// Skip /tools as it already exists for the OpenCode binary
-				if parentDir == ToolsMountPath {
-					continue
-				}
-				externalDirs[parentDir] = true
+			if dir := externalParentDir(fm.filePath, cfg.workspaceDir); dir != "" && !contextInitDirs[dir] {
+				contextInitDirs[dir] = true
+				externalDirs[dir] = true
+				contextInit.VolumeMounts = append(contextInit.VolumeMounts, corev1.VolumeMount{
+					Name:      sanitizeVolumeName(dir),
+					MountPath: dir,
+				})
 			}
 		}
 
-		// Create emptyDir volumes for each unique external parent directory
-		for dir := range externalDirs {
-			volumeName := sanitizeVolumeName(dir)
+		initContainers = append(initContainers, contextInit)
+	}
 
-			// Add emptyDir volume for this external directory
-			volumes = append(volumes, corev1.Volume{
-				Name: volumeName,
-				VolumeSource: corev1.VolumeSource{
-					EmptyDir: &corev1.EmptyDirVolumeSource{},
-				},
-			})
+	// URL contexts outside /workspace also need a shared emptyDir volume
+	for _, um := range urlMounts {
+		if dir := externalParentDir(um.targetPath, cfg.workspaceDir); dir != "" {
+			externalDirs[dir] = true
+		}
+	}
 
-			// Mount this volume in context-init container
-			contextInit.VolumeMounts = append(contextInit.VolumeMounts, corev1.VolumeMount{
-				Name:      volumeName,
-				MountPath: dir,
-			})
+	// Create emptyDir volumes for each unique external parent directory.
+	// Group files by their parent directory to minimize the number of volumes.
+	for dir := range externalDirs {
+		volumeName := sanitizeVolumeName(dir)
 
-			// Mount this volume in agent container
-			volumeMounts = append(volumeMounts, corev1.VolumeMount{
-				Name:      volumeName,
-				MountPath: dir,
-			})
-		}
+		// Add emptyDir volume for this external directory
+		volumes = append(volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
 
-		initContainers = append(initContainers, contextInit)
+		// Mount this volume in agent container
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: dir,
+		})
 	}
 
 	// Add Git context mounts (using git-init containers)
+	if len(gitMounts) > 0 && sysCfg.gitCache != nil {
+		volumes = append(volumes, sysCfg.gitCache.volume())
+	}
 	for i, gm := range gitMounts {
 		volumeName := fmt.Sprintf("git-context-%d", i)
 
@@ -664,6 +834,31 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 		})
 	}
 
+	// Add URL context fetches (using url-fetch containers)
+	// url-fetch runs after context-init so that contexts without mountPath can be
+	// appended to the context file that context-init has already written.
+	for i, um := range urlMounts {
+		urlFetch := buildURLFetchContainer(um, i, sysCfg)
+		switch dir := getParentDir(um.targetPath); {
+		case isUnderPath(um.targetPath, cfg.workspaceDir):
+			urlFetch.VolumeMounts = append(urlFetch.VolumeMounts, corev1.VolumeMount{
+				Name:      "workspace",
+				MountPath: cfg.workspaceDir,
+			})
+		case dir == ToolsMountPath:
+			urlFetch.VolumeMounts = append(urlFetch.VolumeMounts, corev1.VolumeMount{
+				Name:      ToolsVolumeName,
+				MountPath: ToolsMountPath,
+			})
+		default:
+			urlFetch.VolumeMounts = append(urlFetch.VolumeMounts, corev1.VolumeMount{
+				Name:      sanitizeVolumeName(dir),
+				MountPath: dir,
+			})
+		}
+		initContainers = append(initContainers, urlFetch)
+	}
+
 	// If we have Git mounts, add GIT_CONFIG_GLOBAL to point to shared gitconfig
 	// This is needed because init containers run as different users and git will
 	// refuse to work without safe.directory configured
@@ -682,6 +877,33 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 		})
 	}
 
+	return agentWorkload{
+		volumes:        volumes,
+		volumeMounts:   volumeMounts,
+		envVars:        envVars,
+		envFromSources: envFromSources,
+		initContainers: initContainers,
+	}
+}
+
+// buildPod creates a Pod object for the task with context mounts.
+// The agentNamespace parameter specifies where the Pod will be created (may differ from Task namespace
+// when using cross-namespace Agent reference).
+// The serverURL parameter is used for Server-mode Agents: when non-empty, the Pod will use
+// `opencode run --attach <serverURL>` to connect to an existing OpenCode server instead of
+// running a standalone instance.
+func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string, cfg agentConfig, contextConfigMap *corev1.ConfigMap, fileMounts []fileMount, dirMounts []dirMount, gitMounts []gitMount, urlMounts []urlMount, sysCfg systemConfig, serverURL string) *corev1.Pod {
+	taskEnv := []corev1.EnvVar{
+		{Name: "TASK_NAME", Value: task.Name},
+		{Name: "TASK_NAMESPACE", Value: task.Namespace},
+	}
+	// In Server mode the agent runs on the server, where the result file cannot be
+	// collected, so Server-mode Tasks report no results
+	if serverURL == "" {
+		taskEnv = append(taskEnv, corev1.EnvVar{Name: "TASK_RESULT_FILE", Value: cfg.workspaceDir + "/" + ResultFileRelPath})
+	}
+	workload := buildAgentWorkload(cfg, taskEnv, contextConfigMap, fileMounts, dirMounts, gitMounts, urlMounts, sysCfg)
+
 	// Build pod labels - start with base labels
 	podLabels := map[string]string{
 		"app":                  "kubeopencode",
@@ -707,10 +929,17 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 	if len(agentCommand) == 0 {
 		if serverURL != "" {
 			// Server mode: use --attach flag to connect to existing OpenCode server
-			// This allows Tasks to reuse a persistent server for faster execution
+			// This allows Tasks to reuse a persistent server for faster execution.
+			// The workspace directory is the Task's workspace on the server, where
+			// workspace-upload delivered the contexts, so the session runs there.
+			// Follow-up Tasks (sessionRef) send the task into the session they continue.
+			sessionFlag := ""
+			if task.Status.SessionID != "" {
+				sessionFlag = " --session " + task.Status.SessionID
+			}
 			agentCommand = []string{
 				"sh", "-c",
-				fmt.Sprintf(`/tools/opencode run --attach %s "$(cat %s/task.md)"`, serverURL, cfg.workspaceDir),
+				fmt.Sprintf(`/tools/opencode run --attach %s --dir %s%s "$(cat %s/task.md)"`, serverURL, cfg.workspaceDir, sessionFlag, cfg.workspaceDir),
 			}
 		} else {
 			// Pod mode: run standalone OpenCode instance
@@ -720,6 +949,11 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 			}
 		}
 	}
+	// Pushing changes back: record the agent's exit code for the git-push sidecars
+	pushBack := serverURL == "" && hasGitPushBack(gitMounts)
+	if pushBack {
+		agentCommand = recordAgentExitCode(agentCommand, cfg.workspaceDir+"/"+AgentExitCodeFileRelPath)
+	}
 	// Determine executor image: use lightweight attach image for Server mode
 	executorImage := cfg.executorImage
 	if serverURL != "" && cfg.attachImage != "" {
@@ -734,22 +968,72 @@ func buildPod(task *kubeopenv1alpha1.Task, podName string, agentNamespace string
 		Image:           executorImage,
 		ImagePullPolicy: corev1.PullIfNotPresent,
 		Command:         agentCommand,
-		Env:             envVars,
-		EnvFrom:         envFromSources,
-		VolumeMounts:    volumeMounts,
+		Env:             workload.envVars,
+		EnvFrom:         workload.envFromSources,
+		VolumeMounts:    workload.volumeMounts,
+		// The agent reports structured results by writing the result file,
+		// which the controller reads back from the container termination message
+		TerminationMessagePath:   cfg.workspaceDir + "/" + ResultFileRelPath,
+		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
 	}
 
 	// Build containers list
 	containers := []corev1.Container{agentContainer}
 
+	// Server mode: upload the staged workspace to the server after all other init
+	// containers have populated it. The upload sees the workspace as the agent would:
+	// Git, directory, and file contexts mounted inside it are part of the archive.
+	if serverURL != "" {
+		var uploadMounts []corev1.VolumeMount
+		for _, m := range workload.volumeMounts {
+			if isUnderPath(m.MountPath, cfg.workspaceDir) {
+				uploadMounts = append(uploadMounts, m)
+			}
+		}
+		workload.initContainers = append(workload.initContainers, corev1.Container{
+			Name:            "workspace-upload",
+			Image:           sysCfg.systemImage,
+			ImagePullPolicy: sysCfg.systemImagePullPolicy,
+			Command:         []string{"/kubeopencode", "workspace-upload"},
+			Env: []corev1.EnvVar{
+				{Name: "WORKSPACE_DIR", Value: cfg.workspaceDir},
+				{Name: "WORKSPACE_SYNC_URL", Value: ServerTaskWorkspaceURL(serverURL, task.Namespace, task.Name)},
+				workspaceSyncTokenEnv(cfg.name),
+			},
+			VolumeMounts: uploadMounts,
+		})
+	}
+
+	// Pushing changes back: the git-push sidecars start after all other init
+	// containers and push when the agent has exited
+	if pushBack {
+		for i, gm := range gitMounts {
+			if gm.pushBack != nil {
+				workload.initContainers = append(workload.initContainers,
+					buildGitPushContainer(gm, fmt.Sprintf("git-context-%d", i), i, cfg.workspaceDir, sysCfg))
+			}
+		}
+	}
+
 	// Build PodSpec with scheduling configuration
 	podSpec := corev1.PodSpec{
 		ServiceAccountName: cfg.serviceAccountName,
-		InitContainers:     initContainers,
+		InitContainers:     workload.initContainers,
 		Containers:         containers,
-		Volumes:            volumes,
+		Volumes:            workload.volumes,
 		RestartPolicy:      corev1.RestartPolicyNever,
 	}
+	if pushBack {
+		gracePeriod := GitPushTerminationGracePeriodSeconds
+		podSpec.TerminationGracePeriodSeconds = &gracePeriod
+	}
+
+	// Enforce the Task timeout on the node as well, so the Pod is terminated
+	// even if the controller is not running when the deadline passes
+	if task.Spec.TimeoutSeconds != nil {
+		activeDeadlineSeconds := int64(*task.Spec.TimeoutSeconds)
+		podSpec.ActiveDeadlineSeconds = &activeDeadlineSeconds
+	}
 
 	// Apply PodSpec configuration if specified
 	if cfg.podSpec != nil {
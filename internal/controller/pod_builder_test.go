@@ -227,7 +227,7 @@ func TestBuildPod_BasicTask(t *testing.T) {
 		serviceAccountName: "test-sa",
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify pod metadata
 	if pod.Name != "test-task-pod" {
@@ -312,6 +312,17 @@ func TestBuildPod_BasicTask(t *testing.T) {
 	if envMap["WORKSPACE_DIR"] != "/workspace" {
 		t.Errorf("Env[WORKSPACE_DIR] = %q, want %q", envMap["WORKSPACE_DIR"], "/workspace")
 	}
+	if envMap["TASK_RESULT_FILE"] != "/workspace/.kubeopencode/result.json" {
+		t.Errorf("Env[TASK_RESULT_FILE] = %q, want %q", envMap["TASK_RESULT_FILE"], "/workspace/.kubeopencode/result.json")
+	}
+
+	// Verify the result file is the termination message
+	if container.TerminationMessagePath != "/workspace/.kubeopencode/result.json" {
+		t.Errorf("TerminationMessagePath = %q, want %q", container.TerminationMessagePath, "/workspace/.kubeopencode/result.json")
+	}
+	if container.TerminationMessagePolicy != corev1.TerminationMessageReadFile {
+		t.Errorf("TerminationMessagePolicy = %q, want %q", container.TerminationMessagePolicy, corev1.TerminationMessageReadFile)
+	}
 
 	// Verify service account
 	if pod.Spec.ServiceAccountName != "test-sa" {
@@ -329,6 +340,154 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+func TestBuildPod_WithTimeout(t *testing.T) {
+	timeoutSeconds := int32(1800)
+	task := &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-task",
+			Namespace: "default",
+			UID:       "test-uid",
+		},
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kubeopencode.io/v1alpha1",
+			Kind:       "Task",
+		},
+		Spec: kubeopenv1alpha1.TaskSpec{
+			TimeoutSeconds: &timeoutSeconds,
+		},
+	}
+
+	cfg := agentConfig{
+		agentImage:         "test-opencode:v1.0.0",
+		executorImage:      "test-executor:v1.0.0",
+		workspaceDir:       "/workspace",
+		serviceAccountName: "test-sa",
+	}
+
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
+
+	if pod.Spec.ActiveDeadlineSeconds == nil {
+		t.Fatalf("ActiveDeadlineSeconds = nil, want %d", timeoutSeconds)
+	}
+	if *pod.Spec.ActiveDeadlineSeconds != int64(timeoutSeconds) {
+		t.Errorf("ActiveDeadlineSeconds = %d, want %d", *pod.Spec.ActiveDeadlineSeconds, timeoutSeconds)
+	}
+
+	// Without a timeout, the Pod has no deadline
+	task.Spec.TimeoutSeconds = nil
+	pod = buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
+	if pod.Spec.ActiveDeadlineSeconds != nil {
+		t.Errorf("ActiveDeadlineSeconds = %d, want nil", *pod.Spec.ActiveDeadlineSeconds)
+	}
+}
+
+func TestBuildPod_ServerMode(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-task",
+			Namespace: "team-a",
+		},
+	}
+
+	// The controller points the workspace at the Task's workspace on the server
+	taskWorkspace := ServerTaskWorkspaceDir("/workspace", task.Namespace, task.Name)
+	if taskWorkspace != "/workspace/.kubeopencode/tasks/team-a/test-task" {
+		t.Errorf("ServerTaskWorkspaceDir() = %q", taskWorkspace)
+	}
+
+	cfg := agentConfig{
+		name:          "server-agent",
+		agentImage:    "test-opencode:v1.0.0",
+		executorImage: "test-executor:v1.0.0",
+		attachImage:   "test-attach:v1.0.0",
+		workspaceDir:  taskWorkspace,
+	}
+	serverURL := ServerURL("server-agent", "platform", DefaultServerPort)
+
+	// A Git context cloned into the Task workspace is part of the upload
+	gitMounts := []gitMount{
+		{contextName: "source", repository: "https://github.com/org/repo.git", mountPath: taskWorkspace + "/git-source"},
+	}
+
+	pod := buildPod(task, "test-task-pod", "platform", cfg, nil, nil, nil, gitMounts, nil, defaultSystemConfig(), serverURL)
+
+	agent := pod.Spec.Containers[0]
+	if agent.Image != "test-attach:v1.0.0" {
+		t.Errorf("agent image = %q, want attach image", agent.Image)
+	}
+	wantCmd := "--attach " + serverURL + " --dir " + taskWorkspace
+	if !strings.Contains(agent.Command[2], wantCmd) {
+		t.Errorf("agent command = %q, want it to contain %q", agent.Command[2], wantCmd)
+	}
+	// The agent runs on the server, where no result file is collected
+	for _, env := range agent.Env {
+		if env.Name == "TASK_RESULT_FILE" {
+			t.Errorf("agent Env[TASK_RESULT_FILE] = %q, want it unset in Server mode", env.Value)
+		}
+	}
+
+	// workspace-upload must run after all other init containers
+	initContainers := pod.Spec.InitContainers
+	upload := initContainers[len(initContainers)-1]
+	if upload.Name != "workspace-upload" {
+		t.Fatalf("last init container = %q, want workspace-upload", upload.Name)
+	}
+	envs := make(map[string]string)
+	for _, env := range upload.Env {
+		envs[env.Name] = env.Value
+	}
+	if envs["WORKSPACE_DIR"] != taskWorkspace {
+		t.Errorf("WORKSPACE_DIR = %q, want %q", envs["WORKSPACE_DIR"], taskWorkspace)
+	}
+	wantURL := "http://server-agent.platform.svc.cluster.local:4097/workspaces/team-a/test-task"
+	if envs["WORKSPACE_SYNC_URL"] != wantURL {
+		t.Errorf("WORKSPACE_SYNC_URL = %q, want %q", envs["WORKSPACE_SYNC_URL"], wantURL)
+	}
+	var tokenRef *corev1.SecretKeySelector
+	for _, env := range upload.Env {
+		if env.Name == "WORKSPACE_SYNC_TOKEN" && env.ValueFrom != nil {
+			tokenRef = env.ValueFrom.SecretKeyRef
+		}
+	}
+	if tokenRef == nil || tokenRef.Name != "server-agent-server-sync-token" || tokenRef.Key != ServerSyncTokenKey {
+		t.Errorf("WORKSPACE_SYNC_TOKEN = %+v, want the Agent's workspace-sync token", tokenRef)
+	}
+
+	// The upload mounts the Git context where the agent would see it, and nothing
+	// outside the workspace
+	var gitMounted bool
+	for _, m := range upload.VolumeMounts {
+		if m.Name == "git-context-0" && m.MountPath == taskWorkspace+"/git-source" && m.SubPath == DefaultGitLink {
+			gitMounted = true
+		}
+		if !isUnderPath(m.MountPath, taskWorkspace) {
+			t.Errorf("workspace-upload mounts %s outside the Task workspace", m.MountPath)
+		}
+	}
+	if !gitMounted {
+		t.Errorf("workspace-upload mounts = %+v, want the Git context at %s/git-source", upload.VolumeMounts, taskWorkspace)
+	}
+
+	// A follow-up Task (sessionRef) continues the session
+	if strings.Contains(agent.Command[2], "--session") {
+		t.Errorf("agent command = %q, want no --session for a new session", agent.Command[2])
+	}
+	task.Status.SessionID = "ses_abc123"
+	pod = buildPod(task, "test-task-pod", "platform", cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), serverURL)
+	if !strings.Contains(pod.Spec.Containers[0].Command[2], "--session ses_abc123") {
+		t.Errorf("agent command = %q, want --session ses_abc123", pod.Spec.Containers[0].Command[2])
+	}
+	task.Status.SessionID = ""
+
+	// Pod mode has no upload
+	pod = buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == "workspace-upload" {
+			t.Errorf("Pod-mode Task has a workspace-upload init container")
+		}
+	}
+}
+
 func TestBuildPod_WithCredentials(t *testing.T) {
 	task := &kubeopenv1alpha1.Task{
 		ObjectMeta: metav1.ObjectMeta{
@@ -368,7 +527,7 @@ func TestBuildPod_WithCredentials(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
 
 	container := pod.Spec.Containers[0]
 
@@ -444,7 +603,7 @@ func TestBuildPod_WithEntireSecretCredential(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
 
 	container := pod.Spec.Containers[0]
 
@@ -499,7 +658,7 @@ func TestBuildPod_WithMixedCredentials(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
 
 	container := pod.Spec.Containers[0]
 
@@ -566,7 +725,7 @@ func TestBuildPod_WithEntireSecretAsDirectory(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
 
 	container := pod.Spec.Containers[0]
 
@@ -657,7 +816,7 @@ func TestBuildPod_WithPodScheduling(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify node selector
 	if pod.Spec.NodeSelector["node-type"] != "gpu" {
@@ -719,7 +878,7 @@ func TestBuildPod_WithContextConfigMap(t *testing.T) {
 		{filePath: "/workspace/task.md"},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, contextConfigMap, fileMounts, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, contextConfigMap, fileMounts, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify context-files volume exists (for init container to read from)
 	var foundContextVolume bool
@@ -814,7 +973,7 @@ func TestBuildPod_WithDirMounts(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, dirMounts, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, dirMounts, nil, nil, defaultSystemConfig(), "")
 
 	// Verify dir-mount volume exists (for init container to read from)
 	var foundDirVolume bool
@@ -911,7 +1070,7 @@ func TestBuildPod_WithGitMounts(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, nil, defaultSystemConfig(), "")
 
 	// Verify init containers exist (opencode-init first, then git-init-0)
 	if len(pod.Spec.InitContainers) != 2 {
@@ -1008,7 +1167,7 @@ func TestBuildPod_WithGitMountsAndAuth(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, nil, defaultSystemConfig(), "")
 
 	// Verify we have 2 init containers (opencode-init + git-init)
 	if len(pod.Spec.InitContainers) != 2 {
@@ -1057,11 +1216,227 @@ func TestBuildPod_WithGitMountsAndAuth(t *testing.T) {
 	}
 }
 
+func TestBuildPod_WithGitPushBack(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-task",
+			Namespace: "default",
+		},
+	}
+
+	cfg := agentConfig{
+		agentImage:    "test-opencode:v1.0.0",
+		executorImage: "test-executor:v1.0.0",
+		workspaceDir:  "/workspace",
+	}
+
+	gitMounts := []gitMount{
+		{
+			contextName: "docs",
+			repository:  "https://github.com/org/docs.git",
+			mountPath:   "/workspace/docs",
+		},
+		{
+			contextName: "repo",
+			repository:  "https://github.com/org/repo.git",
+			mountPath:   "/workspace/repo",
+			secretName:  "git-credentials",
+			pushBack:    &gitPushBack{branch: "kubeopencode/test-task", commitMessage: "Fix it", force: true},
+		},
+	}
+
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, nil, defaultSystemConfig(), "")
+
+	// opencode-init, git-init-0, git-init-1, then the git-push sidecar
+	if len(pod.Spec.InitContainers) != 4 {
+		t.Fatalf("Expected 4 init containers, got %d", len(pod.Spec.InitContainers))
+	}
+	sidecar := pod.Spec.InitContainers[3]
+	if sidecar.Name != "git-push-1" {
+		t.Errorf("Sidecar name = %q, want %q", sidecar.Name, "git-push-1")
+	}
+	if sidecar.RestartPolicy == nil || *sidecar.RestartPolicy != corev1.ContainerRestartPolicyAlways {
+		t.Errorf("Sidecar restartPolicy = %v, want Always", sidecar.RestartPolicy)
+	}
+
+	envMap := make(map[string]string)
+	var foundPassword bool
+	for _, env := range sidecar.Env {
+		envMap[env.Name] = env.Value
+		if env.Name == "GIT_PASSWORD" && env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil &&
+			env.ValueFrom.SecretKeyRef.Name == "git-credentials" {
+			foundPassword = true
+		}
+	}
+	if envMap["GIT_REPO"] != "https://github.com/org/repo.git" {
+		t.Errorf("GIT_REPO = %q", envMap["GIT_REPO"])
+	}
+	if envMap["GIT_PUSH_BRANCH"] != "kubeopencode/test-task" || envMap["GIT_PUSH_MESSAGE"] != "Fix it" || envMap["GIT_PUSH_FORCE"] != "true" {
+		t.Errorf("push env = %v", envMap)
+	}
+	if envMap["AGENT_EXIT_CODE_FILE"] != "/workspace/"+AgentExitCodeFileRelPath {
+		t.Errorf("AGENT_EXIT_CODE_FILE = %q", envMap["AGENT_EXIT_CODE_FILE"])
+	}
+	if !foundPassword {
+		t.Errorf("GIT_PASSWORD env var with secret reference not found")
+	}
+
+	mounts := make(map[string]string)
+	for _, mount := range sidecar.VolumeMounts {
+		mounts[mount.Name] = mount.MountPath
+	}
+	if mounts["git-context-1"] != DefaultGitRoot || mounts["workspace"] != "/workspace" {
+		t.Errorf("Sidecar volume mounts = %v", mounts)
+	}
+
+	// The agent records its exit code for the sidecar
+	command := pod.Spec.Containers[0].Command
+	if len(command) < 5 || command[0] != "sh" || !strings.Contains(command[2], AgentExitCodeFileRelPath) || command[4] != "sh" {
+		t.Errorf("Agent command = %v, want the default command wrapped to record its exit code", command)
+	}
+
+	if pod.Spec.TerminationGracePeriodSeconds == nil || *pod.Spec.TerminationGracePeriodSeconds != GitPushTerminationGracePeriodSeconds {
+		t.Errorf("TerminationGracePeriodSeconds = %v, want %d", pod.Spec.TerminationGracePeriodSeconds, GitPushTerminationGracePeriodSeconds)
+	}
+
+	// Without pushBack, the Pod is unchanged
+	gitMounts[1].pushBack = nil
+	pod = buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, nil, defaultSystemConfig(), "")
+	if len(pod.Spec.InitContainers) != 3 || pod.Spec.TerminationGracePeriodSeconds != nil {
+		t.Errorf("Pod without pushBack has %d init containers, grace period %v", len(pod.Spec.InitContainers), pod.Spec.TerminationGracePeriodSeconds)
+	}
+	if strings.Contains(strings.Join(pod.Spec.Containers[0].Command, " "), AgentExitCodeFileRelPath) {
+		t.Errorf("Agent command = %v, want it unwrapped", pod.Spec.Containers[0].Command)
+	}
+}
+
+func TestBuildPod_WithGitCheckoutOptions(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-task",
+			Namespace: "default",
+		},
+	}
+
+	cfg := agentConfig{
+		agentImage:    "test-opencode:v1.0.0",
+		executorImage: "test-executor:v1.0.0",
+		workspaceDir:  "/workspace",
+	}
+
+	gitMounts := []gitMount{
+		{contextName: "plain", repository: "https://github.com/org/plain.git", mountPath: "/workspace/plain"},
+		{
+			contextName:    "monorepo",
+			repository:     "https://github.com/org/monorepo.git",
+			mountPath:      "/workspace/monorepo",
+			submodules:     "recursive",
+			lfs:            true,
+			lfsInclude:     []string{"assets/**", "*.bin"},
+			sparseCheckout: []string{"services/api", "libs/common"},
+		},
+	}
+
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, nil, defaultSystemConfig(), "")
+
+	envs := make(map[string]map[string]string)
+	for _, c := range pod.Spec.InitContainers {
+		env := make(map[string]string)
+		for _, e := range c.Env {
+			env[e.Name] = e.Value
+		}
+		envs[c.Name] = env
+	}
+
+	for _, name := range []string{"GIT_SUBMODULES", "GIT_LFS", "GIT_LFS_INCLUDE", "GIT_SPARSE_CHECKOUT"} {
+		if _, ok := envs["git-init-0"][name]; ok {
+			t.Errorf("git-init-0 has %s without checkout options", name)
+		}
+	}
+
+	want := map[string]string{
+		"GIT_SUBMODULES":      "recursive",
+		"GIT_LFS":             "true",
+		"GIT_LFS_INCLUDE":     "assets/**,*.bin",
+		"GIT_SPARSE_CHECKOUT": "services/api\nlibs/common",
+	}
+	for name, value := range want {
+		if got := envs["git-init-1"][name]; got != value {
+			t.Errorf("git-init-1 %s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestBuildPod_WithGitCache(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-task",
+			Namespace: "default",
+		},
+	}
+
+	cfg := agentConfig{
+		agentImage:    "test-opencode:v1.0.0",
+		executorImage: "test-executor:v1.0.0",
+		workspaceDir:  "/workspace",
+	}
+
+	gitMounts := []gitMount{
+		{contextName: "a", repository: "https://github.com/org/a.git", mountPath: "/workspace/a"},
+		{contextName: "b", repository: "https://github.com/org/b.git", mountPath: "/workspace/b"},
+	}
+
+	sysCfg := defaultSystemConfig()
+	sysCfg.gitCache = newGitCacheConfig(&kubeopenv1alpha1.GitCacheConfig{})
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, gitMounts, nil, sysCfg, "")
+
+	var cacheVolumes int
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == GitCacheVolumeName {
+			cacheVolumes++
+		}
+	}
+	if cacheVolumes != 1 {
+		t.Errorf("Found %d %s volumes, want 1", cacheVolumes, GitCacheVolumeName)
+	}
+
+	for _, c := range pod.Spec.InitContainers {
+		if !strings.HasPrefix(c.Name, "git-init-") {
+			continue
+		}
+		var mounted bool
+		for _, m := range c.VolumeMounts {
+			if m.Name == GitCacheVolumeName && m.MountPath == GitCacheMountPath {
+				mounted = true
+			}
+		}
+		if !mounted {
+			t.Errorf("%s does not mount the Git cache", c.Name)
+		}
+	}
+
+	// Only git-init uses the cache: the clone is dissociated from it
+	for _, m := range pod.Spec.Containers[0].VolumeMounts {
+		if m.Name == GitCacheVolumeName {
+			t.Errorf("Agent container mounts the Git cache")
+		}
+	}
+
+	// Without Git contexts, the Pod does not mount the cache
+	pod = buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, sysCfg, "")
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == GitCacheVolumeName {
+			t.Errorf("Pod without Git contexts has a %s volume", GitCacheVolumeName)
+		}
+	}
+}
+
 func TestBuildGitInitContainer(t *testing.T) {
 	gm := gitMount{
 		contextName: "test-context",
 		repository:  "https://github.com/test/repo.git",
 		ref:         "develop",
+		baseRef:     "main",
 		repoPath:    "docs/",
 		mountPath:   "/workspace/docs",
 		depth:       5,
@@ -1077,6 +1452,9 @@ func TestBuildGitInitContainer(t *testing.T) {
 	if container.Image != DefaultKubeOpenCodeImage {
 		t.Errorf("Container image = %q, want %q", container.Image, DefaultKubeOpenCodeImage)
 	}
+	if container.TerminationMessagePolicy != corev1.TerminationMessageFallbackToLogsOnError {
+		t.Errorf("TerminationMessagePolicy = %q, want %q", container.TerminationMessagePolicy, corev1.TerminationMessageFallbackToLogsOnError)
+	}
 
 	// Check env vars
 	envMap := make(map[string]string)
@@ -1092,6 +1470,9 @@ func TestBuildGitInitContainer(t *testing.T) {
 	if envMap["GIT_REF"] != "develop" {
 		t.Errorf("GIT_REF = %q, want %q", envMap["GIT_REF"], "develop")
 	}
+	if envMap["GIT_BASE_REF"] != "main" {
+		t.Errorf("GIT_BASE_REF = %q, want %q", envMap["GIT_BASE_REF"], "main")
+	}
 	if envMap["GIT_DEPTH"] != "5" {
 		t.Errorf("GIT_DEPTH = %q, want %q", envMap["GIT_DEPTH"], "5")
 	}
@@ -1108,6 +1489,179 @@ func TestBuildGitInitContainer(t *testing.T) {
 	}
 }
 
+func TestBuildURLFetchContainer(t *testing.T) {
+	um := urlMount{
+		contextName: "context",
+		namespace:   "default",
+		source:      "https://api.example.com/openapi.yaml",
+		headers:     map[string]string{"X-Custom": "value"},
+		secretName:  "api-credentials",
+		insecure:    true,
+		timeout:     60,
+		targetPath:  "/workspace/specs/openapi.yaml",
+	}
+
+	container := buildURLFetchContainer(um, 1, defaultSystemConfig())
+
+	if container.Name != "url-fetch-1" {
+		t.Errorf("Container name = %q, want %q", container.Name, "url-fetch-1")
+	}
+	if container.Image != DefaultKubeOpenCodeImage {
+		t.Errorf("Container image = %q, want %q", container.Image, DefaultKubeOpenCodeImage)
+	}
+	if container.TerminationMessagePolicy != corev1.TerminationMessageFallbackToLogsOnError {
+		t.Errorf("TerminationMessagePolicy = %q, want %q", container.TerminationMessagePolicy, corev1.TerminationMessageFallbackToLogsOnError)
+	}
+
+	// Check env vars
+	envMap := make(map[string]string)
+	secretKeys := make(map[string]string)
+	for _, env := range container.Env {
+		if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+			if env.ValueFrom.SecretKeyRef.Name != "api-credentials" {
+				t.Errorf("%s secret name = %q, want %q", env.Name, env.ValueFrom.SecretKeyRef.Name, "api-credentials")
+			}
+			if env.ValueFrom.SecretKeyRef.Optional == nil || !*env.ValueFrom.SecretKeyRef.Optional {
+				t.Errorf("%s secret key ref should be optional", env.Name)
+			}
+			secretKeys[env.Name] = env.ValueFrom.SecretKeyRef.Key
+			continue
+		}
+		envMap[env.Name] = env.Value
+	}
+
+	wantEnv := map[string]string{
+		"URL_SOURCE":   "https://api.example.com/openapi.yaml",
+		"URL_TARGET":   "/workspace/specs/openapi.yaml",
+		"URL_TIMEOUT":  "60",
+		"URL_HEADERS":  `{"X-Custom":"value"}`,
+		"URL_INSECURE": "true",
+	}
+	for name, want := range wantEnv {
+		if envMap[name] != want {
+			t.Errorf("%s = %q, want %q", name, envMap[name], want)
+		}
+	}
+	if _, ok := envMap["URL_APPEND"]; ok {
+		t.Errorf("URL_APPEND should not be set when appendToContext is false")
+	}
+
+	wantSecretKeys := map[string]string{
+		"URL_AUTH_TOKEN":    "token",
+		"URL_AUTH_USERNAME": "username",
+		"URL_AUTH_PASSWORD": "password",
+	}
+	for name, want := range wantSecretKeys {
+		if secretKeys[name] != want {
+			t.Errorf("%s secret key = %q, want %q", name, secretKeys[name], want)
+		}
+	}
+}
+
+func TestBuildPod_WithURLMounts(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-task",
+			Namespace: "default",
+			UID:       "test-uid",
+		},
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kubeopencode.io/v1alpha1",
+			Kind:       "Task",
+		},
+	}
+
+	cfg := agentConfig{
+		agentImage:         "test-opencode:v1.0.0",
+		executorImage:      "test-executor:v1.0.0",
+		workspaceDir:       "/workspace",
+		serviceAccountName: "test-sa",
+	}
+
+	urlMounts := []urlMount{
+		{
+			contextName:     "context",
+			namespace:       "default",
+			source:          "https://example.com/guidelines.md",
+			timeout:         DefaultURLTimeout,
+			targetPath:      "/workspace/" + ContextFileRelPath,
+			appendToContext: true,
+		},
+		{
+			contextName: "context",
+			namespace:   "default",
+			source:      "https://example.com/schema.json",
+			timeout:     DefaultURLTimeout,
+			targetPath:  "/etc/schemas/schema.json",
+		},
+	}
+
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, urlMounts, defaultSystemConfig(), "")
+
+	// Verify init containers (opencode-init first, then url-fetch-0 and url-fetch-1)
+	if len(pod.Spec.InitContainers) != 3 {
+		t.Fatalf("Expected 3 init containers (opencode-init + 2 url-fetch), got %d", len(pod.Spec.InitContainers))
+	}
+
+	// url-fetch-0 appends to the context file in the workspace volume
+	urlFetch0 := pod.Spec.InitContainers[1]
+	if urlFetch0.Name != "url-fetch-0" {
+		t.Errorf("Init container name = %q, want %q", urlFetch0.Name, "url-fetch-0")
+	}
+	envMap := make(map[string]string)
+	for _, env := range urlFetch0.Env {
+		envMap[env.Name] = env.Value
+	}
+	if envMap["URL_APPEND"] != "true" {
+		t.Errorf("URL_APPEND = %q, want %q", envMap["URL_APPEND"], "true")
+	}
+	if len(urlFetch0.VolumeMounts) != 1 || urlFetch0.VolumeMounts[0].Name != "workspace" {
+		t.Errorf("url-fetch-0 should mount the workspace volume, got %v", urlFetch0.VolumeMounts)
+	}
+
+	// url-fetch-1 writes outside the workspace into a shared emptyDir volume
+	urlFetch1 := pod.Spec.InitContainers[2]
+	if urlFetch1.Name != "url-fetch-1" {
+		t.Errorf("Init container name = %q, want %q", urlFetch1.Name, "url-fetch-1")
+	}
+	externalVolume := sanitizeVolumeName("/etc/schemas")
+	if len(urlFetch1.VolumeMounts) != 1 || urlFetch1.VolumeMounts[0].Name != externalVolume || urlFetch1.VolumeMounts[0].MountPath != "/etc/schemas" {
+		t.Errorf("url-fetch-1 should mount %s at /etc/schemas, got %v", externalVolume, urlFetch1.VolumeMounts)
+	}
+
+	var foundVolume bool
+	for _, vol := range pod.Spec.Volumes {
+		if vol.Name == externalVolume && vol.EmptyDir != nil {
+			foundVolume = true
+		}
+	}
+	if !foundVolume {
+		t.Errorf("%s emptyDir volume not found", externalVolume)
+	}
+
+	// Agent container mounts the shared volume and loads the context file
+	container := pod.Spec.Containers[0]
+	var foundMount bool
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == externalVolume && mount.MountPath == "/etc/schemas" {
+			foundMount = true
+		}
+	}
+	if !foundMount {
+		t.Errorf("Agent container should mount %s at /etc/schemas", externalVolume)
+	}
+
+	var foundConfigContent bool
+	for _, env := range container.Env {
+		if env.Name == OpenCodeConfigContentEnvVar {
+			foundConfigContent = true
+		}
+	}
+	if !foundConfigContent {
+		t.Errorf("%s should be set when a URL context targets the context file", OpenCodeConfigContentEnvVar)
+	}
+}
+
 func TestBuildContextInitContainer(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -1269,7 +1823,7 @@ func TestBuildPod_WithExternalFileMounts(t *testing.T) {
 		{filePath: "/etc/github-app/github-app-iat.sh"},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, contextConfigMap, fileMounts, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, contextConfigMap, fileMounts, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify workspace emptyDir volume exists
 	var foundWorkspaceVolume bool
@@ -1448,7 +2002,7 @@ func TestBuildPod_WithOpenCodeConfig(t *testing.T) {
 		{filePath: OpenCodeConfigPath},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, configMap, fileMounts, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, configMap, fileMounts, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify OPENCODE_CONFIG env var is set
 	container := pod.Spec.Containers[0]
@@ -1509,7 +2063,7 @@ func TestBuildPod_WithoutOpenCodeConfig(t *testing.T) {
 		config:             nil, // No config provided
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, nil, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify OPENCODE_CONFIG env var is NOT set
 	container := pod.Spec.Containers[0]
@@ -1554,7 +2108,7 @@ func TestBuildPod_WithContextFile(t *testing.T) {
 		},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, contextConfigMap, fileMounts, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, contextConfigMap, fileMounts, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify OPENCODE_CONFIG_CONTENT env var is set
 	container := pod.Spec.Containers[0]
@@ -1597,7 +2151,7 @@ func TestBuildPod_WithoutContextFile(t *testing.T) {
 		{filePath: "/workspace/task.md"},
 	}
 
-	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, fileMounts, nil, nil, defaultSystemConfig(), "")
+	pod := buildPod(task, "test-task-pod", task.Namespace, cfg, nil, fileMounts, nil, nil, nil, defaultSystemConfig(), "")
 
 	// Verify OPENCODE_CONFIG_CONTENT env var is NOT set
 	container := pod.Spec.Containers[0]
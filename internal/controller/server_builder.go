@@ -5,9 +5,12 @@ package controller
 import (
 	"fmt"
 	"maps"
+	"net"
+	"net/url"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -27,8 +30,61 @@ const (
 	// ServerHealthPath is the path used for readiness probes.
 	// OpenCode's /session/status endpoint returns 200 if the server is healthy.
 	ServerHealthPath = "/session/status"
+
+	// ServerConfigHashAnnotation is set on the server Pod template to a hash of the
+	// contents of the Secrets and ConfigMaps the server uses, so changing them
+	// rolls out new server Pods.
+	ServerConfigHashAnnotation = "kubeopencode.io/config-hash"
+
+	// WorkspaceSyncContainerName is the name of the sidecar that receives per-Task
+	// workspaces uploaded by Server-mode Task Pods.
+	WorkspaceSyncContainerName = "workspace-sync"
+
+	// DefaultWorkspaceSyncPort is the port of the workspace-sync sidecar.
+	DefaultWorkspaceSyncPort int32 = 4097
+
+	// ServerSyncTokenSecretSuffix is appended to the Deployment name for the name of
+	// the Secret holding the token that authorizes changes to Task workspaces on
+	// workspace-sync.
+	ServerSyncTokenSecretSuffix = "-sync-token"
+
+	// ServerSyncTokenKey is the key of the token in the workspace-sync token Secret.
+	ServerSyncTokenKey = "token"
+
+	// ServerDrainPath is the workspace-sync endpoint that blocks until no session on
+	// the server is busy. The containers of server Pods call it in their preStop hook,
+	// so terminating servers finish the sessions of running Tasks first.
+	ServerDrainPath = "/drain"
+
+	// ServerTerminationGracePeriodSeconds bounds how long a terminating server Pod
+	// drains its sessions before it is killed.
+	ServerTerminationGracePeriodSeconds int64 = 1800
+
+	// ServerTaskWorkspacesRelPath is the relative path (from workspaceDir) of the
+	// per-Task workspaces in the server's workspace.
+	ServerTaskWorkspacesRelPath = ".kubeopencode/tasks"
+
+	// ServerWorkspacePVCSuffix is appended to the Deployment name for the name of the
+	// PersistentVolumeClaim of a persistent server workspace.
+	ServerWorkspacePVCSuffix = "-workspace"
+
+	// DefaultServerWorkspaceSize is the default size of a persistent server workspace.
+	DefaultServerWorkspaceSize = "10Gi"
+
+	// ServerGitRelPath is the relative path (from the root of a persistent workspace
+	// volume) where Git contexts of the server are kept, one directory per context.
+	ServerGitRelPath = ".kubeopencode/git"
 )
 
+// serverContexts holds the resolved Agent contexts of a Server-mode Agent.
+type serverContexts struct {
+	configMap  *corev1.ConfigMap
+	fileMounts []fileMount
+	dirMounts  []dirMount
+	gitMounts  []gitMount
+	urlMounts  []urlMount
+}
+
 // ServerDeploymentName returns the Deployment name for a Server-mode Agent.
 func ServerDeploymentName(agentName string) string {
 	return agentName + ServerDeploymentSuffix
@@ -40,14 +96,88 @@ func ServerServiceName(agentName string) string {
 	return agentName
 }
 
+// ServerContextConfigMapName returns the name of the ConfigMap holding the
+// context files and OpenCode config of a Server-mode Agent.
+func ServerContextConfigMapName(agentName string) string {
+	return ServerDeploymentName(agentName) + ContextConfigMapSuffix
+}
+
+// ServerWorkspacePVCName returns the name of the PersistentVolumeClaim holding the
+// persistent workspace of a Server-mode Agent.
+func ServerWorkspacePVCName(agentName string) string {
+	return ServerDeploymentName(agentName) + ServerWorkspacePVCSuffix
+}
+
+// ServerSyncTokenSecretName returns the name of the Secret holding the workspace-sync
+// token of a Server-mode Agent.
+func ServerSyncTokenSecretName(agentName string) string {
+	return ServerDeploymentName(agentName) + ServerSyncTokenSecretSuffix
+}
+
+// workspaceSyncTokenEnv returns the environment variable passing the workspace-sync
+// token of a Server-mode Agent to the sidecar and to workspace-upload.
+func workspaceSyncTokenEnv(agentName string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: "WORKSPACE_SYNC_TOKEN",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ServerSyncTokenSecretName(agentName)},
+				Key:                  ServerSyncTokenKey,
+			},
+		},
+	}
+}
+
+// BuildServerSyncTokenSecret creates the Secret holding the workspace-sync token of a
+// Server-mode Agent.
+func BuildServerSyncTokenSecret(agent *kubeopenv1alpha1.Agent, token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServerSyncTokenSecretName(agent.Name),
+			Namespace: agent.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "kubeopencode-server",
+				"app.kubernetes.io/instance":   agent.Name,
+				"app.kubernetes.io/component":  "server",
+				"app.kubernetes.io/managed-by": "kubeopencode",
+				"kubeopencode.io/agent":        agent.Name,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{ServerSyncTokenKey: []byte(token)},
+	}
+}
+
 // ServerURL returns the in-cluster URL for a Server-mode Agent.
 func ServerURL(agentName, namespace string, port int32) string {
 	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", agentName, namespace, port)
 }
 
+// ServerTaskWorkspaceDir returns the directory of a Task's workspace inside the
+// workspace of a Server-mode Agent. Task contexts are staged there and the attached
+// session runs in it.
+func ServerTaskWorkspaceDir(workspaceDir, taskNamespace, taskName string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", workspaceDir, ServerTaskWorkspacesRelPath, taskNamespace, taskName)
+}
+
+// ServerTaskWorkspaceURL returns the workspace-sync URL of a Task's workspace on the
+// server at serverURL. The sidecar is exposed by the same Service on DefaultWorkspaceSyncPort.
+func ServerTaskWorkspaceURL(serverURL, taskNamespace, taskName string) string {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return ""
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), fmt.Sprintf("%d", DefaultWorkspaceSyncPort))
+	u.Path = fmt.Sprintf("/workspaces/%s/%s", taskNamespace, taskName)
+	return u.String()
+}
+
 // BuildServerDeployment creates a Deployment for a Server-mode Agent.
-// The Deployment runs OpenCode in serve mode with a single replica.
-func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig, sysCfg systemConfig) *appsv1.Deployment {
+// The Deployment runs OpenCode in serve mode with a single replica. Credentials,
+// contexts (context-init, git-init, url-fetch) and the OpenCode config are applied
+// the same way as for Pod-mode Task Pods. configHash is recorded in the Pod template
+// so that changes to referenced Secrets and ConfigMaps trigger a rollout.
+func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig, sysCfg systemConfig, contexts serverContexts, configHash string) *appsv1.Deployment {
 	serverConfig := agent.Spec.ServerConfig
 	if serverConfig == nil {
 		return nil
@@ -72,41 +202,10 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 		maps.Copy(labels, agentCfg.podSpec.Labels)
 	}
 
-	// Build environment variables
-	envVars := []corev1.EnvVar{
-		{Name: "WORKSPACE_DIR", Value: agentCfg.workspaceDir},
-		// OpenCode permission settings for automated execution
-		{Name: OpenCodePermissionEnvVar, Value: DefaultOpenCodePermission},
-	}
-
-	// Add OpenCode config if provided
-	if agentCfg.config != nil {
-		envVars = append(envVars, corev1.EnvVar{
-			Name:  OpenCodeConfigEnvVar,
-			Value: OpenCodeConfigPath,
-		})
-	}
-
-	// Build volume mounts
-	volumeMounts := []corev1.VolumeMount{
-		{Name: ToolsVolumeName, MountPath: ToolsMountPath},
-		{Name: "workspace", MountPath: agentCfg.workspaceDir},
-	}
-
-	// Build volumes
-	volumes := []corev1.Volume{
-		{
-			Name: ToolsVolumeName,
-			VolumeSource: corev1.VolumeSource{
-				EmptyDir: &corev1.EmptyDirVolumeSource{},
-			},
-		},
-		{
-			Name: "workspace",
-			VolumeSource: corev1.VolumeSource{
-				EmptyDir: &corev1.EmptyDirVolumeSource{},
-			},
-		},
+	// Build volumes, init containers, environment and mounts shared with Task Pods
+	workload := buildAgentWorkload(agentCfg, nil, contexts.configMap, contexts.fileMounts, contexts.dirMounts, contexts.gitMounts, contexts.urlMounts, sysCfg)
+	if serverConfig.Workspace != nil {
+		mountPersistentWorkspace(&workload, ServerWorkspacePVCName(agent.Name), len(contexts.gitMounts))
 	}
 
 	// Build command for OpenCode serve mode
@@ -115,14 +214,27 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 		fmt.Sprintf("/tools/opencode serve --port %d --hostname 0.0.0.0", port),
 	}
 
+	// Both containers wait for running sessions before they are stopped, so rollouts
+	// and scale-downs do not cut off attached Tasks
+	drainLifecycle := &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   ServerDrainPath,
+				Port:   intstr.FromInt32(DefaultWorkspaceSyncPort),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+	}
+
 	// Build the main container
 	container := corev1.Container{
 		Name:            ServerContainerName,
 		Image:           agentCfg.executorImage,
 		ImagePullPolicy: corev1.PullIfNotPresent,
 		Command:         command,
-		Env:             envVars,
-		VolumeMounts:    volumeMounts,
+		Env:             workload.envVars,
+		EnvFrom:         workload.envFromSources,
+		VolumeMounts:    workload.volumeMounts,
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "http",
@@ -156,6 +268,7 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 			TimeoutSeconds:      5,
 			FailureThreshold:    3,
 		},
+		Lifecycle: drainLifecycle,
 	}
 
 	// Apply resource requirements if specified in podSpec
@@ -163,16 +276,42 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 		container.Resources = *agentCfg.podSpec.Resources
 	}
 
-	// Build init container to copy OpenCode binary
-	initContainer := buildOpenCodeInitContainer(agentCfg.agentImage)
+	// The workspace-sync sidecar extracts the contexts uploaded by Task Pods into
+	// per-Task directories of the shared workspace. Uploads and deletions must carry
+	// the Agent's token, as the sidecar is reachable through the Service.
+	syncContainer := corev1.Container{
+		Name:            WorkspaceSyncContainerName,
+		Image:           sysCfg.systemImage,
+		ImagePullPolicy: sysCfg.systemImagePullPolicy,
+		Command:         []string{"/kubeopencode", "workspace-sync"},
+		Env: []corev1.EnvVar{
+			{Name: "WORKSPACE_DIR", Value: agentCfg.workspaceDir},
+			{Name: "WORKSPACE_SYNC_PORT", Value: fmt.Sprintf("%d", DefaultWorkspaceSyncPort)},
+			{Name: "OPENCODE_SERVER_URL", Value: fmt.Sprintf("http://localhost:%d", port)},
+			workspaceSyncTokenEnv(agent.Name),
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          WorkspaceSyncContainerName,
+				ContainerPort: DefaultWorkspaceSyncPort,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "workspace", MountPath: agentCfg.workspaceDir},
+		},
+		Lifecycle: drainLifecycle,
+	}
 
 	// Build pod template spec
+	gracePeriod := ServerTerminationGracePeriodSeconds
 	podSpec := corev1.PodSpec{
-		ServiceAccountName: agentCfg.serviceAccountName,
-		InitContainers:     []corev1.Container{initContainer},
-		Containers:         []corev1.Container{container},
-		Volumes:            volumes,
-		RestartPolicy:      corev1.RestartPolicyAlways,
+		ServiceAccountName:            agentCfg.serviceAccountName,
+		InitContainers:                workload.initContainers,
+		Containers:                    []corev1.Container{container, syncContainer},
+		Volumes:                       workload.volumes,
+		RestartPolicy:                 corev1.RestartPolicyAlways,
+		TerminationGracePeriodSeconds: &gracePeriod,
 	}
 
 	// Apply scheduling configuration if provided
@@ -194,8 +333,20 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 		podSpec.RuntimeClassName = agentCfg.podSpec.RuntimeClassName
 	}
 
-	// Single replica for now (simplicity)
-	replicas := int32(1)
+	// The Agent controller scales the Deployment to zero when the server is idle
+	replicas := serverReplicaCount(serverConfig)
+
+	var annotations map[string]string
+	if configHash != "" {
+		annotations = map[string]string{ServerConfigHashAnnotation: configHash}
+	}
+
+	// A persistent workspace is usually ReadWriteOnce, so the old server Pod must
+	// release the volume before the new one can mount it
+	var strategy appsv1.DeploymentStrategy
+	if serverConfig.Workspace != nil {
+		strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
 
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -205,6 +356,7 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
+			Strategy: strategy,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"kubeopencode.io/agent": agent.Name,
@@ -212,7 +364,8 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
+					Labels:      labels,
+					Annotations: annotations,
 				},
 				Spec: podSpec,
 			},
@@ -220,6 +373,89 @@ func BuildServerDeployment(agent *kubeopenv1alpha1.Agent, agentCfg agentConfig,
 	}
 }
 
+// mountPersistentWorkspace backs the workspace of a server workload with the
+// PersistentVolumeClaim claimName. The Git contexts' emptyDir volumes are replaced by
+// directories on the same volume, so git-init finds the previous clones on restart
+// and refreshes them in place.
+func mountPersistentWorkspace(workload *agentWorkload, claimName string, gitContexts int) {
+	gitSubPaths := make(map[string]string, gitContexts)
+	for i := range gitContexts {
+		gitSubPaths[fmt.Sprintf("git-context-%d", i)] = fmt.Sprintf("%s/%d", ServerGitRelPath, i)
+	}
+
+	volumes := workload.volumes[:0]
+	for _, v := range workload.volumes {
+		if _, ok := gitSubPaths[v.Name]; ok {
+			continue
+		}
+		if v.Name == "workspace" {
+			v.VolumeSource = corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			}
+		}
+		volumes = append(volumes, v)
+	}
+	workload.volumes = volumes
+
+	remount := func(mounts []corev1.VolumeMount) {
+		for i := range mounts {
+			subPath, ok := gitSubPaths[mounts[i].Name]
+			if !ok {
+				continue
+			}
+			mounts[i].Name = "workspace"
+			if mounts[i].SubPath != "" {
+				subPath += "/" + mounts[i].SubPath
+			}
+			mounts[i].SubPath = subPath
+		}
+	}
+	remount(workload.volumeMounts)
+	for i := range workload.initContainers {
+		remount(workload.initContainers[i].VolumeMounts)
+	}
+}
+
+// BuildServerWorkspacePVC creates the PersistentVolumeClaim of a Server-mode Agent's
+// persistent workspace. Returns nil if the Agent has no persistent workspace.
+func BuildServerWorkspacePVC(agent *kubeopenv1alpha1.Agent) *corev1.PersistentVolumeClaim {
+	if agent.Spec.ServerConfig == nil || agent.Spec.ServerConfig.Workspace == nil {
+		return nil
+	}
+	workspace := agent.Spec.ServerConfig.Workspace
+
+	size := resource.MustParse(DefaultServerWorkspaceSize)
+	if workspace.Size != nil {
+		size = workspace.Size.DeepCopy()
+	}
+
+	accessModes := workspace.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServerWorkspacePVCName(agent.Name),
+			Namespace: agent.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "kubeopencode-server",
+				"app.kubernetes.io/instance":   agent.Name,
+				"app.kubernetes.io/component":  "server",
+				"app.kubernetes.io/managed-by": "kubeopencode",
+				"kubeopencode.io/agent":        agent.Name,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: workspace.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+}
+
 // BuildServerService creates a Service for a Server-mode Agent.
 func BuildServerService(agent *kubeopenv1alpha1.Agent) *corev1.Service {
 	serverConfig := agent.Spec.ServerConfig
@@ -257,6 +493,12 @@ func BuildServerService(agent *kubeopenv1alpha1.Agent) *corev1.Service {
 					TargetPort: intstr.FromInt32(port),
 					Protocol:   corev1.ProtocolTCP,
 				},
+				{
+					Name:       WorkspaceSyncContainerName,
+					Port:       DefaultWorkspaceSyncPort,
+					TargetPort: intstr.FromInt32(DefaultWorkspaceSyncPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
 			},
 		},
 	}
@@ -0,0 +1,112 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+const (
+	// ServerTemplateHashAnnotation is set on the server Deployment to a hash of the Pod
+	// template the controller built, so changes are detected without comparing
+	// against the defaulted template stored by the API server.
+	ServerTemplateHashAnnotation = "kubeopencode.io/template-hash"
+
+	// AgentConditionDraining indicates whether a change to the server is held back
+	// until the Agent's Running Tasks have finished.
+	AgentConditionDraining = "Draining"
+
+	// AgentReasonWaitingForTasks is the Draining reason while Running Tasks are awaited.
+	AgentReasonWaitingForTasks = "WaitingForTasks"
+	// AgentReasonDrained is the Draining reason once all Running Tasks have finished.
+	AgentReasonDrained = "Drained"
+	// AgentReasonDrainTimeout is the Draining reason of a change rolled out after
+	// the drain timeout with Tasks still running.
+	AgentReasonDrainTimeout = "DrainTimeout"
+
+	// DefaultServerDrainTimeout is how long a change to the server waits for the
+	// Agent's Running Tasks to finish.
+	DefaultServerDrainTimeout = 5 * time.Minute
+)
+
+// serverDrainTimeout returns how long a change to the server waits for Running Tasks.
+func serverDrainTimeout(serverConfig *kubeopenv1alpha1.ServerConfig) time.Duration {
+	if serverConfig == nil || serverConfig.DrainTimeoutSeconds == nil || *serverConfig.DrainTimeoutSeconds < 0 {
+		return DefaultServerDrainTimeout
+	}
+	return time.Duration(*serverConfig.DrainTimeoutSeconds) * time.Second
+}
+
+// podTemplateHash returns a hash of a Pod template.
+func podTemplateHash(template *corev1.PodTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash Pod template: %w", err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// runningServerTasks returns the number of Running Tasks of an Agent.
+func (r *AgentReconciler) runningServerTasks(ctx context.Context, agent *kubeopenv1alpha1.Agent) (int, error) {
+	var tasks kubeopenv1alpha1.TaskList
+	if err := r.List(ctx, &tasks); err != nil {
+		return 0, fmt.Errorf("failed to list Tasks: %w", err)
+	}
+	running := 0
+	for i := range tasks.Items {
+		task := &tasks.Items[i]
+		ref := task.Status.AgentRef
+		if ref != nil && ref.Name == agent.Name && ref.Namespace == agent.Namespace &&
+			task.Status.Phase == kubeopenv1alpha1.TaskPhaseRunning {
+			running++
+		}
+	}
+	return running, nil
+}
+
+// drainServer decides whether a change to the server can be rolled out, and reports
+// the progress in the Agent's Draining condition. The change waits while the Agent
+// has Running Tasks, for at most the drain timeout counted from the time the Agent
+// started draining. Returns whether to roll out the change, and otherwise when to
+// check again.
+func drainServer(agent *kubeopenv1alpha1.Agent, running int, now time.Time) (bool, time.Duration) {
+	cond := meta.FindStatusCondition(agent.Status.Conditions, AgentConditionDraining)
+	draining := cond != nil && cond.Status == metav1.ConditionTrue
+
+	if running == 0 {
+		if draining {
+			setAgentCondition(agent, AgentConditionDraining, metav1.ConditionFalse, AgentReasonDrained,
+				"All Running Tasks finished, rolling out the server change")
+		}
+		return true, 0
+	}
+
+	timeout := serverDrainTimeout(agent.Spec.ServerConfig)
+	var waited time.Duration
+	if draining {
+		waited = now.Sub(cond.LastTransitionTime.Time)
+	}
+	if waited >= timeout {
+		if draining {
+			setAgentCondition(agent, AgentConditionDraining, metav1.ConditionFalse, AgentReasonDrainTimeout,
+				fmt.Sprintf("Rolling out the server change after %s with %d Task(s) still running", timeout, running))
+		}
+		return true, 0
+	}
+
+	setAgentCondition(agent, AgentConditionDraining, metav1.ConditionTrue, AgentReasonWaitingForTasks,
+		fmt.Sprintf("Waiting for %d Running Task(s) to finish before rolling out the server change (timeout %s)", running, timeout))
+	return false, min(DefaultServerReconcileInterval, timeout-waited)
+}
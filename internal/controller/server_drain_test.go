@@ -0,0 +1,144 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestServerDrainTimeout(t *testing.T) {
+	seconds := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name         string
+		serverConfig *kubeopenv1alpha1.ServerConfig
+		want         time.Duration
+	}{
+		{name: "pod mode", serverConfig: nil, want: DefaultServerDrainTimeout},
+		{name: "not set", serverConfig: &kubeopenv1alpha1.ServerConfig{}, want: DefaultServerDrainTimeout},
+		{name: "disabled", serverConfig: &kubeopenv1alpha1.ServerConfig{DrainTimeoutSeconds: seconds(0)}, want: 0},
+		{name: "set", serverConfig: &kubeopenv1alpha1.ServerConfig{DrainTimeoutSeconds: seconds(60)}, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverDrainTimeout(tt.serverConfig); got != tt.want {
+				t.Errorf("serverDrainTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodTemplateHash(t *testing.T) {
+	template := func(image string) *corev1.PodTemplateSpec {
+		return &corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "server", Image: image}}},
+		}
+	}
+
+	a, err := podTemplateHash(template("opencode:1"))
+	if err != nil {
+		t.Fatalf("podTemplateHash() error = %v", err)
+	}
+	b, _ := podTemplateHash(template("opencode:1"))
+	c, _ := podTemplateHash(template("opencode:2"))
+	if a != b {
+		t.Errorf("podTemplateHash() of equal templates = %q, %q", a, b)
+	}
+	if a == c {
+		t.Errorf("podTemplateHash() of different templates = %q", a)
+	}
+}
+
+func TestDrainServer(t *testing.T) {
+	now := time.Now()
+	draining := func(since time.Duration) []metav1.Condition {
+		return []metav1.Condition{{
+			Type:               AgentConditionDraining,
+			Status:             metav1.ConditionTrue,
+			Reason:             AgentReasonWaitingForTasks,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}}
+	}
+	disabled := int32(0)
+
+	tests := []struct {
+		name         string
+		conditions   []metav1.Condition
+		serverConfig *kubeopenv1alpha1.ServerConfig
+		running      int
+		wantRollout  bool
+		wantRequeue  time.Duration
+		wantReason   string // empty if the condition is not set
+	}{
+		{
+			name:        "no running tasks",
+			running:     0,
+			wantRollout: true,
+		},
+		{
+			name:        "starts draining",
+			running:     2,
+			wantRequeue: DefaultServerReconcileInterval,
+			wantReason:  AgentReasonWaitingForTasks,
+		},
+		{
+			name:        "keeps draining",
+			conditions:  draining(DefaultServerDrainTimeout - 10*time.Second),
+			running:     1,
+			wantRequeue: 10 * time.Second,
+			wantReason:  AgentReasonWaitingForTasks,
+		},
+		{
+			name:        "drained",
+			conditions:  draining(time.Minute),
+			running:     0,
+			wantRollout: true,
+			wantReason:  AgentReasonDrained,
+		},
+		{
+			name:        "timed out",
+			conditions:  draining(DefaultServerDrainTimeout),
+			running:     1,
+			wantRollout: true,
+			wantReason:  AgentReasonDrainTimeout,
+		},
+		{
+			name:         "draining disabled",
+			serverConfig: &kubeopenv1alpha1.ServerConfig{DrainTimeoutSeconds: &disabled},
+			running:      1,
+			wantRollout:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := &kubeopenv1alpha1.Agent{
+				Spec:   kubeopenv1alpha1.AgentSpec{ServerConfig: tt.serverConfig},
+				Status: kubeopenv1alpha1.AgentStatus{Conditions: tt.conditions},
+			}
+			rollout, requeue := drainServer(agent, tt.running, now)
+			if rollout != tt.wantRollout || requeue != tt.wantRequeue {
+				t.Errorf("drainServer() = %v, %v, want %v, %v", rollout, requeue, tt.wantRollout, tt.wantRequeue)
+			}
+			cond := meta.FindStatusCondition(agent.Status.Conditions, AgentConditionDraining)
+			reason := ""
+			if cond != nil {
+				reason = cond.Reason
+			}
+			if reason != tt.wantReason {
+				t.Errorf("Draining reason = %q, want %q", reason, tt.wantReason)
+			}
+			if cond != nil && (cond.Status == metav1.ConditionTrue) == tt.wantRollout {
+				t.Errorf("Draining status = %s with rollout %v", cond.Status, tt.wantRollout)
+			}
+		})
+	}
+}
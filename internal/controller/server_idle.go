@@ -0,0 +1,93 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// serverIdleTimeout returns the idle timeout after which the server of the Agent is
+// scaled down, or 0 if the server always runs.
+func serverIdleTimeout(serverConfig *kubeopenv1alpha1.ServerConfig) time.Duration {
+	if serverConfig == nil || serverConfig.IdleTimeoutSeconds == nil || *serverConfig.IdleTimeoutSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(*serverConfig.IdleTimeoutSeconds) * time.Second
+}
+
+// isTaskInProgress reports whether a Task has not finished yet.
+func isTaskInProgress(task *kubeopenv1alpha1.Task) bool {
+	return task.DeletionTimestamp == nil &&
+		task.Status.Phase != kubeopenv1alpha1.TaskPhaseCompleted &&
+		task.Status.Phase != kubeopenv1alpha1.TaskPhaseFailed
+}
+
+// serverReplicas returns the desired number of server replicas of a Server-mode Agent
+// and, while it is idle but not yet scaled down, how long until it should be.
+// Tasks in progress from any namespace keep the server running and are recorded in
+// status.serverStatus.lastActiveTime, which updateAgentStatus persists.
+func (r *AgentReconciler) serverReplicas(ctx context.Context, agent *kubeopenv1alpha1.Agent) (int32, time.Duration, error) {
+	replicas := serverReplicaCount(agent.Spec.ServerConfig)
+	timeout := serverIdleTimeout(agent.Spec.ServerConfig)
+	if timeout == 0 {
+		if agent.Status.ServerStatus != nil {
+			agent.Status.ServerStatus.LastActiveTime = nil
+		}
+		return replicas, 0, nil
+	}
+
+	var tasks kubeopenv1alpha1.TaskList
+	if err := r.List(ctx, &tasks); err != nil {
+		return 0, 0, fmt.Errorf("failed to list Tasks: %w", err)
+	}
+	agentKey := client.ObjectKeyFromObject(agent)
+	active := false
+	for i := range tasks.Items {
+		task := &tasks.Items[i]
+		if key, ok := taskAgentKey(task); ok && key == agentKey && isTaskInProgress(task) {
+			active = true
+			break
+		}
+	}
+
+	if agent.Status.ServerStatus == nil {
+		agent.Status.ServerStatus = &kubeopenv1alpha1.ServerStatus{}
+	}
+	now := time.Now()
+	lastActive := agent.Status.ServerStatus.LastActiveTime
+	if active || lastActive == nil {
+		// The idle period starts when the last Task finishes, or when idle scaling is
+		// first enabled for the Agent
+		agent.Status.ServerStatus.LastActiveTime = &metav1.Time{Time: now}
+		return replicas, timeout, nil
+	}
+
+	idle := now.Sub(lastActive.Time)
+	if idle >= timeout {
+		return 0, 0, nil
+	}
+	return replicas, timeout - idle, nil
+}
+
+// agentForTask maps Task events to the Server-mode Agent the Task runs on, so idle
+// servers are scaled up for new Tasks and scaled down after the last one. The Agent
+// is the resolved status.agentRef, which also covers Agents supplied by a TaskTemplate.
+func (r *AgentReconciler) agentForTask(ctx context.Context, obj client.Object) []ctrl.Request {
+	task, ok := obj.(*kubeopenv1alpha1.Task)
+	if !ok {
+		return nil
+	}
+	key, ok := taskAgentKey(task)
+	if !ok {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: key}}
+}
@@ -0,0 +1,34 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestServerIdleTimeout(t *testing.T) {
+	seconds := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name         string
+		serverConfig *kubeopenv1alpha1.ServerConfig
+		want         time.Duration
+	}{
+		{name: "pod mode", serverConfig: nil, want: 0},
+		{name: "not set", serverConfig: &kubeopenv1alpha1.ServerConfig{}, want: 0},
+		{name: "zero", serverConfig: &kubeopenv1alpha1.ServerConfig{IdleTimeoutSeconds: seconds(0)}, want: 0},
+		{name: "set", serverConfig: &kubeopenv1alpha1.ServerConfig{IdleTimeoutSeconds: seconds(600)}, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverIdleTimeout(tt.serverConfig); got != tt.want {
+				t.Errorf("serverIdleTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
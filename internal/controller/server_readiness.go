@@ -0,0 +1,173 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// DefaultServerReadyTimeout is how long a Task waits for the server of its
+// Server-mode Agent to become ready before it fails.
+const DefaultServerReadyTimeout = 10 * time.Minute
+
+// serverReadyTimeout returns how long Tasks wait for the server to become ready.
+func serverReadyTimeout(serverConfig *kubeopenv1alpha1.ServerConfig) time.Duration {
+	if serverConfig == nil || serverConfig.ReadyTimeoutSeconds == nil || *serverConfig.ReadyTimeoutSeconds <= 0 {
+		return DefaultServerReadyTimeout
+	}
+	return time.Duration(*serverConfig.ReadyTimeoutSeconds) * time.Second
+}
+
+// serverWaitCondition returns the Queued reason and message for a Task that has to
+// wait for the server of its Server-mode Agent: ServerDraining while the Agent drains
+// its Running Tasks before rolling out a change, ServerStarting while the server is
+// started after being scaled down when idle, ServerNotReady otherwise. Returns an
+// empty reason if the Agent's ServerReady condition is True and it is not draining.
+func serverWaitCondition(agent *kubeopenv1alpha1.Agent) (string, string) {
+	cond := meta.FindStatusCondition(agent.Status.Conditions, AgentConditionServerReady)
+	switch {
+	case meta.IsStatusConditionTrue(agent.Status.Conditions, AgentConditionDraining):
+		return kubeopenv1alpha1.ReasonServerDraining, fmt.Sprintf("Waiting for agent %q to roll out a change to its server", agent.Name)
+	case cond != nil && cond.Status == metav1.ConditionTrue:
+		return "", ""
+	case cond != nil && cond.Reason == AgentReasonScaledToZero:
+		return kubeopenv1alpha1.ReasonServerStarting, fmt.Sprintf("Waiting for the server of agent %q to start", agent.Name)
+	case cond != nil && cond.Message != "":
+		return kubeopenv1alpha1.ReasonServerNotReady, fmt.Sprintf("Waiting for the server of agent %q to become ready: %s", agent.Name, cond.Message)
+	default:
+		return kubeopenv1alpha1.ReasonServerNotReady, fmt.Sprintf("Waiting for the server of agent %q to become ready", agent.Name)
+	}
+}
+
+// isWaitingForServer reports whether a Queued Task is waiting for the server of its Agent.
+func isWaitingForServer(task *kubeopenv1alpha1.Task) bool {
+	cond := meta.FindStatusCondition(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+	return cond != nil && cond.Status == metav1.ConditionTrue &&
+		(cond.Reason == kubeopenv1alpha1.ReasonServerStarting || cond.Reason == kubeopenv1alpha1.ReasonServerNotReady ||
+			cond.Reason == kubeopenv1alpha1.ReasonServerDraining)
+}
+
+// serverWaitReason returns the Queued reason and message if the Task has to wait for
+// the server of its Agent. Returns an empty reason for Pod-mode Agents and ready servers.
+func (r *TaskReconciler) serverWaitReason(ctx context.Context, cfg agentConfig, agentName, agentNamespace string) (string, string, error) {
+	if cfg.serverConfig == nil {
+		return "", "", nil
+	}
+	agent := &kubeopenv1alpha1.Agent{}
+	if err := r.Get(ctx, types.NamespacedName{Name: agentName, Namespace: agentNamespace}, agent); err != nil {
+		return "", "", err
+	}
+	reason, message := serverWaitCondition(agent)
+	return reason, message, nil
+}
+
+// handleWaitingForServer re-checks a Task queued until the server of its Agent is
+// ready. Once it is (or the Agent is no longer in Server mode), the Task goes back
+// through initializeTask, which checks the Agent's capacity. Tasks that waited longer
+// than the Agent's readyTimeoutSeconds fail.
+func (r *TaskReconciler) handleWaitingForServer(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	agentName, agentNamespace := task.Status.AgentRef.Name, task.Status.AgentRef.Namespace
+
+	agent := &kubeopenv1alpha1.Agent{}
+	err := r.Get(ctx, types.NamespacedName{Name: agentName, Namespace: agentNamespace}, agent)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	// A deleted Agent is reported by initializeTask
+	if err == nil && IsServerMode(agent) {
+		if reason, message := serverWaitCondition(agent); reason != "" {
+			queued := meta.FindStatusCondition(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+			timeout := serverReadyTimeout(agent.Spec.ServerConfig)
+			waited := time.Since(queued.LastTransitionTime.Time)
+			if waited >= timeout {
+				log.Info("server not ready in time, failing task", "agent", agentName, "timeout", timeout)
+				task.Status.Phase = kubeopenv1alpha1.TaskPhaseFailed
+				task.Status.QueuePosition = 0
+				now := metav1.Now()
+				task.Status.CompletionTime = &now
+				meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+					Type:    kubeopenv1alpha1.ConditionTypeReady,
+					Status:  metav1.ConditionFalse,
+					Reason:  kubeopenv1alpha1.ReasonServerNotReady,
+					Message: fmt.Sprintf("The server of agent %q did not become ready within %s (%s)", agentName, timeout, queued.Message),
+				})
+				if err := r.Status().Update(ctx, task); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, nil
+			}
+
+			// Keep the reason current, e.g. ServerStarting turns into ServerNotReady
+			// once the server is scaled up but not ready yet
+			if queued.Reason != reason || queued.Message != message {
+				meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+					Type:    kubeopenv1alpha1.ConditionTypeQueued,
+					Status:  metav1.ConditionTrue,
+					Reason:  reason,
+					Message: message,
+				})
+				if err := r.Status().Update(ctx, task); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{RequeueAfter: min(DefaultQueuedRequeueDelay, timeout-waited)}, nil
+		}
+	}
+
+	log.Info("server ready, proceeding with task", "agent", agentName)
+	task.Status.Phase = ""
+	task.Status.QueuePosition = 0
+	meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+		Type:    kubeopenv1alpha1.ConditionTypeQueued,
+		Status:  metav1.ConditionFalse,
+		Reason:  kubeopenv1alpha1.ReasonServerReady,
+		Message: fmt.Sprintf("The server of agent %q is ready", agentName),
+	})
+	if err := r.Status().Update(ctx, task); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// tasksWaitingForServer maps Agent events to the Tasks queued until its server is
+// ready, so they start as soon as it is.
+func (r *TaskReconciler) tasksWaitingForServer(ctx context.Context, obj client.Object) []ctrl.Request {
+	agent, ok := obj.(*kubeopenv1alpha1.Agent)
+	if !ok || !meta.IsStatusConditionTrue(agent.Status.Conditions, AgentConditionServerReady) ||
+		meta.IsStatusConditionTrue(agent.Status.Conditions, AgentConditionDraining) {
+		return nil
+	}
+
+	var tasks kubeopenv1alpha1.TaskList
+	if err := r.List(ctx, &tasks); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list Tasks waiting for the server", "agent", agent.Name)
+		return nil
+	}
+
+	agentKey := client.ObjectKeyFromObject(agent)
+	var requests []ctrl.Request
+	for i := range tasks.Items {
+		task := &tasks.Items[i]
+		if task.Status.Phase != kubeopenv1alpha1.TaskPhaseQueued || !isWaitingForServer(task) {
+			continue
+		}
+		if key, ok := taskAgentKey(task); ok && key == agentKey {
+			requests = append(requests, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(task)})
+		}
+	}
+	return requests
+}
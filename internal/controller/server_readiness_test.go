@@ -0,0 +1,85 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestServerWaitCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []metav1.Condition
+		wantReason string
+	}{
+		{
+			name:       "no status yet",
+			wantReason: kubeopenv1alpha1.ReasonServerNotReady,
+		},
+		{
+			name: "ready",
+			conditions: []metav1.Condition{{
+				Type: AgentConditionServerReady, Status: metav1.ConditionTrue, Reason: "DeploymentReady",
+			}},
+			wantReason: "",
+		},
+		{
+			name: "not ready",
+			conditions: []metav1.Condition{{
+				Type: AgentConditionServerReady, Status: metav1.ConditionFalse, Reason: "DeploymentNotReady",
+			}},
+			wantReason: kubeopenv1alpha1.ReasonServerNotReady,
+		},
+		{
+			name: "scaled to zero",
+			conditions: []metav1.Condition{{
+				Type: AgentConditionServerReady, Status: metav1.ConditionFalse, Reason: AgentReasonScaledToZero,
+			}},
+			wantReason: kubeopenv1alpha1.ReasonServerStarting,
+		},
+		{
+			name: "draining",
+			conditions: []metav1.Condition{
+				{Type: AgentConditionServerReady, Status: metav1.ConditionTrue, Reason: "DeploymentReady"},
+				{Type: AgentConditionDraining, Status: metav1.ConditionTrue, Reason: AgentReasonWaitingForTasks},
+			},
+			wantReason: kubeopenv1alpha1.ReasonServerDraining,
+		},
+		{
+			name: "drained",
+			conditions: []metav1.Condition{
+				{Type: AgentConditionServerReady, Status: metav1.ConditionTrue, Reason: "DeploymentReady"},
+				{Type: AgentConditionDraining, Status: metav1.ConditionFalse, Reason: AgentReasonDrained},
+			},
+			wantReason: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent"},
+				Status:     kubeopenv1alpha1.AgentStatus{Conditions: tt.conditions},
+			}
+			if reason, _ := serverWaitCondition(agent); reason != tt.wantReason {
+				t.Errorf("serverWaitCondition() reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestServerReadyTimeout(t *testing.T) {
+	if got := serverReadyTimeout(&kubeopenv1alpha1.ServerConfig{}); got != DefaultServerReadyTimeout {
+		t.Errorf("serverReadyTimeout() = %v, want %v", got, DefaultServerReadyTimeout)
+	}
+	seconds := int32(30)
+	if got := serverReadyTimeout(&kubeopenv1alpha1.ServerConfig{ReadyTimeoutSeconds: &seconds}); got != 30*time.Second {
+		t.Errorf("serverReadyTimeout() = %v, want 30s", got)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// serverReplicaCount returns the configured number of server replicas of a Server-mode Agent.
+func serverReplicaCount(serverConfig *kubeopenv1alpha1.ServerConfig) int32 {
+	if serverConfig == nil || serverConfig.Replicas == nil || *serverConfig.Replicas < 1 {
+		return 1
+	}
+	return *serverConfig.Replicas
+}
+
+// validateServerWorkspace checks that all server replicas can mount the persistent
+// workspace of a Server-mode Agent. The replicas share one PersistentVolumeClaim,
+// which needs the ReadWriteMany access mode if there is more than one.
+func validateServerWorkspace(serverConfig *kubeopenv1alpha1.ServerConfig) error {
+	replicas := serverReplicaCount(serverConfig)
+	if serverConfig == nil || serverConfig.Workspace == nil || replicas == 1 {
+		return nil
+	}
+	if slices.Contains(serverConfig.Workspace.AccessModes, corev1.ReadWriteMany) {
+		return nil
+	}
+	return fmt.Errorf("a persistent workspace shared by %d server replicas needs the %s access mode", replicas, corev1.ReadWriteMany)
+}
+
+// ServerReplicaURL returns the URL of a single server replica (Pod) of a Server-mode Agent.
+func ServerReplicaURL(podIP string, port int32) string {
+	return "http://" + net.JoinHostPort(podIP, strconv.Itoa(int(port)))
+}
+
+// serverPodLabels selects the server Pods of an Agent. Task Pods carry the agent
+// label as well, so the component label is needed to tell them apart.
+func serverPodLabels(agentName string) client.MatchingLabels {
+	return client.MatchingLabels{
+		AgentLabelKey:                 agentName,
+		"app.kubernetes.io/component": "server",
+	}
+}
+
+// isServerReplicaAvailable reports whether a server Pod can take new Tasks: it is
+// ready, has an IP and is not terminating. Terminating Pods are draining the
+// sessions of the Tasks already pinned to them.
+func isServerReplicaAvailable(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// pickServerReplica returns the available server Pod with the fewest Tasks pinned to
+// it (load is keyed by Pod name), or nil if none is available. Ties go to the Pod
+// with the lowest name, so concurrent picks are deterministic.
+func pickServerReplica(pods []corev1.Pod, load map[string]int) *corev1.Pod {
+	var candidates []*corev1.Pod
+	for i := range pods {
+		if isServerReplicaAvailable(&pods[i]) {
+			candidates = append(candidates, &pods[i])
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		li, lj := load[candidates[i].Name], load[candidates[j].Name]
+		if li != lj {
+			return li < lj
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates[0]
+}
+
+// assignServerReplica picks the server replica a new Task of a multi-replica
+// Server-mode Agent is pinned to: the available replica with the fewest Tasks in
+// progress. Returns nil if no replica is available.
+func (r *TaskReconciler) assignServerReplica(ctx context.Context, agentName, agentNamespace string) (*corev1.Pod, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(agentNamespace), serverPodLabels(agentName)); err != nil {
+		return nil, fmt.Errorf("failed to list server Pods: %w", err)
+	}
+
+	// Tasks from any namespace can use the Agent
+	var tasks kubeopenv1alpha1.TaskList
+	if err := r.List(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to list Tasks: %w", err)
+	}
+	load := make(map[string]int)
+	for i := range tasks.Items {
+		task := &tasks.Items[i]
+		if task.Status.ServerPod == "" || task.Status.AgentRef == nil || !isTaskInProgress(task) ||
+			task.Status.AgentRef.Name != agentName || task.Status.AgentRef.Namespace != agentNamespace {
+			continue
+		}
+		load[task.Status.ServerPod]++
+	}
+
+	return pickServerReplica(pods.Items, load), nil
+}
+
+// serverReplicaURL returns the URL of a server replica, or "" if the replica is gone.
+func (r *TaskReconciler) serverReplicaURL(ctx context.Context, namespace, podName string, port int32) (string, error) {
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Name: podName, Namespace: namespace}, pod); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if pod.Status.PodIP == "" {
+		return "", nil
+	}
+	return ServerReplicaURL(pod.Status.PodIP, port), nil
+}
+
+// taskServerURL returns the URL of the server a Task of a Server-mode Agent attached
+// to: its pinned replica (status.serverPod), or the Agent's Service. Returns "" if
+// the pinned replica is gone, together with the sessions it held.
+func (r *TaskReconciler) taskServerURL(ctx context.Context, task *kubeopenv1alpha1.Task, agent *kubeopenv1alpha1.Agent) (string, error) {
+	port := GetServerPort(agent)
+	if task.Status.ServerPod == "" {
+		return ServerURL(agent.Name, agent.Namespace, port), nil
+	}
+	return r.serverReplicaURL(ctx, agent.Namespace, task.Status.ServerPod, port)
+}
@@ -0,0 +1,154 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestServerReplicaCount(t *testing.T) {
+	replicas := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name         string
+		serverConfig *kubeopenv1alpha1.ServerConfig
+		want         int32
+	}{
+		{name: "pod mode", serverConfig: nil, want: 1},
+		{name: "not set", serverConfig: &kubeopenv1alpha1.ServerConfig{}, want: 1},
+		{name: "zero", serverConfig: &kubeopenv1alpha1.ServerConfig{Replicas: replicas(0)}, want: 1},
+		{name: "set", serverConfig: &kubeopenv1alpha1.ServerConfig{Replicas: replicas(3)}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverReplicaCount(tt.serverConfig); got != tt.want {
+				t.Errorf("serverReplicaCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerReplicaURL(t *testing.T) {
+	if got := ServerReplicaURL("10.0.0.5", 4096); got != "http://10.0.0.5:4096" {
+		t.Errorf("ServerReplicaURL() = %q", got)
+	}
+	if got := ServerReplicaURL("fd00::5", 4096); got != "http://[fd00::5]:4096" {
+		t.Errorf("ServerReplicaURL() of IPv6 address = %q", got)
+	}
+}
+
+func TestPickServerReplica(t *testing.T) {
+	pod := func(name string, ready bool, mutate ...func(*corev1.Pod)) corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		p := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				PodIP:      "10.0.0.1",
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+		for _, m := range mutate {
+			m(&p)
+		}
+		return p
+	}
+	terminating := func(p *corev1.Pod) {
+		now := metav1.Now()
+		p.DeletionTimestamp = &now
+	}
+	noIP := func(p *corev1.Pod) { p.Status.PodIP = "" }
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		load map[string]int
+		want string
+	}{
+		{name: "no pods", want: ""},
+		{
+			name: "none available",
+			pods: []corev1.Pod{pod("a", false), pod("b", true, terminating), pod("c", true, noIP)},
+			want: "",
+		},
+		{
+			name: "least loaded",
+			pods: []corev1.Pod{pod("a", true), pod("b", true), pod("c", true)},
+			load: map[string]int{"a": 2, "b": 1, "c": 3},
+			want: "b",
+		},
+		{
+			name: "ties go to the lowest name",
+			pods: []corev1.Pod{pod("c", true), pod("b", true), pod("a", true)},
+			load: map[string]int{"a": 1},
+			want: "b",
+		},
+		{
+			name: "skips terminating pods",
+			pods: []corev1.Pod{pod("a", true, terminating), pod("b", true)},
+			load: map[string]int{"b": 5},
+			want: "b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickServerReplica(tt.pods, tt.load)
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("pickServerReplica() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateServerWorkspace(t *testing.T) {
+	replicas := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name         string
+		serverConfig *kubeopenv1alpha1.ServerConfig
+		wantErr      bool
+	}{
+		{name: "pod mode", serverConfig: nil},
+		{name: "no persistent workspace", serverConfig: &kubeopenv1alpha1.ServerConfig{Replicas: replicas(3)}},
+		{name: "single replica", serverConfig: &kubeopenv1alpha1.ServerConfig{Workspace: &kubeopenv1alpha1.ServerWorkspace{}}},
+		{
+			name:         "replicas with the default access mode",
+			serverConfig: &kubeopenv1alpha1.ServerConfig{Replicas: replicas(2), Workspace: &kubeopenv1alpha1.ServerWorkspace{}},
+			wantErr:      true,
+		},
+		{
+			name: "replicas with ReadWriteOnce",
+			serverConfig: &kubeopenv1alpha1.ServerConfig{Replicas: replicas(2), Workspace: &kubeopenv1alpha1.ServerWorkspace{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "replicas with ReadWriteMany",
+			serverConfig: &kubeopenv1alpha1.ServerConfig{Replicas: replicas(2), Workspace: &kubeopenv1alpha1.ServerWorkspace{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadWriteMany},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateServerWorkspace(tt.serverConfig); (err != nil) != tt.wantErr {
+				t.Errorf("validateServerWorkspace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
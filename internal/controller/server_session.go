@@ -0,0 +1,472 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+const (
+	// DefaultSessionLookupInterval is how often the controller looks for the OpenCode
+	// session of a Running Server-mode Task until it is found
+	DefaultSessionLookupInterval = 10 * time.Second
+
+	// DefaultSessionCleanupTimeout is how long the controller retries deleting the
+	// OpenCode sessions of a stopped or deleted Task before giving up, so an
+	// unreachable server does not block Task deletion forever
+	DefaultSessionCleanupTimeout = 5 * time.Minute
+
+	// openCodeRequestTimeout bounds a single request to an OpenCode server
+	openCodeRequestTimeout = 5 * time.Second
+)
+
+// sessionIDPattern matches OpenCode session IDs (e.g., ses_5f2a...)
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// OpenCodeSession is an OpenCode session as returned by the server's session API.
+type OpenCodeSession struct {
+	ID        string `json:"id"`
+	ParentID  string `json:"parentID,omitempty"`
+	Directory string `json:"directory"`
+	Time      struct {
+		Created int64 `json:"created"`
+		Updated int64 `json:"updated,omitempty"`
+	} `json:"time"`
+}
+
+// OpenCodeSessionStatus is the status of a session as returned by GET /session/status.
+// Type is "idle", "busy" or "retry".
+type OpenCodeSessionStatus struct {
+	Type string `json:"type"`
+}
+
+// OpenCodeClient talks to the OpenCode server of a Server-mode Agent.
+// directory selects the OpenCode instance (working directory) the request applies to.
+type OpenCodeClient interface {
+	// ListSessions returns the sessions of the server for the directory.
+	ListSessions(ctx context.Context, serverURL, directory string) ([]OpenCodeSession, error)
+	// GetSession returns a session, or nil if it does not exist.
+	GetSession(ctx context.Context, serverURL, directory, sessionID string) (*OpenCodeSession, error)
+	// DeleteSession deletes a session and its child sessions.
+	// Deleting a session that does not exist is not an error.
+	DeleteSession(ctx context.Context, serverURL, directory, sessionID string) error
+	// SessionStatus returns the status of the sessions for the directory, keyed by
+	// session ID. Sessions without an entry are idle.
+	SessionStatus(ctx context.Context, serverURL, directory string) (map[string]OpenCodeSessionStatus, error)
+}
+
+// httpOpenCodeClient is the OpenCodeClient for the OpenCode HTTP API.
+type httpOpenCodeClient struct {
+	client *http.Client
+}
+
+// ListSessions calls GET /session.
+func (c *httpOpenCodeClient) ListSessions(ctx context.Context, serverURL, directory string) ([]OpenCodeSession, error) {
+	resp, err := c.do(ctx, http.MethodGet, serverURL+"/session?"+url.Values{"directory": {directory}}.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list sessions: unexpected status %d", resp.StatusCode)
+	}
+	var sessions []OpenCodeSession
+	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// GetSession calls GET /session/:id.
+func (c *httpOpenCodeClient) GetSession(ctx context.Context, serverURL, directory, sessionID string) (*OpenCodeSession, error) {
+	resp, err := c.do(ctx, http.MethodGet, serverURL+"/session/"+url.PathEscape(sessionID)+"?"+url.Values{"directory": {directory}}.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get session %s: unexpected status %d", sessionID, resp.StatusCode)
+	}
+	var session OpenCodeSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return nil, fmt.Errorf("get session %s: %w", sessionID, err)
+	}
+	return &session, nil
+}
+
+// DeleteSession calls DELETE /session/:id.
+func (c *httpOpenCodeClient) DeleteSession(ctx context.Context, serverURL, directory, sessionID string) error {
+	resp, err := c.do(ctx, http.MethodDelete, serverURL+"/session/"+url.PathEscape(sessionID)+"?"+url.Values{"directory": {directory}}.Encode())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf("delete session %s: unexpected status %d", sessionID, resp.StatusCode)
+}
+
+// SessionStatus calls GET /session/status.
+func (c *httpOpenCodeClient) SessionStatus(ctx context.Context, serverURL, directory string) (map[string]OpenCodeSessionStatus, error) {
+	resp, err := c.do(ctx, http.MethodGet, serverURL+"/session/status?"+url.Values{"directory": {directory}}.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("session status: unexpected status %d", resp.StatusCode)
+	}
+	var status map[string]OpenCodeSessionStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("session status: %w", err)
+	}
+	return status, nil
+}
+
+func (c *httpOpenCodeClient) do(ctx context.Context, method, target string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, openCodeRequestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := c.client.Do(req) //nolint:gosec // URL is the in-cluster Agent Service
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// Release the timeout with the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose cancels a request context when the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// openCodeClient returns the configured OpenCode client, or the HTTP client.
+func (r *TaskReconciler) openCodeClient() OpenCodeClient {
+	if r.OpenCode != nil {
+		return r.OpenCode
+	}
+	return &httpOpenCodeClient{client: http.DefaultClient}
+}
+
+// serverSessionTarget returns the server URL and the Task workspace directory of a
+// Task whose Agent runs in Server mode. ok is false if the Agent does not exist or
+// is not in Server mode, or if the server replica the Task was pinned to is gone.
+func (r *TaskReconciler) serverSessionTarget(ctx context.Context, task *kubeopenv1alpha1.Task) (serverURL, directory string, ok bool) {
+	if task.Status.AgentRef == nil {
+		return "", "", false
+	}
+	agent := &kubeopenv1alpha1.Agent{}
+	agentKey := types.NamespacedName{Name: task.Status.AgentRef.Name, Namespace: task.Status.AgentRef.Namespace}
+	if err := r.Get(ctx, agentKey, agent); err != nil || !IsServerMode(agent) {
+		return "", "", false
+	}
+	serverURL, err := r.taskServerURL(ctx, task, agent)
+	if err != nil || serverURL == "" {
+		return "", "", false
+	}
+	return serverURL, ServerTaskWorkspaceDir(agent.Spec.WorkspaceDir, task.Namespace, task.Name), true
+}
+
+// taskSessions returns the root sessions (not subagent sessions) in the Task's workspace,
+// newest first.
+func taskSessions(sessions []OpenCodeSession, directory string) []OpenCodeSession {
+	var result []OpenCodeSession
+	for _, s := range sessions {
+		if s.ParentID == "" && s.Directory == directory {
+			result = append(result, s)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Created > result[j].Time.Created
+	})
+	return result
+}
+
+// recordServerSession looks up the OpenCode session of a Running Server-mode Task and
+// sets status.sessionID. Returns true if the status changed, and when to look again
+// if the session was not found yet (0 if there is nothing to look for).
+func (r *TaskReconciler) recordServerSession(ctx context.Context, task *kubeopenv1alpha1.Task) (bool, time.Duration) {
+	if task.Status.SessionID != "" {
+		return false, 0
+	}
+	serverURL, directory, ok := r.serverSessionTarget(ctx, task)
+	if !ok {
+		return false, 0
+	}
+
+	sessions, err := r.openCodeClient().ListSessions(ctx, serverURL, directory)
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("unable to list OpenCode sessions", "server", serverURL, "error", err.Error())
+		return false, DefaultSessionLookupInterval
+	}
+	found := taskSessions(sessions, directory)
+	if len(found) == 0 {
+		return false, DefaultSessionLookupInterval
+	}
+
+	task.Status.SessionID = found[0].ID
+	log.FromContext(ctx).Info("recorded OpenCode session", "session", task.Status.SessionID)
+	return true, 0
+}
+
+// deleteServerSessions deletes the OpenCode sessions of a Server-mode Task: the
+// recorded session and any other session in the Task's workspace (e.g., from
+// retried attempts or a session not recorded yet). Clears status.sessionID on success.
+func (r *TaskReconciler) deleteServerSessions(ctx context.Context, task *kubeopenv1alpha1.Task) error {
+	serverURL, directory, ok := r.serverSessionTarget(ctx, task)
+	if !ok {
+		// Without the server there are no sessions left to delete
+		task.Status.SessionID = ""
+		return nil
+	}
+
+	oc := r.openCodeClient()
+	sessions, err := oc.ListSessions(ctx, serverURL, directory)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(sessions)+1)
+	if task.Status.SessionID != "" {
+		ids = append(ids, task.Status.SessionID)
+	}
+	for _, s := range taskSessions(sessions, directory) {
+		if s.ID != task.Status.SessionID {
+			ids = append(ids, s.ID)
+		}
+	}
+
+	// Sessions continued by follow-up Tasks (sessionRef) are shared; keep them until
+	// the last Task using them is stopped or deleted
+	inUse, err := r.sessionsInUse(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if inUse[id] {
+			log.FromContext(ctx).V(1).Info("keeping OpenCode session used by another task", "session", id)
+			continue
+		}
+		if err := oc.DeleteSession(ctx, serverURL, directory, id); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("deleted OpenCode session", "session", id)
+	}
+	task.Status.SessionID = ""
+	return nil
+}
+
+// retryStoppedSessionCleanup retries deleting the OpenCode session of a stopped
+// Server-mode Task whose session could not be deleted when it was stopped, until
+// DefaultSessionCleanupTimeout after the stop. Returns a non-zero Result while retrying.
+func (r *TaskReconciler) retryStoppedSessionCleanup(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
+	if task.Status.SessionID == "" || task.Status.CompletionTime == nil ||
+		!meta.IsStatusConditionTrue(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeStopped) {
+		return ctrl.Result{}, nil
+	}
+	if time.Since(task.Status.CompletionTime.Time) > DefaultSessionCleanupTimeout {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.deleteServerSessions(ctx, task); err != nil {
+		log.FromContext(ctx).Info("retrying OpenCode session cleanup", "session", task.Status.SessionID, "error", err.Error())
+		return ctrl.Result{RequeueAfter: DefaultRetryBackoff}, nil
+	}
+	return ctrl.Result{}, r.Status().Update(ctx, task)
+}
+
+// sessionsInUse returns the session IDs recorded by the other Tasks in the Task's
+// namespace that are not being deleted or stopped.
+func (r *TaskReconciler) sessionsInUse(ctx context.Context, task *kubeopenv1alpha1.Task) (map[string]bool, error) {
+	var tasks kubeopenv1alpha1.TaskList
+	if err := r.List(ctx, &tasks, client.InNamespace(task.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Tasks: %w", err)
+	}
+	inUse := make(map[string]bool)
+	for i := range tasks.Items {
+		other := &tasks.Items[i]
+		if other.UID == task.UID || other.Status.SessionID == "" || !other.DeletionTimestamp.IsZero() ||
+			meta.IsStatusConditionTrue(other.Status.Conditions, kubeopenv1alpha1.ConditionTypeStopped) {
+			continue
+		}
+		inUse[other.Status.SessionID] = true
+	}
+	return inUse, nil
+}
+
+// sessionWorkspaceTask returns the name of the Task in whose workspace the session of
+// the Task was created. Follow-up Tasks (sessionRef) continue the session of the Task
+// they reference, so the chain is followed to its first Task, or to the first Task
+// that no longer exists.
+func (r *TaskReconciler) sessionWorkspaceTask(ctx context.Context, task *kubeopenv1alpha1.Task) (string, error) {
+	current := task
+	seen := map[string]bool{task.Name: true}
+	for current.Spec.SessionRef != nil && !seen[current.Spec.SessionRef.Name] {
+		name := current.Spec.SessionRef.Name
+		seen[name] = true
+		next := &kubeopenv1alpha1.Task{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: task.Namespace}, next); err != nil {
+			if errors.IsNotFound(err) {
+				return name, nil
+			}
+			return "", fmt.Errorf("failed to get Task %q: %w", name, err)
+		}
+		current = next
+	}
+	return current.Name, nil
+}
+
+// resolveSessionRef resolves the OpenCode session a follow-up Task continues (spec.sessionRef).
+// Returns the session ID and the server replica holding it (empty unless the referenced
+// Task was pinned to one), or a non-empty waitMessage if the Task must wait (the referenced
+// Task has not finished, or the server cannot be reached). An error means the sessionRef
+// cannot be continued.
+func (r *TaskReconciler) resolveSessionRef(ctx context.Context, task *kubeopenv1alpha1.Task, cfg agentConfig, agentName, agentNamespace string) (sessionID, serverPod, waitMessage string, err error) {
+	refName := task.Spec.SessionRef.Name
+	if refName == task.Name {
+		return "", "", "", fmt.Errorf("sessionRef cannot reference the Task itself")
+	}
+	if cfg.serverConfig == nil {
+		return "", "", "", fmt.Errorf("sessionRef requires a Server-mode Agent, agent %q runs in Pod mode", agentName)
+	}
+
+	ref := &kubeopenv1alpha1.Task{}
+	if err := r.Get(ctx, types.NamespacedName{Name: refName, Namespace: task.Namespace}, ref); err != nil {
+		if errors.IsNotFound(err) {
+			return "", "", "", fmt.Errorf("task %q referenced by sessionRef not found", refName)
+		}
+		return "", "", "", fmt.Errorf("failed to get Task %q referenced by sessionRef: %w", refName, err)
+	}
+
+	if ref.Status.AgentRef != nil && (ref.Status.AgentRef.Name != agentName || ref.Status.AgentRef.Namespace != agentNamespace) {
+		return "", "", "", fmt.Errorf("task %q referenced by sessionRef uses agent %s/%s, not %s/%s",
+			refName, ref.Status.AgentRef.Namespace, ref.Status.AgentRef.Name, agentNamespace, agentName)
+	}
+	if ref.Status.Phase != kubeopenv1alpha1.TaskPhaseCompleted && ref.Status.Phase != kubeopenv1alpha1.TaskPhaseFailed {
+		return "", "", fmt.Sprintf("Waiting for task %q to finish before continuing its session", refName), nil
+	}
+	if ref.Status.SessionID == "" {
+		return "", "", "", fmt.Errorf("task %q referenced by sessionRef has no OpenCode session", refName)
+	}
+	// The ID is passed on the attach Pod's command line
+	if !sessionIDPattern.MatchString(ref.Status.SessionID) {
+		return "", "", "", fmt.Errorf("task %q referenced by sessionRef has an invalid session ID %q", refName, ref.Status.SessionID)
+	}
+
+	// Make sure the session still exists on the server, or on the replica it lives on
+	port := GetServerPort(&kubeopenv1alpha1.Agent{Spec: kubeopenv1alpha1.AgentSpec{ServerConfig: cfg.serverConfig}})
+	serverURL := ServerURL(agentName, agentNamespace, port)
+	if ref.Status.ServerPod != "" {
+		replica := &corev1.Pod{}
+		err := r.Get(ctx, types.NamespacedName{Name: ref.Status.ServerPod, Namespace: agentNamespace}, replica)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", "", "", fmt.Errorf("failed to get server replica %q: %w", ref.Status.ServerPod, err)
+		}
+		if err != nil || !isServerReplicaAvailable(replica) {
+			return "", "", "", fmt.Errorf("server replica %q holding session %s of task %q is gone or shutting down",
+				ref.Status.ServerPod, ref.Status.SessionID, refName)
+		}
+		serverURL = ServerReplicaURL(replica.Status.PodIP, port)
+	}
+	// The session lives in the workspace of the Task that created it
+	sessionTask, err := r.sessionWorkspaceTask(ctx, ref)
+	if err != nil {
+		return "", "", "", err
+	}
+	directory := ServerTaskWorkspaceDir(cfg.workspaceDir, ref.Namespace, sessionTask)
+	session, err := r.openCodeClient().GetSession(ctx, serverURL, directory, ref.Status.SessionID)
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("unable to get OpenCode session", "server", serverURL, "error", err.Error())
+		return "", "", fmt.Sprintf("Waiting for the server of agent %q to verify session %s of task %q", agentName, ref.Status.SessionID, refName), nil
+	}
+	if session == nil {
+		return "", "", "", fmt.Errorf("session %s of task %q no longer exists on the server of agent %q", ref.Status.SessionID, refName, agentName)
+	}
+	return ref.Status.SessionID, ref.Status.ServerPod, "", nil
+}
+
+// handleWaitingForSession re-checks a follow-up Task queued until the Task referenced by
+// its sessionRef has finished. Once it has, or it is gone, the Task goes back through
+// initializeTask, which resolves the session and checks the Agent's capacity.
+func (r *TaskReconciler) handleWaitingForSession(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
+	ref := &kubeopenv1alpha1.Task{}
+	err := r.Get(ctx, types.NamespacedName{Name: task.Spec.SessionRef.Name, Namespace: task.Namespace}, ref)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil && ref.Status.Phase != kubeopenv1alpha1.TaskPhaseCompleted && ref.Status.Phase != kubeopenv1alpha1.TaskPhaseFailed {
+		return ctrl.Result{RequeueAfter: DefaultSessionLookupInterval}, nil
+	}
+
+	task.Status.Phase = ""
+	task.Status.QueuePosition = 0
+	meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+		Type:    kubeopenv1alpha1.ConditionTypeQueued,
+		Status:  metav1.ConditionFalse,
+		Reason:  kubeopenv1alpha1.ReasonSessionAvailable,
+		Message: fmt.Sprintf("Task %q has finished", task.Spec.SessionRef.Name),
+	})
+	if err := r.Status().Update(ctx, task); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// followUpTasksForTask maps Task events to the follow-up Tasks whose sessionRef names
+// the Task, so they stop waiting as soon as it finishes.
+func (r *TaskReconciler) followUpTasksForTask(ctx context.Context, obj client.Object) []ctrl.Request {
+	taskList := &kubeopenv1alpha1.TaskList{}
+	if err := r.List(ctx, taskList, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list follow-up Tasks", "task", obj.GetName())
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for i := range taskList.Items {
+		t := &taskList.Items[i]
+		if t.Spec.SessionRef == nil || t.Spec.SessionRef.Name != obj.GetName() || t.Status.Phase != kubeopenv1alpha1.TaskPhaseQueued {
+			continue
+		}
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: t.Name, Namespace: t.Namespace}})
+	}
+	return requests
+}
@@ -0,0 +1,115 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPOpenCodeClient(t *testing.T) {
+	const directory = "/workspace/.kubeopencode/tasks/default/my-task"
+	var deleted []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("directory"); got != directory {
+			t.Errorf("directory = %q, want %q", got, directory)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/session":
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"id": "ses_1", "directory": directory, "time": map[string]any{"created": 1}},
+				{"id": "ses_2", "parentID": "ses_1", "directory": directory, "time": map[string]any{"created": 2}},
+			})
+		case r.Method == http.MethodGet && r.URL.Path == "/session/status":
+			_ = json.NewEncoder(w).Encode(map[string]any{"ses_1": map[string]any{"type": "busy"}})
+		case r.Method == http.MethodGet && r.URL.Path == "/session/ses_1":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "ses_1", "directory": directory})
+		case r.Method == http.MethodGet:
+			http.NotFound(w, r)
+		case r.Method == http.MethodDelete && r.URL.Path == "/session/ses_1":
+			deleted = append(deleted, "ses_1")
+			_, _ = w.Write([]byte("true"))
+		case r.Method == http.MethodDelete:
+			http.NotFound(w, r)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := &httpOpenCodeClient{client: server.Client()}
+
+	sessions, err := client.ListSessions(context.Background(), server.URL, directory)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 2 || sessions[1].ParentID != "ses_1" {
+		t.Errorf("ListSessions() = %+v", sessions)
+	}
+
+	session, err := client.GetSession(context.Background(), server.URL, directory, "ses_1")
+	if err != nil || session == nil || session.ID != "ses_1" {
+		t.Errorf("GetSession() = %+v, %v", session, err)
+	}
+	session, err = client.GetSession(context.Background(), server.URL, directory, "ses_gone")
+	if err != nil || session != nil {
+		t.Errorf("GetSession() of missing session = %+v, %v, want nil, nil", session, err)
+	}
+
+	status, err := client.SessionStatus(context.Background(), server.URL, directory)
+	if err != nil || status["ses_1"].Type != "busy" {
+		t.Errorf("SessionStatus() = %+v, %v", status, err)
+	}
+
+	if err := client.DeleteSession(context.Background(), server.URL, directory, "ses_1"); err != nil {
+		t.Errorf("DeleteSession() error = %v", err)
+	}
+	// Deleting a session that no longer exists is not an error
+	if err := client.DeleteSession(context.Background(), server.URL, directory, "ses_gone"); err != nil {
+		t.Errorf("DeleteSession() of missing session error = %v", err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("deleted = %v, want [ses_1]", deleted)
+	}
+}
+
+func TestHTTPOpenCodeClient_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &httpOpenCodeClient{client: server.Client()}
+	if _, err := client.ListSessions(context.Background(), server.URL, "/workspace"); err == nil {
+		t.Error("ListSessions() error = nil, want error")
+	}
+	if err := client.DeleteSession(context.Background(), server.URL, "/workspace", "ses_1"); err == nil {
+		t.Error("DeleteSession() error = nil, want error")
+	}
+}
+
+func TestTaskSessions(t *testing.T) {
+	const directory = "/workspace/.kubeopencode/tasks/default/my-task"
+	session := func(id, parentID, dir string, created int64) OpenCodeSession {
+		s := OpenCodeSession{ID: id, ParentID: parentID, Directory: dir}
+		s.Time.Created = created
+		return s
+	}
+
+	got := taskSessions([]OpenCodeSession{
+		session("old", "", directory, 1),
+		session("child", "new", directory, 3),
+		session("other-task", "", "/workspace/.kubeopencode/tasks/default/other", 4),
+		session("new", "", directory, 2),
+	}, directory)
+
+	if len(got) != 2 || got[0].ID != "new" || got[1].ID != "old" {
+		t.Errorf("taskSessions() = %+v, want [new old]", got)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+const (
+	// sessionStatsTimeout bounds collecting the session statistics of a server
+	sessionStatsTimeout = 10 * time.Second
+
+	// sessionStatsConcurrency is how many directories are polled for session
+	// statistics at the same time
+	sessionStatsConcurrency = 8
+)
+
+// serverSessionStats are the session statistics of the server of a Server-mode Agent.
+type serverSessionStats struct {
+	active int32
+	idle   int32
+	// oldestActive is the creation time of the oldest active session (zero if none)
+	oldestActive time.Time
+	// lastActivity is the last time a session was updated (zero if unknown)
+	lastActivity time.Time
+}
+
+// add counts root sessions with their status. Sessions without a status, or with
+// status "idle", are idle.
+func (s *serverSessionStats) add(sessions []OpenCodeSession, status map[string]OpenCodeSessionStatus) {
+	for _, session := range sessions {
+		if session.Time.Updated > 0 {
+			if updated := time.UnixMilli(session.Time.Updated); updated.After(s.lastActivity) {
+				s.lastActivity = updated
+			}
+		}
+		if st, ok := status[session.ID]; !ok || st.Type == "idle" {
+			s.idle++
+			continue
+		}
+		s.active++
+		if created := time.UnixMilli(session.Time.Created); s.oldestActive.IsZero() || created.Before(s.oldestActive) {
+			s.oldestActive = created
+		}
+	}
+}
+
+// apply records the statistics in the server status. The last activity time is
+// kept when no session reports one.
+func (s *serverSessionStats) apply(status *kubeopenv1alpha1.ServerStatus) {
+	status.ActiveSessions = s.active
+	status.IdleSessions = s.idle
+	status.OldestActiveSessionTime = nil
+	if !s.oldestActive.IsZero() {
+		status.OldestActiveSessionTime = &metav1.Time{Time: s.oldestActive}
+	}
+	if !s.lastActivity.IsZero() {
+		status.LastActivityTime = &metav1.Time{Time: s.lastActivity}
+	}
+}
+
+// openCodeClient returns the configured OpenCode client, or the HTTP client.
+func (r *AgentReconciler) openCodeClient() OpenCodeClient {
+	if r.OpenCode != nil {
+		return r.OpenCode
+	}
+	return &httpOpenCodeClient{client: http.DefaultClient}
+}
+
+// serverSessionTargets returns the directories to collect session statistics from,
+// keyed by the URL of the server (or replica) that holds their sessions: the Task
+// workspaces of the Agent's Tasks that are running or still have a session, and the
+// server workspace itself for sessions started on the server directly.
+func (r *AgentReconciler) serverSessionTargets(ctx context.Context, agent *kubeopenv1alpha1.Agent) (map[string][]string, error) {
+	port := GetServerPort(agent)
+	servers := map[string]string{"": ServerURL(agent.Name, agent.Namespace, port)}
+	if serverReplicaCount(agent.Spec.ServerConfig) > 1 {
+		// Replicas do not share sessions, so each one is asked. Terminating replicas
+		// are included, as they still run the sessions they are draining.
+		var pods corev1.PodList
+		if err := r.List(ctx, &pods, client.InNamespace(agent.Namespace), serverPodLabels(agent.Name)); err != nil {
+			return nil, fmt.Errorf("failed to list server Pods: %w", err)
+		}
+		servers = make(map[string]string)
+		for i := range pods.Items {
+			if pod := &pods.Items[i]; pod.Status.PodIP != "" && pod.Status.Phase == corev1.PodRunning {
+				servers[pod.Name] = ServerReplicaURL(pod.Status.PodIP, port)
+			}
+		}
+	}
+
+	targets := make(map[string][]string)
+	for _, serverURL := range servers {
+		targets[serverURL] = append(targets[serverURL], agent.Spec.WorkspaceDir)
+	}
+
+	var tasks kubeopenv1alpha1.TaskList
+	if err := r.List(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to list Tasks: %w", err)
+	}
+	for i := range tasks.Items {
+		task := &tasks.Items[i]
+		ref := task.Status.AgentRef
+		if ref == nil || ref.Name != agent.Name || ref.Namespace != agent.Namespace || task.DeletionTimestamp != nil {
+			continue
+		}
+		// Sessions of finished Tasks are deleted with the Task, or once it is stopped
+		if task.Status.SessionID == "" && task.Status.Phase != kubeopenv1alpha1.TaskPhaseRunning {
+			continue
+		}
+		directory := ServerTaskWorkspaceDir(agent.Spec.WorkspaceDir, task.Namespace, task.Name)
+		if serverURL, ok := servers[task.Status.ServerPod]; ok && task.Status.ServerPod != "" {
+			targets[serverURL] = append(targets[serverURL], directory)
+			continue
+		}
+		if task.Status.ServerPod != "" {
+			// The replica is gone, and so are its sessions
+			continue
+		}
+		for _, serverURL := range servers {
+			targets[serverURL] = append(targets[serverURL], directory)
+		}
+	}
+	return targets, nil
+}
+
+// collectSessionStats polls the server of a Server-mode Agent for its sessions and
+// their status (GET /session and GET /session/status), within sessionStatsTimeout.
+func (r *AgentReconciler) collectSessionStats(ctx context.Context, agent *kubeopenv1alpha1.Agent) (*serverSessionStats, error) {
+	targets, err := r.serverSessionTargets(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sessionStatsTimeout)
+	defer cancel()
+	return pollSessionStats(ctx, r.openCodeClient(), targets)
+}
+
+// pollSessionStats collects the session statistics of the directories in targets,
+// keyed by server URL. The directories are polled concurrently; the first error
+// stops the polling.
+func pollSessionStats(ctx context.Context, oc OpenCodeClient, targets map[string][]string) (*serverSessionStats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stats := &serverSessionStats{}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	limit := make(chan struct{}, sessionStatsConcurrency)
+	for serverURL, directories := range targets {
+		for _, directory := range directories {
+			limit <- struct{}{}
+			wg.Go(func() {
+				defer func() { <-limit }()
+				var roots []OpenCodeSession
+				var status map[string]OpenCodeSessionStatus
+				// Skip the remaining directories once polling stopped or timed out
+				err := ctx.Err()
+				if err == nil {
+					roots, status, err = directorySessionStats(ctx, oc, serverURL, directory)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						// The statistics are incomplete, stop polling
+						cancel()
+					}
+					return
+				}
+				stats.add(roots, status)
+			})
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return stats, nil
+}
+
+// directorySessionStats returns the root sessions in directory on the server at
+// serverURL and their status.
+func directorySessionStats(ctx context.Context, oc OpenCodeClient, serverURL, directory string) ([]OpenCodeSession, map[string]OpenCodeSessionStatus, error) {
+	sessions, err := oc.ListSessions(ctx, serverURL, directory)
+	if err != nil {
+		return nil, nil, err
+	}
+	roots := taskSessions(sessions, directory)
+	if len(roots) == 0 {
+		return nil, nil, nil
+	}
+	status, err := oc.SessionStatus(ctx, serverURL, directory)
+	if err != nil {
+		return nil, nil, err
+	}
+	return roots, status, nil
+}
+
+// updateSessionStats records the session statistics of a Server-mode Agent in its
+// status and in the session metrics. A server without ready replicas has no
+// sessions. If the server cannot be polled, the previous statistics are kept.
+func (r *AgentReconciler) updateSessionStats(ctx context.Context, agent *kubeopenv1alpha1.Agent) {
+	status := agent.Status.ServerStatus
+	stats := &serverSessionStats{}
+	if status.ReadyReplicas > 0 {
+		var err error
+		if stats, err = r.collectSessionStats(ctx, agent); err != nil {
+			log.FromContext(ctx).V(1).Info("unable to collect OpenCode session statistics", "error", err.Error())
+			recordServerSessionMetrics(agent.Namespace, agent.Name, status)
+			return
+		}
+	}
+	stats.apply(status)
+	recordServerSessionMetrics(agent.Namespace, agent.Name, status)
+}
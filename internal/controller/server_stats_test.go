@@ -0,0 +1,150 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestServerSessionStats(t *testing.T) {
+	session := func(id string, created, updated int64) OpenCodeSession {
+		s := OpenCodeSession{ID: id}
+		s.Time.Created = created
+		s.Time.Updated = updated
+		return s
+	}
+
+	stats := &serverSessionStats{}
+	stats.add([]OpenCodeSession{
+		session("busy-new", 3000, 9000),
+		session("idle", 1000, 4000),
+	}, map[string]OpenCodeSessionStatus{
+		"busy-new": {Type: "busy"},
+		"idle":     {Type: "idle"},
+	})
+	stats.add([]OpenCodeSession{
+		session("retrying-old", 2000, 5000),
+		session("no-status", 500, 0),
+	}, map[string]OpenCodeSessionStatus{
+		"retrying-old": {Type: "retry"},
+	})
+
+	if stats.active != 2 || stats.idle != 2 {
+		t.Errorf("active, idle = %d, %d; want 2, 2", stats.active, stats.idle)
+	}
+	if !stats.oldestActive.Equal(time.UnixMilli(2000)) {
+		t.Errorf("oldestActive = %v, want %v", stats.oldestActive, time.UnixMilli(2000))
+	}
+	if !stats.lastActivity.Equal(time.UnixMilli(9000)) {
+		t.Errorf("lastActivity = %v, want %v", stats.lastActivity, time.UnixMilli(9000))
+	}
+
+	status := &kubeopenv1alpha1.ServerStatus{}
+	stats.apply(status)
+	if status.ActiveSessions != 2 || status.IdleSessions != 2 || status.OldestActiveSessionTime == nil || status.LastActivityTime == nil {
+		t.Errorf("apply() = %+v", status)
+	}
+
+	// Without sessions the counts are cleared, but the last activity is kept
+	lastActivity := status.LastActivityTime
+	(&serverSessionStats{}).apply(status)
+	if status.ActiveSessions != 0 || status.IdleSessions != 0 || status.OldestActiveSessionTime != nil {
+		t.Errorf("apply() of empty stats = %+v", status)
+	}
+	if !status.LastActivityTime.Equal(lastActivity) {
+		t.Errorf("LastActivityTime = %v, want %v", status.LastActivityTime, lastActivity)
+	}
+}
+
+// slowOpenCodeClient serves one idle session per directory after a delay, and fails
+// for the directory failDir.
+type slowOpenCodeClient struct {
+	delay   time.Duration
+	failDir string
+}
+
+func (c *slowOpenCodeClient) wait(ctx context.Context, directory string) error {
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if directory == c.failDir {
+		return errors.New("server unavailable")
+	}
+	return nil
+}
+
+func (c *slowOpenCodeClient) ListSessions(ctx context.Context, _, directory string) ([]OpenCodeSession, error) {
+	if err := c.wait(ctx, directory); err != nil {
+		return nil, err
+	}
+	return []OpenCodeSession{{ID: "ses_" + directory, Directory: directory}}, nil
+}
+
+func (c *slowOpenCodeClient) GetSession(context.Context, string, string, string) (*OpenCodeSession, error) {
+	return nil, nil
+}
+
+func (c *slowOpenCodeClient) DeleteSession(context.Context, string, string, string) error {
+	return nil
+}
+
+func (c *slowOpenCodeClient) SessionStatus(ctx context.Context, _, directory string) (map[string]OpenCodeSessionStatus, error) {
+	if err := c.wait(ctx, directory); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func TestPollSessionStats(t *testing.T) {
+	targets := map[string][]string{}
+	for i := range 16 {
+		serverURL := fmt.Sprintf("http://10.0.0.%d:4096", i%2)
+		targets[serverURL] = append(targets[serverURL], fmt.Sprintf("/workspace/task-%d", i))
+	}
+	const delay = 50 * time.Millisecond
+
+	t.Run("polls concurrently", func(t *testing.T) {
+		start := time.Now()
+		stats, err := pollSessionStats(context.Background(), &slowOpenCodeClient{delay: delay}, targets)
+		if err != nil {
+			t.Fatalf("pollSessionStats() error = %v", err)
+		}
+		if stats.idle != 16 || stats.active != 0 {
+			t.Errorf("pollSessionStats() = %d idle, %d active; want 16 idle", stats.idle, stats.active)
+		}
+		// Polled one after the other, the 32 requests would take 1.6s
+		if elapsed := time.Since(start); elapsed > 16*delay {
+			t.Errorf("pollSessionStats() took %v", elapsed)
+		}
+	})
+
+	t.Run("stops at the first error", func(t *testing.T) {
+		_, err := pollSessionStats(context.Background(), &slowOpenCodeClient{delay: delay, failDir: "/workspace/task-3"}, targets)
+		if err == nil {
+			t.Error("pollSessionStats() error = nil, want the server error")
+		}
+	})
+
+	t.Run("stops at the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), delay)
+		defer cancel()
+		start := time.Now()
+		_, err := pollSessionStats(ctx, &slowOpenCodeClient{delay: time.Hour}, targets)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("pollSessionStats() error = %v, want the deadline", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("pollSessionStats() took %v after the deadline", elapsed)
+		}
+	})
+}
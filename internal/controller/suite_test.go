@@ -18,6 +18,7 @@ package controller
 import (
 	"context"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -45,6 +46,9 @@ var (
 	ctx       context.Context
 	cancel    context.CancelFunc
 	scheme    *runtime.Scheme
+
+	// fakeOpenCode stands in for the OpenCode servers of Server-mode Agents
+	fakeOpenCode = newFakeOpenCodeClient()
 )
 
 const (
@@ -99,14 +103,17 @@ var _ = BeforeSuite(func() {
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&TaskReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:             k8sManager.GetClient(),
+		Scheme:             k8sManager.GetScheme(),
+		PodLostGracePeriod: time.Second,
+		OpenCode:           fakeOpenCode,
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&AgentReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		OpenCode: fakeOpenCode,
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
@@ -139,6 +146,92 @@ var _ = AfterSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 })
 
+// fakeOpenCodeClient is an in-memory OpenCodeClient keyed by server URL.
+type fakeOpenCodeClient struct {
+	mu       sync.Mutex
+	sessions map[string][]OpenCodeSession
+	// busy holds the IDs of sessions working on a prompt
+	busy map[string]bool
+}
+
+func newFakeOpenCodeClient() *fakeOpenCodeClient {
+	return &fakeOpenCodeClient{sessions: make(map[string][]OpenCodeSession), busy: make(map[string]bool)}
+}
+
+// setBusy marks a session as working on a prompt, or idle.
+func (f *fakeOpenCodeClient) setBusy(id string, busy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.busy[id] = busy
+}
+
+// addSession adds a root session in directory to the server at serverURL.
+func (f *fakeOpenCodeClient) addSession(serverURL, directory, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions[serverURL] = append(f.sessions[serverURL], OpenCodeSession{ID: id, Directory: directory})
+}
+
+// hasSession reports whether the server at serverURL has the session.
+func (f *fakeOpenCodeClient) hasSession(serverURL, id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.sessions[serverURL] {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeOpenCodeClient) ListSessions(_ context.Context, serverURL, directory string) ([]OpenCodeSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []OpenCodeSession
+	for _, s := range f.sessions[serverURL] {
+		if s.Directory == directory {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeOpenCodeClient) GetSession(_ context.Context, serverURL, directory string, sessionID string) (*OpenCodeSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.sessions[serverURL] {
+		if s.ID == sessionID && s.Directory == directory {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeOpenCodeClient) DeleteSession(_ context.Context, serverURL, _ string, sessionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sessions := f.sessions[serverURL]
+	for i, s := range sessions {
+		if s.ID == sessionID {
+			f.sessions[serverURL] = append(sessions[:i:i], sessions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeOpenCodeClient) SessionStatus(_ context.Context, serverURL, directory string) (map[string]OpenCodeSessionStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := make(map[string]OpenCodeSessionStatus)
+	for _, s := range f.sessions[serverURL] {
+		if s.Directory == directory && f.busy[s.ID] {
+			status[s.ID] = OpenCodeSessionStatus{Type: "busy"}
+		}
+	}
+	return status, nil
+}
+
 // stringPtr returns a pointer to the given string value
 func stringPtr(s string) *string {
 	return &s
@@ -0,0 +1,169 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+// initContainerResult is the termination message git-init, git-push and url-fetch
+// write when they succeed.
+type initContainerResult struct {
+	Commit     string `json:"commit"`
+	BaseCommit string `json:"baseCommit"`
+	Branch     string `json:"branch"`
+	Digest     string `json:"digest"`
+	// Cache is "hit" or "miss" when git-init cloned using the Git mirror cache
+	Cache           string `json:"cache"`
+	CacheBytesSaved int64  `json:"cacheBytesSaved"`
+}
+
+// contentDigest returns the SHA-256 digest of content as "sha256:<hex>".
+func contentDigest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// taskContextRecords returns the status records of the Agent and Task contexts, in
+// the order processAllContexts resolves them.
+func (r *TaskReconciler) taskContextRecords(ctx context.Context, task *kubeopenv1alpha1.Task, cfg agentConfig, agentNamespace string) []kubeopenv1alpha1.ResolvedContext {
+	runtimePrompt := runtimeSystemPrompt(cfg)
+	records := contextRecords(ctx, r, cfg.contexts, agentNamespace, runtimePrompt)
+	return append(records, contextRecords(ctx, r, task.Spec.Contexts, task.Namespace, runtimePrompt)...)
+}
+
+// contextRecords returns the status records of context items resolved from namespace.
+// Digests of Text, ConfigMap and Runtime contexts are computed from their content;
+// Git commits and URL digests are reported by the init containers and filled in by
+// recordContextResults. Items resolveContextContent skips are skipped as well, so
+// the n-th Git (URL) record belongs to init container git-init-n (url-fetch-n).
+// runtimePrompt is the Runtime system prompt delivered to the agent.
+func contextRecords(ctx context.Context, c client.Reader, items []kubeopenv1alpha1.ContextItem, namespace, runtimePrompt string) []kubeopenv1alpha1.ResolvedContext {
+	var records []kubeopenv1alpha1.ResolvedContext
+	for _, item := range items {
+		record := kubeopenv1alpha1.ResolvedContext{Type: item.Type, Name: item.Name}
+		var content string
+		switch item.Type {
+		case kubeopenv1alpha1.ContextTypeText:
+			content = item.Text
+		case kubeopenv1alpha1.ContextTypeConfigMap:
+			if item.ConfigMap == nil {
+				continue
+			}
+			record.Source = namespace + "/" + item.ConfigMap.Name
+			// The contexts were resolved just before, so errors are not expected here;
+			// a ConfigMap that cannot be read gets no digest
+			if item.ConfigMap.Key != "" {
+				content, _ = getConfigMapKey(ctx, c, namespace, item.ConfigMap.Name, item.ConfigMap.Key, item.ConfigMap.Optional)
+			} else {
+				content, _ = getConfigMapAllKeys(ctx, c, namespace, item.ConfigMap.Name, item.ConfigMap.Optional)
+			}
+		case kubeopenv1alpha1.ContextTypeGit:
+			if item.Git == nil {
+				continue
+			}
+			record.Source = item.Git.Repository
+			record.Ref = item.Git.Ref
+			if record.Ref == "" {
+				record.Ref = DefaultGitRef
+			}
+		case kubeopenv1alpha1.ContextTypeRuntime:
+			content = runtimePrompt
+		case kubeopenv1alpha1.ContextTypeURL:
+			if item.URL == nil {
+				continue
+			}
+			record.Source = item.URL.Source
+		}
+		if content != "" {
+			record.Digest = contentDigest(content)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// recordContextResults fills in the Git commits and URL digests reported by the
+// Pod's finished git-init and url-fetch init containers, and the pushes reported by
+// its git-push sidecars. Returns whether any record changed.
+func recordContextResults(task *kubeopenv1alpha1.Task, pod *corev1.Pod) bool {
+	changed := false
+	for _, cs := range pod.Status.InitContainerStatuses {
+		terminated := cs.State.Terminated
+		if terminated == nil || terminated.ExitCode != 0 || terminated.Message == "" {
+			continue
+		}
+
+		var ctxType kubeopenv1alpha1.ContextType
+		var suffix string
+		pushed := false
+		switch {
+		case strings.HasPrefix(cs.Name, "git-init-"):
+			ctxType, suffix = kubeopenv1alpha1.ContextTypeGit, strings.TrimPrefix(cs.Name, "git-init-")
+		case strings.HasPrefix(cs.Name, "git-push-"):
+			ctxType, suffix = kubeopenv1alpha1.ContextTypeGit, strings.TrimPrefix(cs.Name, "git-push-")
+			pushed = true
+		case strings.HasPrefix(cs.Name, "url-fetch-"):
+			ctxType, suffix = kubeopenv1alpha1.ContextTypeURL, strings.TrimPrefix(cs.Name, "url-fetch-")
+		default:
+			continue
+		}
+		index, err := strconv.Atoi(suffix)
+		if err != nil {
+			continue
+		}
+		record := nthContextRecord(task.Status.Contexts, ctxType, index)
+		if record == nil {
+			continue
+		}
+
+		var result initContainerResult
+		if err := json.Unmarshal([]byte(terminated.Message), &result); err != nil {
+			continue
+		}
+		if pushed {
+			if record.PushedBranch != result.Branch || record.PushedCommit != result.Commit {
+				record.PushedBranch, record.PushedCommit = result.Branch, result.Commit
+				changed = true
+			}
+			continue
+		}
+		if ctxType == kubeopenv1alpha1.ContextTypeGit && (record.Commit != result.Commit || record.BaseCommit != result.BaseCommit) {
+			// Count each clone once, when its commit is first recorded
+			if record.Commit == "" {
+				recordGitCacheMetrics(result.Cache, result.CacheBytesSaved)
+			}
+			record.Commit, record.BaseCommit = result.Commit, result.BaseCommit
+			changed = true
+		}
+		if ctxType == kubeopenv1alpha1.ContextTypeURL && record.Digest != result.Digest {
+			record.Digest = result.Digest
+			changed = true
+		}
+	}
+	return changed
+}
+
+// nthContextRecord returns the index-th record of the given type, or nil.
+func nthContextRecord(records []kubeopenv1alpha1.ResolvedContext, ctxType kubeopenv1alpha1.ContextType, index int) *kubeopenv1alpha1.ResolvedContext {
+	for i := range records {
+		if records[i].Type != ctxType {
+			continue
+		}
+		if index == 0 {
+			return &records[i]
+		}
+		index--
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestContentDigest(t *testing.T) {
+	// sha256 of "hello"
+	want := "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if got := contentDigest("hello"); got != want {
+		t.Errorf("contentDigest() = %q, want %q", got, want)
+	}
+}
+
+func TestContextRecords(t *testing.T) {
+	items := []kubeopenv1alpha1.ContextItem{
+		{Name: "guide", Type: kubeopenv1alpha1.ContextTypeText, Text: "hello"},
+		{Type: kubeopenv1alpha1.ContextTypeGit, Git: &kubeopenv1alpha1.GitContext{Repository: "https://github.com/org/repo"}},
+		// Skipped like in resolveContextContent, so Git records keep their init container index
+		{Type: kubeopenv1alpha1.ContextTypeGit},
+		{Type: kubeopenv1alpha1.ContextTypeGit, Git: &kubeopenv1alpha1.GitContext{Repository: "https://github.com/org/other", Ref: "refs/pull/1/head"}},
+		{Type: kubeopenv1alpha1.ContextTypeURL, URL: &kubeopenv1alpha1.URLContext{Source: "https://example.com/spec.yaml"}},
+		{Type: kubeopenv1alpha1.ContextTypeRuntime},
+	}
+
+	records := contextRecords(context.Background(), nil, items, "default", RuntimeSystemPrompt)
+	if len(records) != 5 {
+		t.Fatalf("contextRecords() returned %d records, want 5: %+v", len(records), records)
+	}
+	if records[0].Name != "guide" || records[0].Digest != contentDigest("hello") {
+		t.Errorf("Text record = %+v", records[0])
+	}
+	if records[1].Source != "https://github.com/org/repo" || records[1].Ref != DefaultGitRef || records[1].Digest != "" {
+		t.Errorf("Git record = %+v", records[1])
+	}
+	if records[2].Source != "https://github.com/org/other" || records[2].Ref != "refs/pull/1/head" {
+		t.Errorf("second Git record = %+v", records[2])
+	}
+	if records[3].Source != "https://example.com/spec.yaml" || records[3].Digest != "" {
+		t.Errorf("URL record = %+v", records[3])
+	}
+	if records[4].Digest != contentDigest(RuntimeSystemPrompt) {
+		t.Errorf("Runtime record = %+v", records[4])
+	}
+}
+
+func TestRecordContextResults(t *testing.T) {
+	task := &kubeopenv1alpha1.Task{
+		Status: kubeopenv1alpha1.TaskExecutionStatus{
+			Contexts: []kubeopenv1alpha1.ResolvedContext{
+				{Type: kubeopenv1alpha1.ContextTypeGit, Source: "https://github.com/org/repo"},
+				{Type: kubeopenv1alpha1.ContextTypeURL, Source: "https://example.com/spec.yaml"},
+				{Type: kubeopenv1alpha1.ContextTypeGit, Source: "https://github.com/org/other"},
+			},
+		},
+	}
+	terminated := func(name string, exitCode int32, message string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name:  name,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Message: message}},
+		}
+	}
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				terminated("context-init", 0, ""),
+				terminated("git-init-0", 0, `{"commit":"aaa"}`),
+				terminated("git-init-1", 0, `{"commit":"bbb","baseCommit":"ccc"}`),
+				terminated("url-fetch-0", 0, `{"digest":"sha256:ddd"}`),
+			},
+		},
+	}
+
+	if !recordContextResults(task, pod) {
+		t.Fatal("recordContextResults() = false, want true")
+	}
+	got := task.Status.Contexts
+	if got[0].Commit != "aaa" || got[2].Commit != "bbb" || got[2].BaseCommit != "ccc" || got[1].Digest != "sha256:ddd" {
+		t.Errorf("Contexts = %+v", got)
+	}
+	if recordContextResults(task, pod) {
+		t.Error("recordContextResults() = true for unchanged results, want false")
+	}
+
+	// git-push sidecars report the pushed branch and commit of the Git record
+	pushed := &corev1.Pod{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+		terminated("git-push-1", 0, `{"branch":"kubeopencode/my-task","commit":"eee"}`),
+	}}}
+	if !recordContextResults(task, pushed) {
+		t.Fatal("recordContextResults() = false for a push, want true")
+	}
+	if got := task.Status.Contexts[2]; got.PushedBranch != "kubeopencode/my-task" || got.PushedCommit != "eee" || got.Commit != "bbb" {
+		t.Errorf("pushed Git record = %+v", got)
+	}
+
+	// A failed init container reports an error message, not a result
+	failed := &corev1.Pod{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+		terminated("url-fetch-0", 1, "failed to fetch https://example.com/spec.yaml: HTTP 404"),
+	}}}
+	if recordContextResults(task, failed) || task.Status.Contexts[1].Digest != "sha256:ddd" {
+		t.Errorf("recordContextResults() changed records for a failed init container: %+v", task.Status.Contexts)
+	}
+}
+
+func TestValidateGitCheckoutOptions(t *testing.T) {
+	valid := []kubeopenv1alpha1.GitContext{
+		{},
+		{Submodules: kubeopenv1alpha1.GitSubmodulesRecursive},
+		{LFS: &kubeopenv1alpha1.GitLFS{Include: []string{"assets/**", "*.bin"}}},
+		{SparseCheckout: []string{"services/api", "libs/common/"}},
+		{SparseCheckout: []string{"services/api"}, Path: "services/api/.claude/"},
+		{SparseCheckout: []string{"services/api"}, Path: "services/api"},
+		// Files at the root are always checked out
+		{SparseCheckout: []string{"services/api"}, Path: "AGENTS.md"},
+	}
+	for _, git := range valid {
+		if err := validateGitCheckoutOptions(&git); err != nil {
+			t.Errorf("validateGitCheckoutOptions(%+v) error = %v", git, err)
+		}
+	}
+
+	invalid := []kubeopenv1alpha1.GitContext{
+		{Submodules: "all"},
+		{LFS: &kubeopenv1alpha1.GitLFS{Include: []string{""}}},
+		{LFS: &kubeopenv1alpha1.GitLFS{Include: []string{"a,b"}}},
+		{SparseCheckout: []string{""}},
+		{SparseCheckout: []string{"."}},
+		{SparseCheckout: []string{"/services/api"}},
+		{SparseCheckout: []string{"../other"}},
+		{SparseCheckout: []string{"services/*"}},
+		{SparseCheckout: []string{"!services/api"}},
+		{SparseCheckout: []string{"services/api"}, Path: "services/web/.claude"},
+		{SparseCheckout: []string{"services/api"}, Path: "services/api-v2/docs"},
+	}
+	for _, git := range invalid {
+		if err := validateGitCheckoutOptions(&git); err == nil {
+			t.Errorf("validateGitCheckoutOptions(%+v) error = nil, want error", git)
+		}
+	}
+}
+
+func TestValidateServerTaskMounts(t *testing.T) {
+	const workspace = "/workspace/.kubeopencode/tasks/default/task"
+	tests := []struct {
+		name      string
+		resolved  []resolvedContext
+		gitMounts []gitMount
+		urlMounts []urlMount
+		wantErr   bool
+	}{
+		{
+			name:      "inside the Task workspace",
+			resolved:  []resolvedContext{{mountPath: workspace + "/guide.md"}, {content: "appended"}},
+			gitMounts: []gitMount{{mountPath: workspace + "/source"}},
+			urlMounts: []urlMount{{targetPath: workspace + "/spec.yaml"}, {targetPath: "/tmp/url-0", appendToContext: true}},
+		},
+		{name: "absolute file path", resolved: []resolvedContext{{mountPath: "/etc/guide.md"}}, wantErr: true},
+		{name: "Git context outside the workspace", gitMounts: []gitMount{{mountPath: "/workspace/source"}}, wantErr: true},
+		{name: "Git context at the workspace root", gitMounts: []gitMount{{mountPath: workspace}}, wantErr: true},
+		{name: "URL context outside the workspace", urlMounts: []urlMount{{targetPath: "/data/spec.yaml"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateServerTaskMounts(workspace, tt.resolved, nil, tt.gitMounts, tt.urlMounts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateServerTaskMounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerRuntimePrompt(t *testing.T) {
+	if strings.Contains(ServerRuntimeSystemPrompt, "TASK_RESULT_FILE") {
+		t.Error("ServerRuntimeSystemPrompt asks for a result file that Server-mode Tasks cannot report")
+	}
+	if !strings.Contains(RuntimeSystemPrompt, "TASK_RESULT_FILE") {
+		t.Error("RuntimeSystemPrompt does not ask for a result file")
+	}
+
+	resolved := []resolvedContext{
+		{ctxType: string(kubeopenv1alpha1.ContextTypeText), content: "hello"},
+		{ctxType: string(kubeopenv1alpha1.ContextTypeRuntime), content: RuntimeSystemPrompt},
+	}
+	useServerRuntimePrompt(resolved)
+	if resolved[0].content != "hello" || resolved[1].content != ServerRuntimeSystemPrompt {
+		t.Errorf("useServerRuntimePrompt() = %+v", resolved)
+	}
+
+	items := []kubeopenv1alpha1.ContextItem{{Type: kubeopenv1alpha1.ContextTypeRuntime}}
+	cfg := agentConfig{serverConfig: &kubeopenv1alpha1.ServerConfig{}}
+	records := contextRecords(context.Background(), nil, items, "default", runtimeSystemPrompt(cfg))
+	if len(records) != 1 || records[0].Digest != contentDigest(ServerRuntimeSystemPrompt) {
+		t.Errorf("Server-mode Runtime record = %+v, want the digest of ServerRuntimeSystemPrompt", records)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -23,6 +25,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
 )
@@ -47,12 +50,43 @@ const (
 	// AgentLabelKey is the label key used to identify which Agent a Task uses
 	AgentLabelKey = "kubeopencode.io/agent"
 
-	// DefaultQueuedRequeueDelay is the default delay for requeuing queued Tasks
+	// DefaultQueuedRequeueDelay is the default delay for requeuing queued Tasks.
+	// Queued Tasks are woken as soon as their Agent frees a slot; the periodic
+	// requeue refreshes queue positions and is a safety net for missed events.
 	DefaultQueuedRequeueDelay = 10 * time.Second
 
 	// DefaultQuotaRequeueDelay is the minimum delay for requeuing quota-blocked Tasks
 	DefaultQuotaRequeueDelay = 30 * time.Second
 
+	// PodReasonDeadlineExceeded is the Pod status reason set by the kubelet
+	// when a Pod exceeds its activeDeadlineSeconds
+	PodReasonDeadlineExceeded = "DeadlineExceeded"
+
+	// PodReasonEvicted is the Pod status reason set when a Pod is evicted from its node
+	PodReasonEvicted = "Evicted"
+
+	// DefaultRetryBackoff is the default delay before the first retry of a failed attempt
+	DefaultRetryBackoff = 10 * time.Second
+
+	// DefaultMaxRetryBackoff caps the exponential backoff between retries
+	DefaultMaxRetryBackoff = 300 * time.Second
+
+	// DefaultPodLostGracePeriod is how long after an attempt starts a missing Pod
+	// is tolerated before the attempt fails with reason PodLost. It covers the window
+	// in which a newly created Pod is not yet visible in the informer cache.
+	DefaultPodLostGracePeriod = 30 * time.Second
+
+	// DefaultPodPendingThreshold is how long a Pod may stay Pending before the Task
+	// is flagged with a Stalled condition (reason PodPending)
+	DefaultPodPendingThreshold = 5 * time.Minute
+
+	// serverWorkspaceCleanupTimeout bounds the request removing a Task's workspace
+	// from a Server-mode Agent's server on Task deletion
+	serverWorkspaceCleanupTimeout = 5 * time.Second
+
+	// ContainerReasonOOMKilled is the termination reason of a container killed for exceeding its memory limit
+	ContainerReasonOOMKilled = "OOMKilled"
+
 	// AnnotationStop is the annotation key for user-initiated task stop
 	AnnotationStop = "kubeopencode.io/stop"
 
@@ -66,7 +100,14 @@ const (
 
 	// RuntimeSystemPrompt is the system prompt injected when Runtime context is enabled.
 	// It provides KubeOpenCode platform awareness to the agent.
-	RuntimeSystemPrompt = `## KubeOpenCode Runtime Context
+	RuntimeSystemPrompt = runtimePromptHeader + runtimePromptResultEnv + runtimePromptInfo + runtimePromptResult + runtimePromptConcepts
+
+	// ServerRuntimeSystemPrompt is the Runtime system prompt of Server-mode Tasks. The
+	// session runs on the server, where the result file cannot be collected, so it
+	// does not ask the agent to report results.
+	ServerRuntimeSystemPrompt = runtimePromptHeader + runtimePromptInfo + runtimePromptConcepts
+
+	runtimePromptHeader = `## KubeOpenCode Runtime Context
 
 You are running as an AI agent inside a Kubernetes Pod, managed by KubeOpenCode.
 
@@ -74,7 +115,12 @@ You are running as an AI agent inside a Kubernetes Pod, managed by KubeOpenCode.
 - TASK_NAME: Name of the current Task CR
 - TASK_NAMESPACE: Namespace of the current Task CR
 - WORKSPACE_DIR: Working directory where task.md and context files are mounted
+`
+
+	runtimePromptResultEnv = `- TASK_RESULT_FILE: Where to write your structured result (see below)
+`
 
+	runtimePromptInfo = `
 ### Getting More Information
 To get full Task specification:
   kubectl get task ${TASK_NAME} -n ${TASK_NAMESPACE} -o yaml
@@ -87,17 +133,50 @@ To get Task status:
 - ${WORKSPACE_DIR}/.kubeopencode/context.md: KubeOpenCode context (loaded via OpenCode instructions)
 - Additional contexts may be mounted as separate files
 - Note: Repository's AGENTS.md/CLAUDE.md files are preserved and loaded by OpenCode automatically
+`
+
+	runtimePromptResult = `
+### Reporting Results
+Before exiting, write a JSON result to ${TASK_RESULT_FILE} (max 4KB) so callers
+can see what you did without reading logs:
+  {"outcome": "succeeded", "summary": "Opened PR #42", "outputs": {"prURL": "https://..."}}
+outcome is one of: succeeded, failed, needs-human.
+`
 
+	runtimePromptConcepts = `
 ### KubeOpenCode Concepts
 - Task: Single AI task execution (what you're running now)
 - Agent: Configuration for how tasks are executed (image, credentials, etc.)
 `
 )
 
+// defaultRetryOn lists the failure reasons retried when retryPolicy.retryOn is empty.
+// These are infrastructure failures that are likely to succeed on a fresh Pod.
+var defaultRetryOn = []string{
+	kubeopenv1alpha1.ReasonEvicted,
+	kubeopenv1alpha1.ReasonPodLost,
+	kubeopenv1alpha1.ReasonGitInitError,
+	kubeopenv1alpha1.ReasonURLFetchError,
+	kubeopenv1alpha1.ReasonPodCreationError,
+}
+
 // TaskReconciler reconciles a Task object
 type TaskReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// PodLostGracePeriod overrides DefaultPodLostGracePeriod when non-zero
+	PodLostGracePeriod time.Duration
+
+	// PodPendingThreshold overrides DefaultPodPendingThreshold when non-zero
+	PodPendingThreshold time.Duration
+
+	// OpenCode is used to manage sessions on the servers of Server-mode Agents.
+	// Defaults to the OpenCode HTTP API when nil.
+	OpenCode OpenCodeClient
+
+	// admission tracks per-Agent concurrency slots and queues in memory
+	admission *taskAdmission
 }
 
 // +kubebuilder:rbac:groups=kubeopencode.io,resources=tasks,verbs=get;list;watch;create;update;patch;delete
@@ -109,6 +188,7 @@ type TaskReconciler struct {
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create
 
 // Reconcile is part of the main kubernetes reconciliation loop
 func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -140,6 +220,15 @@ func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return r.handleQueuedTask(ctx, task)
 	}
 
+	// If waiting to retry a failed attempt, check if the backoff has elapsed
+	// (a user-initiated stop cancels the pending retry)
+	if task.Status.Phase == kubeopenv1alpha1.TaskPhasePending {
+		if task.Annotations != nil && task.Annotations[AnnotationStop] == "true" {
+			return r.handleStop(ctx, task)
+		}
+		return r.handleRetry(ctx, task)
+	}
+
 	// If completed/failed, handle cleanup based on KubeOpenCodeConfig
 	if task.Status.Phase == kubeopenv1alpha1.TaskPhaseCompleted ||
 		task.Status.Phase == kubeopenv1alpha1.TaskPhaseFailed {
@@ -154,12 +243,13 @@ func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 	}
 
 	// Update task status from Pod status (both Pod mode and Server mode use Pods now)
-	if err := r.updateTaskStatusFromPod(ctx, task); err != nil {
+	result, err := r.updateTaskStatusFromPod(ctx, task)
+	if err != nil {
 		log.Error(err, "unable to update task status")
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 // initializeTask initializes a new Task and creates its Pod
@@ -248,31 +338,121 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// Check agent capacity if MaxConcurrentTasks is set
-	// Note: For cross-namespace, we check capacity in the Agent's namespace
-	if agentConfig.maxConcurrentTasks != nil && *agentConfig.maxConcurrentTasks > 0 {
-		hasCapacity, err := r.checkAgentCapacity(ctx, agentNamespace, agentName, *agentConfig.maxConcurrentTasks)
+	// Tasks of Server-mode Agents wait in the queue until the server is ready, e.g.
+	// during a rollout or while it is started again after being scaled down when idle
+	waitReason, waitMessage, err := r.serverWaitReason(ctx, agentConfig, agentName, agentNamespace)
+	if err != nil {
+		log.Error(err, "unable to check server readiness", "agent", agentName)
+		return ctrl.Result{}, err
+	}
+	if waitReason != "" {
+		log.Info("waiting for server to become ready", "agent", agentName, "reason", waitReason)
+		task.Status.ObservedGeneration = task.Generation
+		task.Status.Phase = kubeopenv1alpha1.TaskPhaseQueued
+		task.Status.AgentRef = &kubeopenv1alpha1.AgentReference{
+			Name:      agentName,
+			Namespace: agentNamespace,
+		}
+		meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+			Type:    kubeopenv1alpha1.ConditionTypeQueued,
+			Status:  metav1.ConditionTrue,
+			Reason:  waitReason,
+			Message: waitMessage,
+		})
+		if err := r.Status().Update(ctx, task); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: DefaultQueuedRequeueDelay}, nil
+	}
+
+	// Follow-up Tasks continue the OpenCode session of the Task referenced by sessionRef,
+	// on the server replica that holds it. Other Tasks (and retried attempts) start a
+	// new session.
+	workingTask.Status.SessionID = ""
+	workingTask.Status.ServerPod = ""
+	if task.Spec.SessionRef != nil {
+		sessionID, serverPod, waitMessage, err := r.resolveSessionRef(ctx, task, agentConfig, agentName, agentNamespace)
+		if err != nil {
+			log.Error(err, "unable to resolve sessionRef")
+			task.Status.ObservedGeneration = task.Generation
+			task.Status.Phase = kubeopenv1alpha1.TaskPhaseFailed
+			now := metav1.Now()
+			task.Status.CompletionTime = &now
+			meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+				Type:    kubeopenv1alpha1.ConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  kubeopenv1alpha1.ReasonSessionRefError,
+				Message: err.Error(),
+			})
+			if updateErr := r.Status().Update(ctx, task); updateErr != nil {
+				log.Error(updateErr, "unable to update Task status")
+				return ctrl.Result{}, updateErr
+			}
+			return ctrl.Result{}, nil // Don't requeue, the session cannot be continued
+		}
+		if waitMessage != "" {
+			log.Info("waiting for session", "sessionRef", task.Spec.SessionRef.Name)
+			task.Status.ObservedGeneration = task.Generation
+			task.Status.Phase = kubeopenv1alpha1.TaskPhaseQueued
+			task.Status.AgentRef = &kubeopenv1alpha1.AgentReference{
+				Name:      agentName,
+				Namespace: agentNamespace,
+			}
+			meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+				Type:    kubeopenv1alpha1.ConditionTypeQueued,
+				Status:  metav1.ConditionTrue,
+				Reason:  kubeopenv1alpha1.ReasonWaitingForSession,
+				Message: waitMessage,
+			})
+			if err := r.Status().Update(ctx, task); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: DefaultSessionLookupInterval}, nil
+		}
+		workingTask.Status.SessionID = sessionID
+		workingTask.Status.ServerPod = serverPod
+	}
+
+	// Tasks of multi-replica servers are pinned to one replica and attach to it
+	// directly, so their session always runs on the same server
+	if serverReplicaCount(agentConfig.serverConfig) > 1 && workingTask.Status.ServerPod == "" {
+		replica, err := r.assignServerReplica(ctx, agentName, agentNamespace)
 		if err != nil {
-			log.Error(err, "unable to check agent capacity")
+			log.Error(err, "unable to assign server replica", "agent", agentName)
 			return ctrl.Result{}, err
 		}
+		if replica == nil {
+			// The Agent reported ready replicas, but they are terminating or not ready anymore
+			log.Info("no server replica available, requeueing", "agent", agentName)
+			return ctrl.Result{RequeueAfter: DefaultQueuedRequeueDelay}, nil
+		}
+		workingTask.Status.ServerPod = replica.Name
+	}
 
-		if !hasCapacity {
-			// Agent is at capacity, queue the task
-			log.Info("agent at capacity, queueing task", "agent", agentName, "maxConcurrent", *agentConfig.maxConcurrentTasks)
+	// Check agent capacity if MaxConcurrentTasks or a per-namespace fair share is set
+	// Note: For cross-namespace, we check capacity in the Agent's namespace
+	limits := agentAdmissionLimits(agentConfig)
+	if limits.enabled(task.Namespace) {
+		check := r.checkAgentCapacity(ctx, task, agentNamespace, agentName, limits)
+		if !check.admitted {
+			// Agent (or the Task's namespace share) is at capacity, or queued Tasks are ahead, queue the task
+			log.Info("agent at capacity, queueing task", "agent", agentName, "maxConcurrent", limits.maxConcurrent,
+				"namespaceLimited", check.namespaceLimited, "position", check.position)
 
 			task.Status.ObservedGeneration = task.Generation
 			task.Status.Phase = kubeopenv1alpha1.TaskPhaseQueued
+			task.Status.QueuePosition = check.position
 			task.Status.AgentRef = &kubeopenv1alpha1.AgentReference{
 				Name:      agentName,
 				Namespace: agentNamespace,
 			}
 
+			reason, message := capacityQueuedCondition(agentName, task.Namespace, limits, check)
 			meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
 				Type:    kubeopenv1alpha1.ConditionTypeQueued,
 				Status:  metav1.ConditionTrue,
-				Reason:  kubeopenv1alpha1.ReasonAgentAtCapacity,
-				Message: fmt.Sprintf("Waiting for agent %q capacity (max: %d)", agentName, *agentConfig.maxConcurrentTasks),
+				Reason:  reason,
+				Message: message,
 			})
 
 			if err := r.Status().Update(ctx, task); err != nil {
@@ -287,7 +467,8 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 
 	// Check agent quota if configured
 	// Note: This checks rate limiting for task starts within a sliding window
-	if agentConfig.quota != nil {
+	nsQuota := namespaceQuota(agentConfig, task.Namespace)
+	if agentConfig.quota != nil || nsQuota != nil {
 		agent, err := r.getAgentForQuota(ctx, agentName, agentNamespace)
 		if err != nil {
 			log.Error(err, "unable to get Agent for quota check")
@@ -299,13 +480,19 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 			log.Error(err, "unable to check agent quota")
 			return ctrl.Result{}, err
 		}
+		reason, message := "", ""
+		if !hasQuota {
+			reason = kubeopenv1alpha1.ReasonQuotaExceeded
+			message = fmt.Sprintf("Waiting for agent %q quota (max: %d per %ds)",
+				agentName, agentConfig.quota.MaxTaskStarts, agentConfig.quota.WindowSeconds)
+		} else if hasQuota, requeueDelay = checkNamespaceQuota(agent, task.Namespace, nsQuota); !hasQuota {
+			reason = kubeopenv1alpha1.ReasonNamespaceQuotaExceeded
+			message = namespaceQuotaQueuedMessage(agentName, task.Namespace, nsQuota)
+		}
 
 		if !hasQuota {
 			// Quota exceeded, queue the task
-			log.Info("agent quota exceeded, queueing task",
-				"agent", agentName,
-				"maxTaskStarts", agentConfig.quota.MaxTaskStarts,
-				"windowSeconds", agentConfig.quota.WindowSeconds)
+			log.Info("agent quota exceeded, queueing task", "agent", agentName, "reason", reason)
 
 			task.Status.ObservedGeneration = task.Generation
 			task.Status.Phase = kubeopenv1alpha1.TaskPhaseQueued
@@ -315,11 +502,10 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 			}
 
 			meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
-				Type:   kubeopenv1alpha1.ConditionTypeQueued,
-				Status: metav1.ConditionTrue,
-				Reason: kubeopenv1alpha1.ReasonQuotaExceeded,
-				Message: fmt.Sprintf("Waiting for agent %q quota (max: %d per %ds)",
-					agentName, agentConfig.quota.MaxTaskStarts, agentConfig.quota.WindowSeconds),
+				Type:    kubeopenv1alpha1.ConditionTypeQueued,
+				Status:  metav1.ConditionTrue,
+				Reason:  reason,
+				Message: message,
 			})
 
 			if err := r.Status().Update(ctx, task); err != nil {
@@ -331,6 +517,16 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 		}
 	}
 
+	// Hold the slot until the Task is observed as Running. If a concurrent reconcile
+	// took the slot since the capacity check, requeue to queue the Task.
+	if limits.enabled(task.Namespace) {
+		agentKey := types.NamespacedName{Namespace: agentNamespace, Name: agentName}
+		if !r.admission.tryReserve(task, agentKey, limits) {
+			log.V(1).Info("agent slot taken by another task, requeueing", "agent", agentName)
+			return ctrl.Result{Requeue: true}, nil
+		}
+	}
+
 	// Determine server URL for Server-mode Agents (empty for Pod mode)
 	// In Server mode, Tasks create Pods that use `opencode run --attach` to connect
 	// to the persistent OpenCode server instead of running a standalone instance.
@@ -338,18 +534,28 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 	if agentConfig.serverConfig != nil {
 		port := GetServerPort(&kubeopenv1alpha1.Agent{Spec: kubeopenv1alpha1.AgentSpec{ServerConfig: agentConfig.serverConfig}})
 		serverURL = ServerURL(agentName, agentNamespace, port)
+		if workingTask.Status.ServerPod != "" {
+			replicaURL, err := r.serverReplicaURL(ctx, agentNamespace, workingTask.Status.ServerPod, port)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if replicaURL == "" {
+				log.Info("server replica gone, requeueing", "serverPod", workingTask.Status.ServerPod)
+				return ctrl.Result{Requeue: true}, nil
+			}
+			serverURL = replicaURL
+		}
 		log.Info("Creating Pod for Server-mode Task", "serverURL", serverURL)
+
+		// Stage contexts in the Task's own workspace on the server. Using the same
+		// path in the Task Pod keeps the mount paths in task.md valid on the server.
+		agentConfig.workspaceDir = ServerTaskWorkspaceDir(agentConfig.workspaceDir, task.Namespace, task.Name)
 	}
 
 	// Generate Pod name
-	// For cross-namespace, include Task namespace to avoid name conflicts
-	isCrossNamespace := agentNamespace != task.Namespace
-	var podName string
-	if isCrossNamespace {
-		podName = fmt.Sprintf("%s-%s-pod", task.Namespace, task.Name)
-	} else {
-		podName = fmt.Sprintf("%s-pod", task.Name)
-	}
+	// Each attempt gets a fresh Pod; retries append the attempt number
+	attempt := int32(len(task.Status.Attempts)) + 1
+	podName := taskPodName(task, agentNamespace, attempt)
 
 	// Check if Pod already exists (in Agent's namespace)
 	existingPod := &corev1.Pod{}
@@ -359,9 +565,20 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 		task.Status.ObservedGeneration = task.Generation
 		task.Status.PodName = podName
 		task.Status.PodNamespace = agentNamespace
+		task.Status.SessionID = workingTask.Status.SessionID
+		task.Status.ServerPod = workingTask.Status.ServerPod
 		task.Status.Phase = kubeopenv1alpha1.TaskPhaseRunning
+		task.Status.AgentRef = &kubeopenv1alpha1.AgentReference{
+			Name:      agentName,
+			Namespace: agentNamespace,
+		}
 		now := metav1.Now()
 		task.Status.StartTime = &now
+		task.Status.Attempts = append(task.Status.Attempts, kubeopenv1alpha1.TaskAttempt{
+			Attempt:   attempt,
+			PodName:   podName,
+			StartTime: &now,
+		})
 		return ctrl.Result{}, r.Status().Update(ctx, task)
 	}
 
@@ -374,7 +591,7 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 	// Note: workingTask has merged spec from TaskTemplate (if any)
 	// Note: For cross-namespace, Task ConfigMap contexts are read from Task namespace
 	// and embedded into the ConfigMap created in Agent namespace
-	contextConfigMap, fileMounts, dirMounts, gitMounts, err := r.processAllContexts(ctx, workingTask, agentConfig, agentNamespace)
+	contextConfigMap, fileMounts, dirMounts, gitMounts, urlMounts, err := r.processAllContexts(ctx, workingTask, agentConfig, agentNamespace)
 	if err != nil {
 		log.Error(err, "unable to process contexts")
 		// Update task status to Failed - context errors are user configuration issues
@@ -423,36 +640,42 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 
 	// Get system configuration (image, pull policies)
 	// Use Agent's namespace for system config lookup
-	sysCfg := r.getSystemConfig(ctx, agentNamespace)
+	sysCfg := getSystemConfig(ctx, r, agentNamespace)
+
+	// Resolve the effective timeout (Task/TaskTemplate value capped by the Agent ceiling).
+	// It is recorded in the Pod's activeDeadlineSeconds, which the controller also
+	// reads back to enforce the timeout while the Task is Running.
+	workingTask.Spec.TimeoutSeconds = effectiveTimeoutSeconds(workingTask.Spec.TimeoutSeconds, agentConfig.maxTimeoutSeconds)
+
+	// The Git mirror cache claim must exist before a Pod mounting it is created
+	if len(gitMounts) > 0 {
+		if err := ensureGitCachePVC(ctx, r.Client, agentNamespace, sysCfg.gitCache); err != nil {
+			log.Error(err, "unable to ensure Git cache PersistentVolumeClaim", "namespace", agentNamespace)
+			return ctrl.Result{}, err
+		}
+	}
 
 	// Create Pod with agent configuration and context mounts
 	// Pod is created in Agent's namespace
 	// Use workingTask which has merged spec from TaskTemplate (if any)
 	// For Server-mode, serverURL is passed to generate --attach command
-	pod := buildPod(workingTask, podName, agentNamespace, agentConfig, contextConfigMap, fileMounts, dirMounts, gitMounts, sysCfg, serverURL)
+	pod := buildPod(workingTask, podName, agentNamespace, agentConfig, contextConfigMap, fileMounts, dirMounts, gitMounts, urlMounts, sysCfg, serverURL)
 
 	if err := r.Create(ctx, pod); err != nil {
 		log.Error(err, "unable to create Pod", "pod", podName, "namespace", agentNamespace)
-		// Update task status to Failed - Pod creation error is a terminal failure
-		task.Status.ObservedGeneration = task.Generation
-		task.Status.Phase = kubeopenv1alpha1.TaskPhaseFailed
+		// Record the failed attempt - Pod creation error is terminal unless the retryPolicy retries it
 		now := metav1.Now()
-		task.Status.CompletionTime = &now
-		meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
-			Type:    kubeopenv1alpha1.ConditionTypeReady,
-			Status:  metav1.ConditionFalse,
-			Reason:  kubeopenv1alpha1.ReasonPodCreationError,
-			Message: err.Error(),
+		task.Status.PodNamespace = agentNamespace
+		task.Status.Attempts = append(task.Status.Attempts, kubeopenv1alpha1.TaskAttempt{
+			Attempt:   attempt,
+			PodName:   podName,
+			StartTime: &now,
 		})
-		if updateErr := r.Status().Update(ctx, task); updateErr != nil {
-			log.Error(updateErr, "unable to update Task status")
-			return ctrl.Result{}, updateErr
-		}
-		return ctrl.Result{}, nil // Don't requeue, Pod creation failed
+		return r.failAttempt(ctx, task, kubeopenv1alpha1.ReasonPodCreationError, err.Error(), nil)
 	}
 
 	// Record task start for quota tracking (if quota is configured)
-	if agentConfig.quota != nil {
+	if agentConfig.quota != nil || nsQuota != nil {
 		agent, err := r.getAgentForQuota(ctx, agentName, agentNamespace)
 		if err != nil {
 			log.Error(err, "unable to get Agent for quota recording")
@@ -469,6 +692,8 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 	task.Status.ObservedGeneration = task.Generation
 	task.Status.PodName = podName
 	task.Status.PodNamespace = agentNamespace
+	task.Status.SessionID = workingTask.Status.SessionID
+	task.Status.ServerPod = workingTask.Status.ServerPod
 	task.Status.Phase = kubeopenv1alpha1.TaskPhaseRunning
 	task.Status.AgentRef = &kubeopenv1alpha1.AgentReference{
 		Name:      agentName,
@@ -476,22 +701,36 @@ func (r *TaskReconciler) initializeTask(ctx context.Context, task *kubeopenv1alp
 	}
 	now := metav1.Now()
 	task.Status.StartTime = &now
+	task.Status.Results = nil // Results are reported per attempt
+	task.Status.Contexts = r.taskContextRecords(ctx, workingTask, agentConfig, agentNamespace)
+	task.Status.Attempts = append(task.Status.Attempts, kubeopenv1alpha1.TaskAttempt{
+		Attempt:   attempt,
+		PodName:   podName,
+		StartTime: &now,
+	})
 
 	if err := r.Status().Update(ctx, task); err != nil {
 		log.Error(err, "unable to update Task status")
 		return ctrl.Result{}, err
 	}
 
-	log.Info("initialized Task", "pod", podName, "image", agentConfig.agentImage)
+	log.Info("initialized Task", "pod", podName, "attempt", attempt, "image", agentConfig.agentImage)
+
+	// Requeue at the deadline so the timeout is enforced even without Pod events
+	if workingTask.Spec.TimeoutSeconds != nil {
+		return ctrl.Result{RequeueAfter: time.Duration(*workingTask.Spec.TimeoutSeconds) * time.Second}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
-// updateTaskStatusFromPod syncs task status from Pod status
-func (r *TaskReconciler) updateTaskStatusFromPod(ctx context.Context, task *kubeopenv1alpha1.Task) error {
+// updateTaskStatusFromPod syncs task status from Pod status.
+// While the Pod is still active, it enforces the Task timeout and returns a
+// requeue at the deadline.
+func (r *TaskReconciler) updateTaskStatusFromPod(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
 	if task.Status.PodName == "" {
-		return nil
+		return ctrl.Result{}, nil
 	}
 
 	// Get Pod status from the correct namespace
@@ -505,31 +744,422 @@ func (r *TaskReconciler) updateTaskStatusFromPod(ctx context.Context, task *kube
 	podKey := types.NamespacedName{Name: task.Status.PodName, Namespace: podNamespace}
 	if err := r.Get(ctx, podKey, pod); err != nil {
 		if errors.IsNotFound(err) {
-			log.Error(err, "Pod not found", "pod", task.Status.PodName)
-			return nil
+			return r.handlePodLost(ctx, task, podKey)
 		}
-		return err
+		return ctrl.Result{}, err
 	}
 
+	// Record the OpenCode session of Server-mode Tasks, so it can be deleted on stop
+	// or deletion. It is persisted by the status updates below.
+	sessionRecorded, sessionLookupAfter := r.recordServerSession(ctx, task)
+
+	// Record the Git commits and URL digests reported by the init containers
+	contextsRecorded := recordContextResults(task, pod)
+
 	// Check Pod phase
 	switch pod.Status.Phase {
 	case corev1.PodSucceeded:
 		task.Status.ObservedGeneration = task.Generation
+		task.Status.Results = agentResults(pod)
+		// The git-push sidecars do not affect the Pod phase: the agent succeeded,
+		// but its changes were not pushed back
+		if msg, exitCode, failed := initContainerFailure(pod, "git-push-"); failed {
+			log.Info("task attempt failed to push changes", "pod", task.Status.PodName)
+			return r.failAttempt(ctx, task, kubeopenv1alpha1.ReasonGitPushError, msg, &exitCode)
+		}
 		task.Status.Phase = kubeopenv1alpha1.TaskPhaseCompleted
 		now := metav1.Now()
 		task.Status.CompletionTime = &now
+		exitCode := int32(0)
+		endCurrentAttempt(task, now, &exitCode, "", "")
 		log.Info("task completed", "pod", task.Status.PodName)
-		return r.Status().Update(ctx, task)
+		return ctrl.Result{}, r.Status().Update(ctx, task)
 	case corev1.PodFailed:
-		task.Status.ObservedGeneration = task.Generation
-		task.Status.Phase = kubeopenv1alpha1.TaskPhaseFailed
-		now := metav1.Now()
-		task.Status.CompletionTime = &now
-		log.Info("task failed", "pod", task.Status.PodName)
-		return r.Status().Update(ctx, task)
+		// The agent may report a result (e.g., outcome failed) even when it exits non-zero
+		task.Status.Results = agentResults(pod)
+		reason, message, exitCode := podFailure(task, pod)
+		log.Info("task attempt failed", "pod", task.Status.PodName, "reason", reason)
+		return r.failAttempt(ctx, task, reason, message, exitCode)
 	}
 
-	return nil
+	// Enforce the Task timeout while the Pod is still active
+	var requeueAfter time.Duration
+	if pod.Spec.ActiveDeadlineSeconds != nil && task.Status.StartTime != nil {
+		deadline := task.Status.StartTime.Add(time.Duration(*pod.Spec.ActiveDeadlineSeconds) * time.Second)
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return r.handleTimeout(ctx, task, pod)
+		}
+		requeueAfter = remaining
+	}
+
+	// Flag Pods that are not making progress while the Task is still Running
+	pendingThreshold := r.PodPendingThreshold
+	if pendingThreshold <= 0 {
+		pendingThreshold = DefaultPodPendingThreshold
+	}
+	reason, message, recheckAfter := podStall(pod, pendingThreshold, time.Now())
+	if recheckAfter > 0 && (requeueAfter == 0 || recheckAfter < requeueAfter) {
+		requeueAfter = recheckAfter
+	}
+	if sessionLookupAfter > 0 && (requeueAfter == 0 || sessionLookupAfter < requeueAfter) {
+		requeueAfter = sessionLookupAfter
+	}
+	if setStalledCondition(task, reason, message) || sessionRecorded || contextsRecorded {
+		if reason != "" {
+			log.Info("task pod stalled", "pod", pod.Name, "reason", reason, "message", message)
+		}
+		if err := r.Status().Update(ctx, task); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// podStall reports why an active Pod is not making progress: a container waiting on
+// an image pull or an invalid configuration, the Pod being unschedulable, or the Pod
+// staying Pending longer than pendingThreshold. Returns an empty reason if the Pod
+// is progressing; recheckAfter is when a Pending Pod crosses the threshold.
+func podStall(pod *corev1.Pod, pendingThreshold time.Duration, now time.Time) (reason, message string, recheckAfter time.Duration) {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		waiting := cs.State.Waiting
+		if waiting == nil {
+			continue
+		}
+		switch waiting.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+			return kubeopenv1alpha1.ReasonImagePullError, waitingMessage(cs.Name, waiting), 0
+		case "CreateContainerConfigError", "CreateContainerError":
+			return kubeopenv1alpha1.ReasonContainerConfigError, waitingMessage(cs.Name, waiting), 0
+		}
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			return kubeopenv1alpha1.ReasonUnschedulable, fmt.Sprintf("Pod %s cannot be scheduled: %s", pod.Name, cond.Message), 0
+		}
+	}
+
+	if pod.Status.Phase == corev1.PodPending {
+		pendingFor := now.Sub(pod.CreationTimestamp.Time)
+		if pendingFor < pendingThreshold {
+			return "", "", pendingThreshold - pendingFor
+		}
+		return kubeopenv1alpha1.ReasonPodPending, fmt.Sprintf("Pod %s has been Pending for more than %s", pod.Name, pendingThreshold), 0
+	}
+
+	return "", "", 0
+}
+
+// waitingMessage describes a container stuck in the Waiting state.
+func waitingMessage(containerName string, waiting *corev1.ContainerStateWaiting) string {
+	if waiting.Message == "" {
+		return fmt.Sprintf("container %s is waiting: %s", containerName, waiting.Reason)
+	}
+	return fmt.Sprintf("container %s is waiting (%s): %s", containerName, waiting.Reason, waiting.Message)
+}
+
+// setStalledCondition sets the Stalled condition for the given stall reason, or removes
+// it when reason is empty. Returns true if the Task conditions changed.
+func setStalledCondition(task *kubeopenv1alpha1.Task, reason, message string) bool {
+	if reason == "" {
+		return meta.RemoveStatusCondition(&task.Status.Conditions, kubeopenv1alpha1.ConditionTypeStalled)
+	}
+	return meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+		Type:    kubeopenv1alpha1.ConditionTypeStalled,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// handlePodLost fails the current attempt with reason PodLost when the Task's Pod
+// no longer exists, e.g. after a node drain or a manual deletion. Until the grace
+// period since the attempt started has elapsed, the Pod may simply not be in the
+// informer cache yet, so the check is requeued instead.
+func (r *TaskReconciler) handlePodLost(ctx context.Context, task *kubeopenv1alpha1.Task, podKey types.NamespacedName) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	gracePeriod := r.PodLostGracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultPodLostGracePeriod
+	}
+	if task.Status.StartTime != nil {
+		if remaining := time.Until(task.Status.StartTime.Add(gracePeriod)); remaining > 0 {
+			log.V(1).Info("pod not found, waiting for grace period", "pod", podKey.Name, "remaining", remaining)
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	log.Info("pod lost", "pod", podKey.Name, "namespace", podKey.Namespace)
+	message := fmt.Sprintf("Pod %s/%s was deleted before the Task finished", podKey.Namespace, podKey.Name)
+	return r.failAttempt(ctx, task, kubeopenv1alpha1.ReasonPodLost, message, nil)
+}
+
+// handleTimeout terminates the Pod of a Task that exceeded its timeout
+// and fails the current attempt with reason TimedOut.
+func (r *TaskReconciler) handleTimeout(ctx context.Context, task *kubeopenv1alpha1.Task, pod *corev1.Pod) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "failed to delete pod for timed out task", "pod", pod.Name)
+		return ctrl.Result{}, err
+	}
+
+	log.Info("task timed out", "pod", pod.Name, "timeoutSeconds", *pod.Spec.ActiveDeadlineSeconds)
+	return r.failAttempt(ctx, task, kubeopenv1alpha1.ReasonTimedOut, timedOutMessage(task, pod), nil)
+}
+
+// timedOutMessage describes a Task that exceeded its timeout,
+// including the elapsed time since the Task started.
+func timedOutMessage(task *kubeopenv1alpha1.Task, pod *corev1.Pod) string {
+	elapsed := "unknown"
+	if task.Status.StartTime != nil {
+		elapsed = time.Since(task.Status.StartTime.Time).Round(time.Second).String()
+	}
+	timeout := int64(0)
+	if pod.Spec.ActiveDeadlineSeconds != nil {
+		timeout = *pod.Spec.ActiveDeadlineSeconds
+	}
+	msg := fmt.Sprintf("Task timed out after %s (timeout: %ds)", elapsed, timeout)
+	// Explain why the Pod never made progress, if it was flagged as stalled
+	if stalled := meta.FindStatusCondition(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeStalled); stalled != nil {
+		msg = fmt.Sprintf("%s while stalled (%s): %s", msg, stalled.Reason, stalled.Message)
+	}
+	return msg
+}
+
+// effectiveTimeoutSeconds applies the Agent's maxTimeoutSeconds ceiling to the
+// Task timeout. Returns nil if neither is set.
+func effectiveTimeoutSeconds(timeoutSeconds, maxTimeoutSeconds *int32) *int32 {
+	if maxTimeoutSeconds == nil || *maxTimeoutSeconds <= 0 {
+		return timeoutSeconds
+	}
+	if timeoutSeconds == nil || *timeoutSeconds > *maxTimeoutSeconds {
+		ceiling := *maxTimeoutSeconds
+		return &ceiling
+	}
+	return timeoutSeconds
+}
+
+// podFailure classifies a failed Pod into a failure reason, a human-readable
+// message, and the exit code of the failed container (if known).
+func podFailure(task *kubeopenv1alpha1.Task, pod *corev1.Pod) (string, string, *int32) {
+	switch pod.Status.Reason {
+	case PodReasonDeadlineExceeded:
+		// The kubelet enforced activeDeadlineSeconds before the controller did
+		return kubeopenv1alpha1.ReasonTimedOut, timedOutMessage(task, pod), nil
+	case PodReasonEvicted:
+		return kubeopenv1alpha1.ReasonEvicted, pod.Status.Message, nil
+	}
+
+	if msg, exitCode, failed := initContainerFailure(pod, "url-fetch-"); failed {
+		return kubeopenv1alpha1.ReasonURLFetchError, msg, &exitCode
+	}
+	if msg, exitCode, failed := initContainerFailure(pod, "git-init-"); failed {
+		return kubeopenv1alpha1.ReasonGitInitError, msg, &exitCode
+	}
+	if msg, exitCode, failed := initContainerFailure(pod, "git-push-"); failed {
+		return kubeopenv1alpha1.ReasonGitPushError, msg, &exitCode
+	}
+	if msg, exitCode, failed := initContainerFailure(pod, ""); failed {
+		return kubeopenv1alpha1.ReasonPodFailed, msg, &exitCode
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		terminated := cs.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		exitCode := terminated.ExitCode
+		if terminated.Reason == ContainerReasonOOMKilled {
+			msg := fmt.Sprintf("container %s was OOMKilled (exit code %d)", cs.Name, exitCode)
+			if limit := containerMemoryLimit(pod, cs.Name); limit != "" {
+				msg = fmt.Sprintf("%s; memory limit: %s", msg, limit)
+			}
+			return kubeopenv1alpha1.ReasonOOMKilled, msg, &exitCode
+		}
+		msg := fmt.Sprintf("container %s exited with code %d", cs.Name, exitCode)
+		if terminated.Reason != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, terminated.Reason)
+		}
+		return kubeopenv1alpha1.ReasonPodFailed, msg, &exitCode
+	}
+
+	msg := pod.Status.Message
+	if msg == "" {
+		msg = fmt.Sprintf("Pod %s failed", pod.Name)
+	}
+	return kubeopenv1alpha1.ReasonPodFailed, msg, nil
+}
+
+// containerMemoryLimit returns the memory limit of the named container, or "" if unset.
+func containerMemoryLimit(pod *corev1.Pod, containerName string) string {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != containerName {
+			continue
+		}
+		if limit, ok := c.Resources.Limits[corev1.ResourceMemory]; ok {
+			return limit.String()
+		}
+	}
+	return ""
+}
+
+// initContainerFailure reports whether an init container with the given name prefix
+// failed, returning its termination message (or a generic message if none was written)
+// and exit code.
+func initContainerFailure(pod *corev1.Pod, namePrefix string) (string, int32, bool) {
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if !strings.HasPrefix(cs.Name, namePrefix) {
+			continue
+		}
+		terminated := cs.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		if msg := strings.TrimSpace(terminated.Message); msg != "" {
+			return msg, terminated.ExitCode, true
+		}
+		return fmt.Sprintf("init container %s failed with exit code %d", cs.Name, terminated.ExitCode), terminated.ExitCode, true
+	}
+	return "", 0, false
+}
+
+// failAttempt records the end of the current attempt and either schedules a retry
+// (phase Pending) when the retryPolicy allows it, or marks the Task as Failed.
+func (r *TaskReconciler) failAttempt(ctx context.Context, task *kubeopenv1alpha1.Task, reason, message string, exitCode *int32) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	now := metav1.Now()
+	endCurrentAttempt(task, now, exitCode, reason, message)
+	task.Status.ObservedGeneration = task.Generation
+
+	attempts := int32(len(task.Status.Attempts))
+	if policy := task.Spec.RetryPolicy; policy != nil && attempts < policy.MaxAttempts && isRetryableReason(policy, reason) {
+		delay := retryBackoff(policy, attempts)
+		task.Status.Phase = kubeopenv1alpha1.TaskPhasePending
+		meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+			Type:    kubeopenv1alpha1.ConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  kubeopenv1alpha1.ReasonRetrying,
+			Message: fmt.Sprintf("Attempt %d of %d failed (%s): %s; retrying in %s", attempts, policy.MaxAttempts, reason, message, delay),
+		})
+		if err := r.Status().Update(ctx, task); err != nil {
+			log.Error(err, "unable to update Task status")
+			return ctrl.Result{}, err
+		}
+		log.Info("scheduled task retry", "attempt", attempts, "reason", reason, "delay", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	task.Status.Phase = kubeopenv1alpha1.TaskPhaseFailed
+	task.Status.CompletionTime = &now
+	meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+		Type:    kubeopenv1alpha1.ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	log.Info("task failed", "pod", task.Status.PodName, "reason", reason)
+	return ctrl.Result{}, r.Status().Update(ctx, task)
+}
+
+// handleRetry starts the next attempt of a Task in Pending phase once
+// the retry backoff has elapsed.
+func (r *TaskReconciler) handleRetry(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	attempts := int32(len(task.Status.Attempts))
+	if attempts > 0 && task.Spec.RetryPolicy != nil {
+		last := task.Status.Attempts[attempts-1]
+		if last.EndTime != nil {
+			retryAt := last.EndTime.Add(retryBackoff(task.Spec.RetryPolicy, attempts))
+			if remaining := time.Until(retryAt); remaining > 0 {
+				return ctrl.Result{RequeueAfter: remaining}, nil
+			}
+		}
+	}
+
+	// Backoff elapsed, transition to empty phase to trigger initializeTask
+	log.Info("retrying task", "attempt", attempts+1)
+	task.Status.Phase = ""
+	task.Status.PodName = ""
+	if err := r.Status().Update(ctx, task); err != nil {
+		log.Error(err, "unable to update Task status")
+		return ctrl.Result{}, err
+	}
+
+	// Requeue immediately to trigger initializeTask
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// endCurrentAttempt fills in the outcome of the most recent attempt if it is still open.
+func endCurrentAttempt(task *kubeopenv1alpha1.Task, now metav1.Time, exitCode *int32, reason, message string) {
+	// A finished attempt is no longer stalled
+	meta.RemoveStatusCondition(&task.Status.Conditions, kubeopenv1alpha1.ConditionTypeStalled)
+
+	n := len(task.Status.Attempts)
+	if n == 0 || task.Status.Attempts[n-1].EndTime != nil {
+		return
+	}
+	attempt := &task.Status.Attempts[n-1]
+	attempt.EndTime = &now
+	attempt.ExitCode = exitCode
+	attempt.Reason = reason
+	attempt.Message = message
+}
+
+// isRetryableReason reports whether the retryPolicy retries failures with the given reason.
+func isRetryableReason(policy *kubeopenv1alpha1.RetryPolicy, reason string) bool {
+	retryOn := policy.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOn
+	}
+	for _, r := range retryOn {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff returns the delay before retrying after the given number of
+// failed attempts: backoffSeconds doubled for each prior retry, capped at maxBackoffSeconds.
+func retryBackoff(policy *kubeopenv1alpha1.RetryPolicy, failedAttempts int32) time.Duration {
+	backoff := DefaultRetryBackoff
+	if policy.BackoffSeconds != nil && *policy.BackoffSeconds > 0 {
+		backoff = time.Duration(*policy.BackoffSeconds) * time.Second
+	}
+	maxBackoff := DefaultMaxRetryBackoff
+	if policy.MaxBackoffSeconds != nil && *policy.MaxBackoffSeconds > 0 {
+		maxBackoff = time.Duration(*policy.MaxBackoffSeconds) * time.Second
+	}
+
+	delay := backoff
+	for i := int32(1); i < failedAttempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// taskPodName returns the Pod name for the given attempt of a Task.
+// The first attempt keeps the unsuffixed name; retries append the attempt number.
+// For cross-namespace, the Task namespace is included to avoid name conflicts.
+func taskPodName(task *kubeopenv1alpha1.Task, agentNamespace string, attempt int32) string {
+	podName := fmt.Sprintf("%s-pod", task.Name)
+	if agentNamespace != task.Namespace {
+		podName = fmt.Sprintf("%s-%s-pod", task.Namespace, task.Name)
+	}
+	if attempt > 1 {
+		podName = fmt.Sprintf("%s-%d", podName, attempt)
+	}
+	return podName
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -537,12 +1167,25 @@ func (r *TaskReconciler) updateTaskStatusFromPod(ctx context.Context, task *kube
 // to Tasks (to support cross-namespace Agent scenarios). The custom handler maps
 // Pod events to Task reconciliation requests using labels.
 func (r *TaskReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.admission = newTaskAdmission()
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kubeopenv1alpha1.Task{}).
 		Watches(
 			&corev1.Pod{},
 			handler.EnqueueRequestsFromMapFunc(podToTaskMapper),
 		).
+		// Wake Tasks waiting for the server of a Server-mode Agent to be ready
+		Watches(
+			&kubeopenv1alpha1.Agent{},
+			handler.EnqueueRequestsFromMapFunc(r.tasksWaitingForServer),
+		).
+		// Wake follow-up Tasks waiting for the Task their sessionRef points to
+		Watches(
+			&kubeopenv1alpha1.Task{},
+			handler.EnqueueRequestsFromMapFunc(r.followUpTasksForTask),
+		).
+		// Track Agent capacity from Task events and wake Queued Tasks when a slot frees up
+		WatchesRawSource(source.Kind(mgr.GetCache(), &kubeopenv1alpha1.Task{}, &admissionEventHandler{admission: r.admission})).
 		Complete(r)
 }
 
@@ -612,7 +1255,15 @@ func (r *TaskReconciler) getAgentConfigWithName(ctx context.Context, task *kubeo
 		attachImage = DefaultAttachImage
 	}
 
+	// Server-mode Agents can count the active sessions on the server against maxConcurrentTasks
+	var activeSessions int32
+	if sc := agent.Spec.ServerConfig; sc != nil && sc.ConcurrencyCounting == kubeopenv1alpha1.ConcurrencyCountingActiveSessions &&
+		agent.Status.ServerStatus != nil {
+		activeSessions = agent.Status.ServerStatus.ActiveSessions
+	}
+
 	return agentConfig{
+		name:               agentName,
 		agentImage:         agentImage,
 		executorImage:      executorImage,
 		attachImage:        attachImage,
@@ -625,7 +1276,10 @@ func (r *TaskReconciler) getAgentConfigWithName(ctx context.Context, task *kubeo
 		serviceAccountName: agent.Spec.ServiceAccountName,
 		maxConcurrentTasks: agent.Spec.MaxConcurrentTasks,
 		quota:              agent.Spec.Quota,
+		fairShare:          agent.Spec.FairShare,
+		maxTimeoutSeconds:  agent.Spec.MaxTimeoutSeconds,
 		serverConfig:       agent.Spec.ServerConfig,
+		activeSessions:     activeSessions,
 	}, agentName, agentNamespace, nil
 }
 
@@ -732,6 +1386,13 @@ func (r *TaskReconciler) resolveTaskTemplate(ctx context.Context, task *kubeopen
 		merged.Description = template.Spec.Description
 	}
 
+	// 4. TimeoutSeconds: Task takes precedence
+	if task.Spec.TimeoutSeconds != nil {
+		merged.TimeoutSeconds = task.Spec.TimeoutSeconds
+	} else if template.Spec.TimeoutSeconds != nil {
+		merged.TimeoutSeconds = template.Spec.TimeoutSeconds
+	}
+
 	// Keep the TaskTemplateRef reference in merged spec
 	merged.TaskTemplateRef = task.Spec.TaskTemplateRef
 
@@ -741,7 +1402,7 @@ func (r *TaskReconciler) resolveTaskTemplate(ctx context.Context, task *kubeopen
 // handleTaskDeletion handles Task deletion, cleaning up cross-namespace Pods or Server-mode sessions.
 // When Pod runs in a different namespace (cross-namespace Agent), we can't use
 // OwnerReference for automatic cleanup, so we use a finalizer instead.
-// For Server-mode tasks, we also need to delete the OpenCode session.
+// For Server-mode tasks, we also delete the OpenCode session and the Task workspace on the server.
 func (r *TaskReconciler) handleTaskDeletion(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
@@ -751,16 +1412,19 @@ func (r *TaskReconciler) handleTaskDeletion(ctx context.Context, task *kubeopenv
 
 	log.Info("cleaning up resources for deleted Task", "task", task.Name)
 
-	// Delete Pod in the execution namespace (both Pod mode and Server mode use Pods)
-	if task.Status.PodName != "" && task.Status.PodNamespace != "" {
-		pod := &corev1.Pod{}
-		podKey := types.NamespacedName{Name: task.Status.PodName, Namespace: task.Status.PodNamespace}
-		if err := r.Get(ctx, podKey, pod); err == nil {
-			if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
-				log.Error(err, "failed to delete cross-namespace Pod")
-				return ctrl.Result{}, err
+	// Delete Pods in the execution namespace (both Pod mode and Server mode use Pods).
+	// Pods from earlier attempts are kept for their logs, so delete every attempt's Pod.
+	if task.Status.PodNamespace != "" {
+		for _, podName := range taskPodNames(task) {
+			pod := &corev1.Pod{}
+			podKey := types.NamespacedName{Name: podName, Namespace: task.Status.PodNamespace}
+			if err := r.Get(ctx, podKey, pod); err == nil {
+				if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+					log.Error(err, "failed to delete cross-namespace Pod")
+					return ctrl.Result{}, err
+				}
+				log.Info("deleted cross-namespace Pod", "pod", podName, "namespace", task.Status.PodNamespace)
 			}
-			log.Info("deleted cross-namespace Pod", "pod", task.Status.PodName, "namespace", task.Status.PodNamespace)
 		}
 
 		// Also delete the context ConfigMap in the execution namespace
@@ -775,6 +1439,20 @@ func (r *TaskReconciler) handleTaskDeletion(ctx context.Context, task *kubeopenv
 		}
 	}
 
+	// Delete the OpenCode sessions of Server-mode Tasks. Failures are retried, but only
+	// for DefaultSessionCleanupTimeout so an unreachable server cannot block deletion.
+	sessionID := task.Status.SessionID
+	if err := r.deleteServerSessions(ctx, task); err != nil {
+		if time.Since(task.DeletionTimestamp.Time) < DefaultSessionCleanupTimeout {
+			log.Info("failed to delete OpenCode session, retrying", "error", err.Error())
+			return ctrl.Result{RequeueAfter: DefaultRetryBackoff}, nil
+		}
+		log.Error(err, "giving up deleting OpenCode session", "session", task.Status.SessionID)
+	}
+
+	// Remove the Task's workspace from the server of a Server-mode Agent
+	r.deleteServerTaskWorkspace(ctx, task, sessionID)
+
 	// Re-fetch the task to get the latest version before updating
 	// This is necessary because the task may have been modified during cleanup
 	latestTask := &kubeopenv1alpha1.Task{}
@@ -803,8 +1481,114 @@ func (r *TaskReconciler) handleTaskDeletion(ctx context.Context, task *kubeopenv
 	return ctrl.Result{}, nil
 }
 
+// deleteServerTaskWorkspace removes the Task's workspace from the server of its Agent,
+// if the Agent runs in Server mode. sessionID is the session the Task recorded. A
+// workspace holding a session that follow-up Tasks (sessionRef) still use is kept,
+// like the session; the last follow-up Task removes it once its Task is gone.
+// Best effort: the workspace is only disk usage on the server, so failures are
+// logged and do not block Task deletion.
+func (r *TaskReconciler) deleteServerTaskWorkspace(ctx context.Context, task *kubeopenv1alpha1.Task, sessionID string) {
+	log := log.FromContext(ctx)
+
+	if task.Status.AgentRef == nil || task.Status.PodName == "" {
+		return
+	}
+
+	agent := &kubeopenv1alpha1.Agent{}
+	agentKey := types.NamespacedName{Name: task.Status.AgentRef.Name, Namespace: task.Status.AgentRef.Namespace}
+	if err := r.Get(ctx, agentKey, agent); err != nil || !IsServerMode(agent) {
+		// Without the Agent there is no server, and its workspace is gone as well
+		return
+	}
+
+	names := []string{task.Name}
+	if sessionID != "" {
+		inUse, err := r.sessionsInUse(ctx, task)
+		if err != nil {
+			log.Info("failed to check whether the session is in use, keeping Task workspace on server", "error", err.Error())
+			return
+		}
+		sessionTask, err := r.sessionWorkspaceTask(ctx, task)
+		if err != nil {
+			log.Info("failed to resolve the workspace of the session, keeping Task workspace on server", "error", err.Error())
+			return
+		}
+		switch {
+		case inUse[sessionID] && sessionTask == task.Name:
+			log.V(1).Info("keeping Task workspace holding a session used by another task", "session", sessionID)
+			return
+		case !inUse[sessionID] && sessionTask != task.Name:
+			// The workspace of the Task that created the session was kept while the
+			// session was in use
+			if err := r.Get(ctx, types.NamespacedName{Name: sessionTask, Namespace: task.Namespace}, &kubeopenv1alpha1.Task{}); errors.IsNotFound(err) {
+				names = append(names, sessionTask)
+			}
+		}
+	}
+
+	// Deletions are authorized with the Agent's workspace-sync token
+	tokenSecret := &corev1.Secret{}
+	tokenKey := types.NamespacedName{Name: ServerSyncTokenSecretName(agent.Name), Namespace: agent.Namespace}
+	if err := r.Get(ctx, tokenKey, tokenSecret); err != nil {
+		log.Info("failed to get workspace-sync token, not deleting Task workspace on server", "error", err.Error())
+		return
+	}
+	token := string(tokenSecret.Data[ServerSyncTokenKey])
+
+	// The workspace is on the replica the Task was pinned to. If that replica is gone,
+	// try through the Service: a persistent workspace is shared by all replicas.
+	serverURL, err := r.taskServerURL(ctx, task, agent)
+	if err != nil || serverURL == "" {
+		serverURL = ServerURL(agent.Name, agent.Namespace, GetServerPort(agent))
+	}
+	for _, name := range names {
+		deleteServerWorkspace(ctx, ServerTaskWorkspaceURL(serverURL, task.Namespace, name), token)
+	}
+}
+
+// deleteServerWorkspace deletes a Task workspace through the workspace-sync sidecar.
+func deleteServerWorkspace(ctx context.Context, workspaceURL, token string) {
+	log := log.FromContext(ctx)
+
+	reqCtx, cancel := context.WithTimeout(ctx, serverWorkspaceCleanupTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodDelete, workspaceURL, nil)
+	if err != nil {
+		log.Error(err, "failed to build server workspace cleanup request")
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req) //nolint:gosec // URL is the in-cluster Agent Service
+	if err != nil {
+		log.Info("failed to delete Task workspace on server", "url", workspaceURL, "error", err.Error())
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		log.Info("failed to delete Task workspace on server", "url", workspaceURL, "status", resp.StatusCode)
+		return
+	}
+	log.Info("deleted Task workspace on server", "url", workspaceURL)
+}
+
+// taskPodNames returns the names of all Pods created for a Task across its attempts.
+func taskPodNames(task *kubeopenv1alpha1.Task) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, attempt := range task.Status.Attempts {
+		if attempt.PodName != "" && !seen[attempt.PodName] {
+			seen[attempt.PodName] = true
+			names = append(names, attempt.PodName)
+		}
+	}
+	if task.Status.PodName != "" && !seen[task.Status.PodName] {
+		names = append(names, task.Status.PodName)
+	}
+	return names
+}
+
 // processAllContexts processes all contexts from Agent and Task
-// and returns the ConfigMap, file mounts, directory mounts, and git mounts for the Pod.
+// and returns the ConfigMap, file mounts, directory mounts, git mounts, and URL mounts for the Pod.
 //
 // Content order in task.md (top to bottom):
 //  1. Task.description (appears first in task.md)
@@ -813,51 +1597,159 @@ func (r *TaskReconciler) handleTaskDeletion(ctx context.Context, task *kubeopenv
 //
 // The agentNamespace parameter specifies where the Pod runs (and where ConfigMap is created).
 // For cross-namespace Agent references, this differs from task.Namespace.
-func (r *TaskReconciler) processAllContexts(ctx context.Context, task *kubeopenv1alpha1.Task, cfg agentConfig, agentNamespace string) (*corev1.ConfigMap, []fileMount, []dirMount, []gitMount, error) {
-	var resolved []resolvedContext
-	var dirMounts []dirMount
-	var gitMounts []gitMount
-
+func (r *TaskReconciler) processAllContexts(ctx context.Context, task *kubeopenv1alpha1.Task, cfg agentConfig, agentNamespace string) (*corev1.ConfigMap, []fileMount, []dirMount, []gitMount, []urlMount, error) {
 	// 1. Resolve Agent.contexts (appears after description in task.md)
 	// Agent contexts are resolved from Agent's namespace
-	for i, item := range cfg.contexts {
-		rc, dm, gm, err := r.resolveContextItem(ctx, &item, agentNamespace, cfg.workspaceDir)
-		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("failed to resolve Agent context[%d]: %w", i, err)
-		}
-		switch {
-		case dm != nil:
-			dirMounts = append(dirMounts, *dm)
-		case gm != nil:
-			gitMounts = append(gitMounts, *gm)
-		case rc != nil:
-			resolved = append(resolved, *rc)
-		}
+	resolved, dirMounts, gitMounts, urlMounts, err := resolveContextItems(ctx, r, "Agent", cfg.contexts, agentNamespace, cfg.workspaceDir)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
 	}
 
 	// 2. Resolve Task.contexts (appears last in task.md)
 	// Task contexts are resolved from Task's namespace (may differ from Agent namespace)
-	for i, item := range task.Spec.Contexts {
-		rc, dm, gm, err := r.resolveContextItem(ctx, &item, task.Namespace, cfg.workspaceDir)
+	taskResolved, taskDirMounts, taskGitMounts, taskURLMounts, err := resolveContextItems(ctx, r, "Task", task.Spec.Contexts, task.Namespace, cfg.workspaceDir)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	// Server-mode Tasks only deliver their workspace to the server, so their own
+	// contexts must be mounted inside it
+	if cfg.serverConfig != nil {
+		if err := validateServerTaskMounts(cfg.workspaceDir, taskResolved, taskDirMounts, taskGitMounts, taskURLMounts); err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+	}
+	resolved = append(resolved, taskResolved...)
+	if cfg.serverConfig != nil {
+		useServerRuntimePrompt(resolved)
+	}
+	dirMounts = append(dirMounts, taskDirMounts...)
+	gitMounts = append(gitMounts, taskGitMounts...)
+	urlMounts = append(urlMounts, taskURLMounts...)
+	if err := renderGitPushBack(gitMounts, task, cfg); err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	// 3. Handle Task.description (highest priority, becomes ${WORKSPACE_DIR}/task.md)
+	var taskDescription string
+	if task.Spec.Description != nil && *task.Spec.Description != "" {
+		taskDescription = *task.Spec.Description
+	}
+
+	configMapData, fileMounts, err := buildContextFiles(resolved, taskDescription, cfg)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	// Create ConfigMap if there's any content
+	// ConfigMap is created in Agent's namespace (where Pod runs)
+	var configMap *corev1.ConfigMap
+	if len(configMapData) > 0 {
+		configMapName := task.Name + ContextConfigMapSuffix
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapName,
+				Namespace: agentNamespace, // Create in Agent's namespace
+				Labels: map[string]string{
+					"app":                  "kubeopencode",
+					"kubeopencode.io/task": task.Name,
+					TaskNamespaceLabelKey:  task.Namespace, // Track source Task namespace
+				},
+			},
+			Data: configMapData,
+		}
+		// ConfigMap cleanup is handled via finalizer on the Task (same as Pod cleanup).
+		// We don't use OwnerReference to keep cleanup behavior consistent.
+	}
+
+	// Validate mount path conflicts
+	// Multiple contexts mounting to the same path would silently overwrite each other,
+	// so we detect and report conflicts explicitly.
+	if err := validateMountPathConflicts(fileMounts, dirMounts, gitMounts, urlMounts); err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	return configMap, fileMounts, dirMounts, gitMounts, urlMounts, nil
+}
+
+// validateServerTaskMounts checks that the mount paths of a Server-mode Task's contexts
+// are inside its workspace on the server (workspaceDir). Paths outside of it are on
+// the server's file system shared by all Tasks, where workspace-upload cannot stage them.
+func validateServerTaskMounts(workspaceDir string, resolved []resolvedContext, dirMounts []dirMount, gitMounts []gitMount, urlMounts []urlMount) error {
+	var paths []string
+	for _, rc := range resolved {
+		if rc.mountPath != "" {
+			paths = append(paths, rc.mountPath)
+		}
+	}
+	for _, dm := range dirMounts {
+		paths = append(paths, dm.dirPath)
+	}
+	for _, gm := range gitMounts {
+		paths = append(paths, gm.mountPath)
+	}
+	for _, um := range urlMounts {
+		if !um.appendToContext {
+			paths = append(paths, um.targetPath)
+		}
+	}
+	for _, path := range paths {
+		if !isUnderPath(path, workspaceDir) || path == workspaceDir {
+			return fmt.Errorf("mountPath %q of a Server-mode Task context must be a relative path inside the Task workspace", path)
+		}
+	}
+	return nil
+}
+
+// useServerRuntimePrompt replaces the Runtime system prompt of resolved contexts
+// with ServerRuntimeSystemPrompt, for Server-mode Agents.
+func useServerRuntimePrompt(resolved []resolvedContext) {
+	for i := range resolved {
+		if resolved[i].ctxType == string(kubeopenv1alpha1.ContextTypeRuntime) {
+			resolved[i].content = ServerRuntimeSystemPrompt
+		}
+	}
+}
+
+// runtimeSystemPrompt returns the Runtime system prompt for the Agent's mode.
+func runtimeSystemPrompt(cfg agentConfig) string {
+	if cfg.serverConfig != nil {
+		return ServerRuntimeSystemPrompt
+	}
+	return RuntimeSystemPrompt
+}
+
+// resolveContextItems resolves a list of ContextItems from the given namespace.
+// Content contexts are returned in order; directory, git, and URL contexts are
+// returned as mounts. source ("Agent" or "Task") is used in error messages.
+func resolveContextItems(ctx context.Context, c client.Reader, source string, items []kubeopenv1alpha1.ContextItem, namespace, workspaceDir string) ([]resolvedContext, []dirMount, []gitMount, []urlMount, error) {
+	var resolved []resolvedContext
+	var dirMounts []dirMount
+	var gitMounts []gitMount
+	var urlMounts []urlMount
+
+	for i, item := range items {
+		rc, dm, gm, um, err := resolveContextItem(ctx, c, &item, namespace, workspaceDir)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("failed to resolve Task context[%d]: %w", i, err)
+			return nil, nil, nil, nil, fmt.Errorf("failed to resolve %s context[%d]: %w", source, i, err)
 		}
 		switch {
 		case dm != nil:
 			dirMounts = append(dirMounts, *dm)
 		case gm != nil:
 			gitMounts = append(gitMounts, *gm)
+		case um != nil:
+			urlMounts = append(urlMounts, *um)
 		case rc != nil:
 			resolved = append(resolved, *rc)
 		}
 	}
 
-	// 3. Handle Task.description (highest priority, becomes ${WORKSPACE_DIR}/task.md)
-	var taskDescription string
-	if task.Spec.Description != nil && *task.Spec.Description != "" {
-		taskDescription = *task.Spec.Description
-	}
+	return resolved, dirMounts, gitMounts, urlMounts, nil
+}
 
+// buildContextFiles builds the ConfigMap data and file mounts for resolved contexts,
+// the task description (if any), and the OpenCode config (if any).
+func buildContextFiles(resolved []resolvedContext, taskDescription string, cfg agentConfig) (map[string]string, []fileMount, error) {
 	// Build the final content
 	// - Separate contexts with mountPath (independent files)
 	// - Contexts without mountPath are written to .kubeopencode/context.md with XML tags
@@ -915,7 +1807,7 @@ func (r *TaskReconciler) processAllContexts(ctx context.Context, task *kubeopenv
 		// Validate JSON syntax
 		var jsonCheck interface{}
 		if err := json.Unmarshal([]byte(*cfg.config), &jsonCheck); err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("invalid JSON in Agent config: %w", err)
+			return nil, nil, fmt.Errorf("invalid JSON in Agent config: %w", err)
 		}
 		// Use sanitizeConfigMapKey to ensure consistent key naming with fileMount
 		configMapKey := sanitizeConfigMapKey(OpenCodeConfigPath)
@@ -923,40 +1815,13 @@ func (r *TaskReconciler) processAllContexts(ctx context.Context, task *kubeopenv
 		fileMounts = append(fileMounts, fileMount{filePath: OpenCodeConfigPath})
 	}
 
-	// Create ConfigMap if there's any content
-	// ConfigMap is created in Agent's namespace (where Pod runs)
-	var configMap *corev1.ConfigMap
-	if len(configMapData) > 0 {
-		configMapName := task.Name + ContextConfigMapSuffix
-		configMap = &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      configMapName,
-				Namespace: agentNamespace, // Create in Agent's namespace
-				Labels: map[string]string{
-					"app":                  "kubeopencode",
-					"kubeopencode.io/task": task.Name,
-					TaskNamespaceLabelKey:  task.Namespace, // Track source Task namespace
-				},
-			},
-			Data: configMapData,
-		}
-		// ConfigMap cleanup is handled via finalizer on the Task (same as Pod cleanup).
-		// We don't use OwnerReference to keep cleanup behavior consistent.
-	}
-
-	// Validate mount path conflicts
-	// Multiple contexts mounting to the same path would silently overwrite each other,
-	// so we detect and report conflicts explicitly.
-	if err := validateMountPathConflicts(fileMounts, dirMounts, gitMounts); err != nil {
-		return nil, nil, nil, nil, err
-	}
-
-	return configMap, fileMounts, dirMounts, gitMounts, nil
+	return configMapData, fileMounts, nil
 }
 
 // validateMountPathConflicts checks for duplicate mount paths across all mount types.
 // Returns an error if any two mounts target the same path.
-func validateMountPathConflicts(fileMounts []fileMount, dirMounts []dirMount, gitMounts []gitMount) error {
+// URL mounts that append to the context file are excluded, since they share it by design.
+func validateMountPathConflicts(fileMounts []fileMount, dirMounts []dirMount, gitMounts []gitMount, urlMounts []urlMount) error {
 	mountPaths := make(map[string]string) // path -> source description
 
 	for _, fm := range fileMounts {
@@ -980,15 +1845,25 @@ func validateMountPathConflicts(fileMounts []fileMount, dirMounts []dirMount, gi
 		mountPaths[gm.mountPath] = fmt.Sprintf("git mount (%s)", gm.contextName)
 	}
 
+	for _, um := range urlMounts {
+		if um.appendToContext {
+			continue
+		}
+		if existing, ok := mountPaths[um.targetPath]; ok {
+			return fmt.Errorf("mount path conflict: %q is used by both %s and URL mount (%s)", um.targetPath, existing, um.source)
+		}
+		mountPaths[um.targetPath] = fmt.Sprintf("URL mount (%s)", um.source)
+	}
+
 	return nil
 }
 
-// resolveContextItem resolves a ContextItem to its content, directory mount, or git mount.
-func (r *TaskReconciler) resolveContextItem(ctx context.Context, item *kubeopenv1alpha1.ContextItem, defaultNS, workspaceDir string) (*resolvedContext, *dirMount, *gitMount, error) {
+// resolveContextItem resolves a ContextItem to its content, directory mount, git mount, or URL mount.
+func resolveContextItem(ctx context.Context, c client.Reader, item *kubeopenv1alpha1.ContextItem, defaultNS, workspaceDir string) (*resolvedContext, *dirMount, *gitMount, *urlMount, error) {
 	// Validate: Git context requires mountPath to be specified
 	// Without mountPath, multiple Git contexts would conflict with the default "git-context" path.
 	if item.Type == kubeopenv1alpha1.ContextTypeGit && item.MountPath == "" {
-		return nil, nil, nil, fmt.Errorf("git context requires mountPath to be specified")
+		return nil, nil, nil, nil, fmt.Errorf("git context requires mountPath to be specified")
 	}
 
 	// Use a generated name for contexts
@@ -1006,17 +1881,21 @@ func (r *TaskReconciler) resolveContextItem(ctx context.Context, item *kubeopenv
 	}
 
 	// Resolve content based on context type
-	content, dm, gm, err := r.resolveContextContent(ctx, defaultNS, name, workspaceDir, item, resolvedPath)
+	content, dm, gm, um, err := resolveContextContent(ctx, c, defaultNS, name, workspaceDir, item, resolvedPath)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	if dm != nil {
-		return nil, dm, nil, nil
+		return nil, dm, nil, nil, nil
 	}
 
 	if gm != nil {
-		return nil, nil, gm, nil
+		return nil, nil, gm, nil, nil
+	}
+
+	if um != nil {
+		return nil, nil, nil, um, nil
 	}
 
 	return &resolvedContext{
@@ -1026,7 +1905,7 @@ func (r *TaskReconciler) resolveContextItem(ctx context.Context, item *kubeopenv
 		content:   content,
 		mountPath: resolvedPath,
 		fileMode:  item.FileMode,
-	}, nil, nil, nil
+	}, nil, nil, nil, nil
 }
 
 // resolveMountPath converts relative paths to absolute paths based on workspaceDir.
@@ -1044,25 +1923,25 @@ func resolveMountPath(mountPath, workspaceDir string) string {
 }
 
 // resolveContextContent resolves content from a ContextItem.
-// Returns: content string, dirMount pointer, gitMount pointer, error
-func (r *TaskReconciler) resolveContextContent(ctx context.Context, namespace, name, workspaceDir string, item *kubeopenv1alpha1.ContextItem, mountPath string) (string, *dirMount, *gitMount, error) {
+// Returns: content string, dirMount pointer, gitMount pointer, urlMount pointer, error
+func resolveContextContent(ctx context.Context, c client.Reader, namespace, name, workspaceDir string, item *kubeopenv1alpha1.ContextItem, mountPath string) (string, *dirMount, *gitMount, *urlMount, error) {
 	switch item.Type {
 	case kubeopenv1alpha1.ContextTypeText:
 		if item.Text == "" {
-			return "", nil, nil, nil
+			return "", nil, nil, nil, nil
 		}
-		return item.Text, nil, nil, nil
+		return item.Text, nil, nil, nil, nil
 
 	case kubeopenv1alpha1.ContextTypeConfigMap:
 		if item.ConfigMap == nil {
-			return "", nil, nil, nil
+			return "", nil, nil, nil, nil
 		}
 		cm := item.ConfigMap
 
 		// If Key is specified, return the content
 		if cm.Key != "" {
-			content, err := r.getConfigMapKey(ctx, namespace, cm.Name, cm.Key, cm.Optional)
-			return content, nil, nil, err
+			content, err := getConfigMapKey(ctx, c, namespace, cm.Name, cm.Key, cm.Optional)
+			return content, nil, nil, nil, err
 		}
 
 		// If Key is not specified but mountPath is, return a directory mount
@@ -1075,16 +1954,16 @@ func (r *TaskReconciler) resolveContextContent(ctx context.Context, namespace, n
 				dirPath:       mountPath,
 				configMapName: cm.Name,
 				optional:      optional,
-			}, nil, nil
+			}, nil, nil, nil
 		}
 
 		// If Key is not specified and mountPath is empty, aggregate all keys to task.md
-		content, err := r.getConfigMapAllKeys(ctx, namespace, cm.Name, cm.Optional)
-		return content, nil, nil, err
+		content, err := getConfigMapAllKeys(ctx, c, namespace, cm.Name, cm.Optional)
+		return content, nil, nil, nil, err
 
 	case kubeopenv1alpha1.ContextTypeGit:
 		if item.Git == nil {
-			return "", nil, nil, nil
+			return "", nil, nil, nil, nil
 		}
 		git := item.Git
 
@@ -1106,36 +1985,157 @@ func (r *TaskReconciler) resolveContextContent(ctx context.Context, namespace, n
 			ref = "HEAD"
 		}
 
+		if err := validateGitCheckoutOptions(git); err != nil {
+			return "", nil, nil, nil, err
+		}
+
 		// Get secret name if specified
 		secretName := ""
 		if git.SecretRef != nil {
 			secretName = git.SecretRef.Name
 		}
 
+		submodules := ""
+		if git.Submodules != kubeopenv1alpha1.GitSubmodulesNone {
+			submodules = string(git.Submodules)
+		}
+		var lfsInclude []string
+		if git.LFS != nil {
+			lfsInclude = git.LFS.Include
+		}
+
 		return "", nil, &gitMount{
-			contextName: name,
-			repository:  git.Repository,
-			ref:         ref,
-			repoPath:    git.Path,
-			mountPath:   resolvedMountPath,
-			depth:       depth,
-			secretName:  secretName,
-		}, nil
+			contextName:    name,
+			repository:     git.Repository,
+			ref:            ref,
+			baseRef:        git.BaseRef,
+			repoPath:       git.Path,
+			mountPath:      resolvedMountPath,
+			depth:          depth,
+			secretName:     secretName,
+			submodules:     submodules,
+			lfs:            git.LFS != nil,
+			lfsInclude:     lfsInclude,
+			sparseCheckout: git.SparseCheckout,
+			pushBack:       newGitPushBack(git.PushBack),
+		}, nil, nil
 
 	case kubeopenv1alpha1.ContextTypeRuntime:
 		// Runtime context returns the hardcoded system prompt
 		// MountPath is ignored for Runtime context - content is always appended to task.md
-		return RuntimeSystemPrompt, nil, nil, nil
+		return RuntimeSystemPrompt, nil, nil, nil, nil
+
+	case kubeopenv1alpha1.ContextTypeURL:
+		if item.URL == nil {
+			return "", nil, nil, nil, nil
+		}
+		u := item.URL
+
+		if err := validateURLSource(u.Source); err != nil {
+			return "", nil, nil, nil, err
+		}
+
+		// Determine target: use specified path or append to the context file
+		// (fetched by url-fetch, so content can't be embedded in the ConfigMap)
+		targetPath := mountPath
+		appendToContext := false
+		if targetPath == "" {
+			targetPath = workspaceDir + "/" + ContextFileRelPath
+			appendToContext = true
+		}
+
+		// Determine timeout: default to 30 seconds
+		timeout := DefaultURLTimeout
+		if u.Timeout != nil && *u.Timeout > 0 {
+			timeout = *u.Timeout
+		}
+
+		// Get secret name if specified
+		secretName := ""
+		if u.SecretRef != nil {
+			secretName = u.SecretRef.Name
+		}
+
+		return "", nil, nil, &urlMount{
+			contextName:     name,
+			namespace:       namespace,
+			source:          u.Source,
+			headers:         u.Headers,
+			secretName:      secretName,
+			insecure:        u.InsecureSkipTLSVerify,
+			timeout:         timeout,
+			targetPath:      targetPath,
+			appendToContext: appendToContext,
+		}, nil
 
 	default:
-		return "", nil, nil, fmt.Errorf("unknown context type: %s", item.Type)
+		return "", nil, nil, nil, fmt.Errorf("unknown context type: %s", item.Type)
 	}
 }
 
+// validateURLSource checks that a URL context source is an absolute HTTP or HTTPS URL.
+func validateURLSource(source string) error {
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("invalid URL context source %q: %w", source, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid URL context source %q: must be an HTTP or HTTPS URL", source)
+	}
+	return nil
+}
+
+// validateGitCheckoutOptions checks the submodules, LFS and sparse checkout options of
+// a Git context. Sparse checkout directories must be relative paths within the
+// repository (no patterns), and Path must lie within one of them or at the root of the
+// repository, which is always checked out.
+func validateGitCheckoutOptions(git *kubeopenv1alpha1.GitContext) error {
+	switch git.Submodules {
+	case "", kubeopenv1alpha1.GitSubmodulesNone, kubeopenv1alpha1.GitSubmodulesShallow, kubeopenv1alpha1.GitSubmodulesRecursive:
+	default:
+		return fmt.Errorf("invalid Git context submodules %q: must be none, shallow or recursive", git.Submodules)
+	}
+
+	if git.LFS != nil {
+		for _, pattern := range git.LFS.Include {
+			// git-init passes the patterns to git lfs as a comma-separated list
+			if strings.TrimSpace(pattern) == "" || strings.Contains(pattern, ",") {
+				return fmt.Errorf("invalid Git context LFS include pattern %q: must be non-empty and must not contain commas", pattern)
+			}
+		}
+	}
+
+	if len(git.SparseCheckout) == 0 {
+		return nil
+	}
+	for _, dir := range git.SparseCheckout {
+		cleaned := filepath.Clean(dir)
+		if strings.TrimSpace(dir) == "" || cleaned == "." || filepath.IsAbs(dir) ||
+			cleaned == ".." || strings.HasPrefix(cleaned, "../") ||
+			strings.HasPrefix(dir, "!") || strings.ContainsAny(dir, "*?[\\\n") {
+			return fmt.Errorf("invalid Git context sparse checkout directory %q: must be a relative directory path within the repository", dir)
+		}
+	}
+	if git.Path == "" {
+		return nil
+	}
+	repoPath := strings.Trim(filepath.Clean(git.Path), "/")
+	if !strings.Contains(repoPath, "/") {
+		return nil
+	}
+	for _, dir := range git.SparseCheckout {
+		cleaned := filepath.Clean(dir)
+		if repoPath == cleaned || strings.HasPrefix(repoPath, cleaned+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("git context path %q is not within the sparse checkout directories %v", git.Path, git.SparseCheckout)
+}
+
 // getConfigMapKey retrieves a specific key from a ConfigMap
-func (r *TaskReconciler) getConfigMapKey(ctx context.Context, namespace, name, key string, optional *bool) (string, error) {
+func getConfigMapKey(ctx context.Context, c client.Reader, namespace, name, key string, optional *bool) (string, error) {
 	cm := &corev1.ConfigMap{}
-	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, cm); err != nil {
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, cm); err != nil {
 		if optional != nil && *optional {
 			return "", nil
 		}
@@ -1151,9 +2151,9 @@ func (r *TaskReconciler) getConfigMapKey(ctx context.Context, namespace, name, k
 }
 
 // getConfigMapAllKeys retrieves all keys from a ConfigMap and formats them for aggregation
-func (r *TaskReconciler) getConfigMapAllKeys(ctx context.Context, namespace, name string, optional *bool) (string, error) {
+func getConfigMapAllKeys(ctx context.Context, c client.Reader, namespace, name string, optional *bool) (string, error) {
 	cm := &corev1.ConfigMap{}
-	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, cm); err != nil {
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, cm); err != nil {
 		if optional != nil && *optional {
 			return "", nil
 		}
@@ -1178,41 +2178,71 @@ func (r *TaskReconciler) getConfigMapAllKeys(ctx context.Context, namespace, nam
 	return strings.Join(parts, "\n"), nil
 }
 
-// checkAgentCapacity checks if the agent has capacity for a new task.
-// Returns true if capacity is available, false if at limit.
-func (r *TaskReconciler) checkAgentCapacity(ctx context.Context, namespace, agentName string, maxConcurrent int32) (bool, error) {
+// checkAgentCapacity checks if the agent has capacity for the given task.
+// Running (and just admitted) Tasks occupy slots; free slots go to Queued Tasks in
+// priority-then-FIFO order (see queuedBefore), so a Task is only admitted if fewer
+// Queued Tasks are ahead of it than there are free slots. This keeps new Tasks from
+// jumping the queue. Accounting is kept in memory by taskAdmission.
+// With a fairShare, a Task whose namespace holds its share of slots waits for one of
+// them to free up, without blocking Tasks from other namespaces.
+// Returns whether capacity is available, and otherwise the Task's 1-based queue position.
+func (r *TaskReconciler) checkAgentCapacity(ctx context.Context, task *kubeopenv1alpha1.Task, namespace, agentName string, limits admissionLimits) admissionCheck {
 	log := log.FromContext(ctx)
 
-	// List all Tasks for this Agent using label selector
-	taskList := &kubeopenv1alpha1.TaskList{}
-	listOpts := []client.ListOption{
-		client.InNamespace(namespace),
-		client.MatchingLabels{AgentLabelKey: agentName},
-	}
+	agent := types.NamespacedName{Namespace: namespace, Name: agentName}
+	check := r.admission.check(task, agent, limits)
 
-	if err := r.List(ctx, taskList, listOpts...); err != nil {
-		return false, err
-	}
+	log.V(1).Info("agent capacity check", "agent", agentName, "holding", check.holding, "position", check.position,
+		"max", limits.maxConcurrent, "namespaceMax", limits.namespaceMax(task.Namespace))
 
-	// Count running tasks (those with Jobs created and in progress)
-	runningCount := int32(0)
-	for i := range taskList.Items {
-		task := &taskList.Items[i]
-		// Count tasks that are Running
-		if task.Status.Phase == kubeopenv1alpha1.TaskPhaseRunning {
-			runningCount++
-		}
+	return check
+}
+
+// queuedBefore reports whether Task a precedes Task b in an Agent's admission queue:
+// higher spec.priority first, then older creationTimestamp, then name as a tie-breaker.
+func queuedBefore(a, b *kubeopenv1alpha1.Task) bool {
+	if a.Spec.Priority != b.Spec.Priority {
+		return a.Spec.Priority > b.Spec.Priority
 	}
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
 
-	log.V(1).Info("agent capacity check", "agent", agentName, "running", runningCount, "max", maxConcurrent)
+// isAdmittedFromQueue reports whether a Task was just admitted from the queue and is
+// initializing (phase reset to empty) but not yet Running. Such Tasks hold a slot.
+func isAdmittedFromQueue(task *kubeopenv1alpha1.Task) bool {
+	if task.Status.Phase != "" {
+		return false
+	}
+	cond := meta.FindStatusCondition(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+	return cond != nil && cond.Status == metav1.ConditionFalse && cond.Reason == kubeopenv1alpha1.ReasonCapacityAvailable
+}
 
-	return runningCount < maxConcurrent, nil
+// capacityQueuedMessage describes a Task waiting for Agent capacity.
+func capacityQueuedMessage(agentName string, maxConcurrent, position int32) string {
+	return fmt.Sprintf("Waiting for agent %q capacity (max: %d, queue position: %d)", agentName, maxConcurrent, position)
 }
 
 // handleQueuedTask checks if a queued task can now be started
 func (r *TaskReconciler) handleQueuedTask(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
+	// Follow-up Tasks wait for the Task their sessionRef continues before taking a slot
+	if cond := meta.FindStatusCondition(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued); task.Spec.SessionRef != nil &&
+		cond != nil && cond.Status == metav1.ConditionTrue && cond.Reason == kubeopenv1alpha1.ReasonWaitingForSession {
+		return r.handleWaitingForSession(ctx, task)
+	}
+
+	// Tasks of Server-mode Agents wait for the server to be ready
+	if isWaitingForServer(task) && task.Status.AgentRef != nil {
+		return r.handleWaitingForServer(ctx, task)
+	}
+
 	// Get agent configuration with name and namespace
 	agentConfig, agentName, agentNamespace, err := r.getAgentConfigWithName(ctx, task)
 	if err != nil {
@@ -1234,14 +2264,17 @@ func (r *TaskReconciler) handleQueuedTask(ctx context.Context, task *kubeopenv1a
 		return ctrl.Result{}, nil
 	}
 
-	// Check if agent still has MaxConcurrentTasks set
-	hasCapacityLimit := agentConfig.maxConcurrentTasks != nil && *agentConfig.maxConcurrentTasks > 0
-	hasQuotaLimit := agentConfig.quota != nil
+	// Check if agent still has MaxConcurrentTasks, quota or a fair share set
+	limits := agentAdmissionLimits(agentConfig)
+	nsQuota := namespaceQuota(agentConfig, task.Namespace)
+	hasCapacityLimit := limits.enabled(task.Namespace)
+	hasQuotaLimit := agentConfig.quota != nil || nsQuota != nil
 
 	// If neither limit is configured, proceed to initialize
 	if !hasCapacityLimit && !hasQuotaLimit {
 		log.Info("no limits configured, proceeding with task", "agent", agentName)
 		task.Status.Phase = ""
+		task.Status.QueuePosition = 0
 		meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
 			Type:    kubeopenv1alpha1.ConditionTypeQueued,
 			Status:  metav1.ConditionFalse,
@@ -1256,21 +2289,32 @@ func (r *TaskReconciler) handleQueuedTask(ctx context.Context, task *kubeopenv1a
 
 	// Check capacity if limit is set
 	if hasCapacityLimit {
-		hasCapacity, err := r.checkAgentCapacity(ctx, agentNamespace, agentName, *agentConfig.maxConcurrentTasks)
-		if err != nil {
-			log.Error(err, "unable to check agent capacity")
-			return ctrl.Result{}, err
-		}
-
-		if !hasCapacity {
-			// Still at capacity, requeue
-			log.V(1).Info("agent still at capacity, remaining queued", "agent", agentName)
+		check := r.checkAgentCapacity(ctx, task, agentNamespace, agentName, limits)
+		if !check.admitted {
+			// Still at capacity, publish the current queue position and requeue
+			log.V(1).Info("agent still at capacity, remaining queued", "agent", agentName,
+				"namespaceLimited", check.namespaceLimited, "position", check.position)
+			reason, message := capacityQueuedCondition(agentName, task.Namespace, limits, check)
+			if cond := meta.FindStatusCondition(task.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued); task.Status.QueuePosition != check.position ||
+				cond == nil || cond.Reason != reason || cond.Message != message {
+				task.Status.QueuePosition = check.position
+				meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
+					Type:    kubeopenv1alpha1.ConditionTypeQueued,
+					Status:  metav1.ConditionTrue,
+					Reason:  reason,
+					Message: message,
+				})
+				if err := r.Status().Update(ctx, task); err != nil {
+					log.Error(err, "unable to update queued task status")
+					return ctrl.Result{}, err
+				}
+			}
 			return ctrl.Result{RequeueAfter: DefaultQueuedRequeueDelay}, nil
 		}
 	}
 
 	// Check agent quota if configured
-	if agentConfig.quota != nil {
+	if hasQuotaLimit {
 		agent, err := r.getAgentForQuota(ctx, agentName, agentNamespace)
 		if err != nil {
 			log.Error(err, "unable to get Agent for quota check")
@@ -1282,13 +2326,19 @@ func (r *TaskReconciler) handleQueuedTask(ctx context.Context, task *kubeopenv1a
 			log.Error(err, "unable to check agent quota")
 			return ctrl.Result{}, err
 		}
+		reason, message := "", ""
+		if !hasQuota {
+			reason = kubeopenv1alpha1.ReasonQuotaExceeded
+			message = fmt.Sprintf("Waiting for agent %q quota (max: %d per %ds)",
+				agentName, agentConfig.quota.MaxTaskStarts, agentConfig.quota.WindowSeconds)
+		} else if hasQuota, requeueDelay = checkNamespaceQuota(agent, task.Namespace, nsQuota); !hasQuota {
+			reason = kubeopenv1alpha1.ReasonNamespaceQuotaExceeded
+			message = namespaceQuotaQueuedMessage(agentName, task.Namespace, nsQuota)
+		}
 
 		if !hasQuota {
 			// Quota still exceeded, update condition and requeue
-			log.V(1).Info("agent quota still exceeded, remaining queued",
-				"agent", agentName,
-				"maxTaskStarts", agentConfig.quota.MaxTaskStarts,
-				"windowSeconds", agentConfig.quota.WindowSeconds)
+			log.V(1).Info("agent quota still exceeded, remaining queued", "agent", agentName, "reason", reason)
 
 			// Ensure AgentRef is set (may be missing from older tasks)
 			if task.Status.AgentRef == nil {
@@ -1299,11 +2349,10 @@ func (r *TaskReconciler) handleQueuedTask(ctx context.Context, task *kubeopenv1a
 			}
 
 			meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
-				Type:   kubeopenv1alpha1.ConditionTypeQueued,
-				Status: metav1.ConditionTrue,
-				Reason: kubeopenv1alpha1.ReasonQuotaExceeded,
-				Message: fmt.Sprintf("Waiting for agent %q quota (max: %d per %ds)",
-					agentName, agentConfig.quota.MaxTaskStarts, agentConfig.quota.WindowSeconds),
+				Type:    kubeopenv1alpha1.ConditionTypeQueued,
+				Status:  metav1.ConditionTrue,
+				Reason:  reason,
+				Message: message,
 			})
 
 			if err := r.Status().Update(ctx, task); err != nil {
@@ -1317,7 +2366,16 @@ func (r *TaskReconciler) handleQueuedTask(ctx context.Context, task *kubeopenv1a
 
 	// Capacity available, transition to empty phase to trigger initializeTask
 	log.Info("agent capacity available, transitioning to initialize", "agent", agentName)
+	if hasCapacityLimit {
+		// Hold the slot so other Queued Tasks are not admitted before this one is Running
+		agentKey := types.NamespacedName{Namespace: agentNamespace, Name: agentName}
+		if !r.admission.tryReserve(task, agentKey, limits) {
+			log.V(1).Info("agent slot taken by another task, remaining queued", "agent", agentName)
+			return ctrl.Result{RequeueAfter: DefaultQueuedRequeueDelay}, nil
+		}
+	}
 	task.Status.Phase = ""
+	task.Status.QueuePosition = 0
 	meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
 		Type:    kubeopenv1alpha1.ConditionTypeQueued,
 		Status:  metav1.ConditionFalse,
@@ -1358,11 +2416,18 @@ func (r *TaskReconciler) handleStop(ctx context.Context, task *kubeopenv1alpha1.
 		}
 	}
 
+	// Delete the OpenCode session of Server-mode Tasks. On failure the session ID
+	// stays in the status and the cleanup is retried once the Task is Completed.
+	if err := r.deleteServerSessions(ctx, task); err != nil {
+		log.Info("failed to delete OpenCode session of stopped task, will retry", "session", task.Status.SessionID, "error", err.Error())
+	}
+
 	// Update Task status to Completed with Stopped condition
 	task.Status.Phase = kubeopenv1alpha1.TaskPhaseCompleted
 	task.Status.ObservedGeneration = task.Generation
 	now := metav1.Now()
 	task.Status.CompletionTime = &now
+	endCurrentAttempt(task, now, nil, kubeopenv1alpha1.ReasonUserStopped, "Task stopped by user")
 
 	meta.SetStatusCondition(&task.Status.Conditions, metav1.Condition{
 		Type:    kubeopenv1alpha1.ConditionTypeStopped,
@@ -1380,9 +2445,9 @@ func (r *TaskReconciler) handleStop(ctx context.Context, task *kubeopenv1alpha1.
 }
 
 // getSystemConfig retrieves the system configuration from KubeOpenCodeConfig.
-// It looks for config in KubeOpenCodeConfig named "default" in the task's namespace.
-// Returns a systemConfig with defaults if no config is found.
-func (r *TaskReconciler) getSystemConfig(ctx context.Context, namespace string) systemConfig {
+// It looks for config in KubeOpenCodeConfig named "default" in the given namespace
+// (where the agent Pods run). Returns a systemConfig with defaults if no config is found.
+func getSystemConfig(ctx context.Context, c client.Reader, namespace string) systemConfig {
 	log := log.FromContext(ctx)
 
 	// Default configuration
@@ -1395,7 +2460,7 @@ func (r *TaskReconciler) getSystemConfig(ctx context.Context, namespace string)
 	config := &kubeopenv1alpha1.KubeOpenCodeConfig{}
 	configKey := types.NamespacedName{Name: "default", Namespace: namespace}
 
-	if err := r.Get(ctx, configKey, config); err != nil {
+	if err := c.Get(ctx, configKey, config); err != nil {
 		if !errors.IsNotFound(err) {
 			log.Error(err, "unable to get KubeOpenCodeConfig for system config, using defaults")
 		}
@@ -1412,6 +2477,7 @@ func (r *TaskReconciler) getSystemConfig(ctx context.Context, namespace string)
 			cfg.systemImagePullPolicy = config.Spec.SystemImage.ImagePullPolicy
 		}
 	}
+	cfg.gitCache = newGitCacheConfig(config.Spec.GitCache)
 
 	return cfg
 }
@@ -1422,6 +2488,11 @@ func (r *TaskReconciler) getSystemConfig(ctx context.Context, namespace string)
 func (r *TaskReconciler) handleTaskCleanup(ctx context.Context, task *kubeopenv1alpha1.Task) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
+	// Finish deleting the OpenCode session of a stopped Server-mode Task
+	if result, err := r.retryStoppedSessionCleanup(ctx, task); err != nil || result.RequeueAfter > 0 {
+		return result, err
+	}
+
 	// Get cleanup configuration from KubeOpenCodeConfig
 	cleanupConfig := r.getCleanupConfig(ctx, task.Namespace)
 	if cleanupConfig == nil {
@@ -1665,7 +2736,7 @@ func (r *TaskReconciler) checkAgentQuota(ctx context.Context, agent *kubeopenv1a
 func (r *TaskReconciler) recordTaskStart(ctx context.Context, agent *kubeopenv1alpha1.Agent, task *kubeopenv1alpha1.Task) error {
 	log := log.FromContext(ctx)
 
-	if agent.Spec.Quota == nil {
+	if quotaHistoryWindow(agent) == 0 {
 		return nil
 	}
 
@@ -1678,15 +2749,13 @@ func (r *TaskReconciler) recordTaskStart(ctx context.Context, agent *kubeopenv1a
 		}
 
 		// Check if quota is still configured (could be removed between retries)
-		if freshAgent.Spec.Quota == nil {
+		window := quotaHistoryWindow(freshAgent)
+		if window == 0 {
 			return nil
 		}
 
-		// Prune old records and add new one
-		freshAgent.Status.TaskStartHistory = pruneTaskStartHistory(
-			freshAgent.Status.TaskStartHistory,
-			freshAgent.Spec.Quota.WindowSeconds,
-		)
+		// Prune old records (kept for the longest quota window) and add new one
+		freshAgent.Status.TaskStartHistory = pruneTaskStartHistory(freshAgent.Status.TaskStartHistory, window)
 		freshAgent.Status.TaskStartHistory = append(freshAgent.Status.TaskStartHistory, kubeopenv1alpha1.TaskStartRecord{
 			TaskName:      task.Name,
 			TaskNamespace: task.Namespace,
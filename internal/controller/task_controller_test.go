@@ -16,6 +16,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -1007,6 +1008,171 @@ var _ = Describe("TaskController", func() {
 			Expect(k8sClient.Delete(ctx, task2)).Should(Succeed())
 			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
 		})
+
+		It("Should admit Queued Tasks in priority order and publish queue positions", func() {
+			agentName := "test-agent-priority"
+			maxConcurrent := int32(1)
+			description := "# Priority task"
+			// Queued Tasks re-check capacity every DefaultQueuedRequeueDelay
+			queueTimeout := timeout + DefaultQueuedRequeueDelay
+
+			By("Creating Agent with maxConcurrentTasks=1")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServiceAccountName: "test-agent",
+					WorkspaceDir:       "/workspace",
+					MaxConcurrentTasks: &maxConcurrent,
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			newTask := func(name string, priority int32) *kubeopenv1alpha1.Task {
+				return &kubeopenv1alpha1.Task{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: taskNamespace,
+					},
+					Spec: kubeopenv1alpha1.TaskSpec{
+						AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+						Description: &description,
+						Priority:    priority,
+					},
+				}
+			}
+			getTask := func(name string) *kubeopenv1alpha1.Task {
+				task := &kubeopenv1alpha1.Task{}
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: taskNamespace}, task); err != nil {
+					return &kubeopenv1alpha1.Task{}
+				}
+				return task
+			}
+
+			By("Creating a Task that occupies the only slot")
+			running := newTask("test-task-priority-running", 0)
+			Expect(k8sClient.Create(ctx, running)).Should(Succeed())
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				return getTask(running.Name).Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Creating a low-priority Task, then a high-priority Task")
+			low := newTask("test-task-priority-low", 0)
+			Expect(k8sClient.Create(ctx, low)).Should(Succeed())
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				return getTask(low.Name).Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseQueued))
+
+			high := newTask("test-task-priority-high", 10)
+			Expect(k8sClient.Create(ctx, high)).Should(Succeed())
+			Eventually(func() int32 {
+				return getTask(high.Name).Status.QueuePosition
+			}, timeout, interval).Should(Equal(int32(1)))
+			Expect(getTask(high.Name).Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseQueued))
+
+			By("Checking the low-priority Task moves behind the high-priority Task")
+			Eventually(func() int32 {
+				return getTask(low.Name).Status.QueuePosition
+			}, queueTimeout, interval).Should(Equal(int32(2)))
+
+			By("Completing the running Task")
+			pod := &corev1.Pod{}
+			podLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", running.Name), Namespace: taskNamespace}
+			Expect(k8sClient.Get(ctx, podLookupKey, pod)).Should(Succeed())
+			pod.Status.Phase = corev1.PodSucceeded
+			Expect(k8sClient.Status().Update(ctx, pod)).Should(Succeed())
+
+			By("Checking the high-priority Task is admitted first")
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				return getTask(high.Name).Status.Phase
+			}, queueTimeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+			Expect(getTask(high.Name).Status.QueuePosition).Should(BeZero())
+			Expect(getTask(low.Name).Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseQueued))
+
+			Eventually(func() int32 {
+				return getTask(low.Name).Status.QueuePosition
+			}, queueTimeout, interval).Should(Equal(int32(1)))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, running)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, low)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, high)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+
+		It("Should admit the next Queued Task as soon as a slot frees up", func() {
+			agentName := "test-agent-dequeue"
+			maxConcurrent := int32(1)
+			description := "# Dequeue task"
+
+			By("Creating Agent with maxConcurrentTasks=1")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServiceAccountName: "test-agent",
+					WorkspaceDir:       "/workspace",
+					MaxConcurrentTasks: &maxConcurrent,
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			getPhase := func(name string) kubeopenv1alpha1.TaskPhase {
+				task := &kubeopenv1alpha1.Task{}
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: taskNamespace}, task); err != nil {
+					return ""
+				}
+				return task.Status.Phase
+			}
+
+			By("Creating a running Task and a queued Task")
+			first := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-task-dequeue-1", Namespace: taskNamespace},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, first)).Should(Succeed())
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				return getPhase(first.Name)
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			second := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-task-dequeue-2", Namespace: taskNamespace},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, second)).Should(Succeed())
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				return getPhase(second.Name)
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseQueued))
+
+			By("Stopping the running Task")
+			stopped := &kubeopenv1alpha1.Task{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: first.Name, Namespace: taskNamespace}, stopped)).Should(Succeed())
+			if stopped.Annotations == nil {
+				stopped.Annotations = map[string]string{}
+			}
+			stopped.Annotations[AnnotationStop] = "true"
+			Expect(k8sClient.Update(ctx, stopped)).Should(Succeed())
+
+			By("Checking the queued Task is admitted without waiting for the periodic requeue")
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				return getPhase(second.Name)
+			}, DefaultQueuedRequeueDelay/2, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, first)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, second)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
 	})
 
 	Context("When stopping a Running Task via annotation", func() {
@@ -2352,6 +2518,101 @@ var _ = Describe("TaskController", func() {
 		})
 	})
 
+	Context("When Agent has fairShare configured", func() {
+		It("Should queue Tasks over their namespace share without blocking other namespaces", func() {
+			agentName := "test-agent-fair-share"
+			otherNamespace := "team-beta"
+			maxConcurrent := int32(2)
+			perNamespace := int32(1)
+			description := "Test fair share"
+
+			By("Creating test namespace for the second tenant")
+			otherNs := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: otherNamespace,
+				},
+			}
+			err := k8sClient.Create(ctx, otherNs)
+			if err != nil && !apierrors.IsAlreadyExists(err) {
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			By("Creating Agent with maxConcurrentTasks=2 and maxConcurrentTasksPerNamespace=1")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServiceAccountName: "test-agent",
+					WorkspaceDir:       "/workspace",
+					MaxConcurrentTasks: &maxConcurrent,
+					FairShare: &kubeopenv1alpha1.FairShareConfig{
+						MaxConcurrentTasksPerNamespace: &perNamespace,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			newTask := func(name, namespace string) *kubeopenv1alpha1.Task {
+				return &kubeopenv1alpha1.Task{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: namespace,
+					},
+					Spec: kubeopenv1alpha1.TaskSpec{
+						AgentRef: &kubeopenv1alpha1.AgentReference{
+							Name:      agentName,
+							Namespace: taskNamespace,
+						},
+						Description: &description,
+					},
+				}
+			}
+			taskPhase := func(task *kubeopenv1alpha1.Task) func() kubeopenv1alpha1.TaskPhase {
+				return func() kubeopenv1alpha1.TaskPhase {
+					updatedTask := &kubeopenv1alpha1.Task{}
+					if err := k8sClient.Get(ctx, types.NamespacedName{Name: task.Name, Namespace: task.Namespace}, updatedTask); err != nil {
+						return ""
+					}
+					return updatedTask.Status.Phase
+				}
+			}
+
+			By("Creating first Task in the Agent's namespace")
+			task1 := newTask("test-task-fair-share-1", taskNamespace)
+			Expect(k8sClient.Create(ctx, task1)).Should(Succeed())
+			Eventually(taskPhase(task1), timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Creating second Task in the same namespace")
+			task2 := newTask("test-task-fair-share-2", taskNamespace)
+			Expect(k8sClient.Create(ctx, task2)).Should(Succeed())
+			Eventually(taskPhase(task2), timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseQueued))
+
+			By("Verifying second Task is queued due to its namespace share")
+			task2Updated := &kubeopenv1alpha1.Task{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: task2.Name, Namespace: task2.Namespace}, task2Updated)).Should(Succeed())
+			queuedCondition := meta.FindStatusCondition(task2Updated.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+			Expect(queuedCondition).ShouldNot(BeNil())
+			Expect(queuedCondition.Reason).Should(Equal(kubeopenv1alpha1.ReasonNamespaceAtCapacity))
+			Expect(queuedCondition.Message).Should(ContainSubstring(taskNamespace))
+
+			By("Creating a Task in another namespace, which takes the remaining slot")
+			task3 := newTask("test-task-fair-share-3", otherNamespace)
+			Expect(k8sClient.Create(ctx, task3)).Should(Succeed())
+			Eventually(taskPhase(task3), timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Deleting first Task to free the namespace share")
+			Expect(k8sClient.Delete(ctx, task1)).Should(Succeed())
+			Eventually(taskPhase(task2), timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task2)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, task3)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+	})
+
 	Context("Cross-namespace Agent with AllowedNamespaces", func() {
 		It("Should allow Task when allowedNamespaces is empty (default: all allowed)", func() {
 			agentName := "test-agent-cross-ns-empty"
@@ -2588,6 +2849,104 @@ var _ = Describe("TaskController", func() {
 			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
 			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
 		})
+		It("Should count Tasks from all namespaces against a shared Agent's maxConcurrentTasks", func() {
+			agentName := "test-agent-shared-capacity"
+			agentNamespace := "default"
+			description := "Test shared Agent capacity"
+			maxConcurrent := int32(1)
+
+			By("Creating test namespace for the second tenant")
+			teamNs := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "team-beta",
+				},
+			}
+			err := k8sClient.Create(ctx, teamNs)
+			if err != nil && !apierrors.IsAlreadyExists(err) {
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			By("Creating shared Agent with maxConcurrentTasks=1")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: agentNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServiceAccountName: "test-agent",
+					WorkspaceDir:       "/workspace",
+					MaxConcurrentTasks: &maxConcurrent,
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			By("Creating first Task in the Agent's namespace")
+			task1 := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-task-shared-capacity-1",
+					Namespace: agentNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef: &kubeopenv1alpha1.AgentReference{
+						Name:      agentName,
+						Namespace: agentNamespace,
+					},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task1)).Should(Succeed())
+
+			task1LookupKey := types.NamespacedName{Name: task1.Name, Namespace: agentNamespace}
+			createdTask1 := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, task1LookupKey, createdTask1); err != nil {
+					return ""
+				}
+				return createdTask1.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Creating second Task in another namespace referencing the same Agent")
+			task2 := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-task-shared-capacity-2",
+					Namespace: "team-beta",
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef: &kubeopenv1alpha1.AgentReference{
+						Name:      agentName,
+						Namespace: agentNamespace,
+					},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task2)).Should(Succeed())
+
+			By("Checking second Task is Queued behind the first")
+			task2LookupKey := types.NamespacedName{Name: task2.Name, Namespace: "team-beta"}
+			createdTask2 := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, task2LookupKey, createdTask2); err != nil {
+					return ""
+				}
+				return createdTask2.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseQueued))
+			Expect(createdTask2.Status.QueuePosition).Should(Equal(int32(1)))
+
+			By("Deleting first Task to free the slot")
+			Expect(k8sClient.Delete(ctx, task1)).Should(Succeed())
+
+			By("Checking second Task is admitted")
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, task2LookupKey, createdTask2); err != nil {
+					return ""
+				}
+				return createdTask2.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task2)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
 	})
 
 	Context("Custom agent command", func() {
@@ -3066,23 +3425,13 @@ var _ = Describe("TaskController", func() {
 		})
 	})
 
-	// NOTE: URL Context type is defined in the API (ContextTypeURL) but not yet implemented
-	// in the controller. This test is marked as Pending until the feature is implemented.
 	Context("URL Context", func() {
-		PIt("Should create url-fetch init container for URL context (TODO: implement URL context type)", func() {
-			// This test verifies URL context type creates a url-fetch init container.
-			// Currently returns "unknown context type: URL" error.
-		})
-	})
-
-	Context("Git Context configuration", func() {
-		It("Should create git-init container with correct arguments", func() {
-			taskName := "test-task-git-context"
-			description := "Test Git context"
-			mountPath := "/workspace/source"
+		It("Should create url-fetch init container for URL context", func() {
+			taskName := "test-task-url-context"
+			description := "Test URL context"
+			timeoutSeconds := int32(60)
 
-			By("Creating Task with Git context")
-			depth := 1
+			By("Creating Task with URL context")
 			task := &kubeopenv1alpha1.Task{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      taskName,
@@ -3093,14 +3442,14 @@ var _ = Describe("TaskController", func() {
 					Description: &description,
 					Contexts: []kubeopenv1alpha1.ContextItem{
 						{
-							Name: "source-code",
-							Type: kubeopenv1alpha1.ContextTypeGit,
-							Git: &kubeopenv1alpha1.GitContext{
-								Repository: "https://github.com/example/repo.git",
-								Ref:        "main",
-								Depth:      &depth,
+							Name: "api-spec",
+							Type: kubeopenv1alpha1.ContextTypeURL,
+							URL: &kubeopenv1alpha1.URLContext{
+								Source:    "https://api.example.com/openapi.yaml",
+								SecretRef: &kubeopenv1alpha1.URLSecretReference{Name: "api-credentials"},
+								Timeout:   &timeoutSeconds,
 							},
-							MountPath: mountPath,
+							MountPath: "specs/openapi.yaml",
 						},
 					},
 				},
@@ -3117,7 +3466,7 @@ var _ = Describe("TaskController", func() {
 				return createdTask.Status.Phase
 			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
 
-			By("Checking Pod has git-init-0 container")
+			By("Checking Pod has url-fetch-0 container")
 			podName := fmt.Sprintf("%s-pod", taskName)
 			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
 			createdPod := &corev1.Pod{}
@@ -3125,67 +3474,1800 @@ var _ = Describe("TaskController", func() {
 				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
 			}, timeout, interval).Should(BeTrue())
 
-			// Git init containers are named git-init-0, git-init-1, etc.
-			var foundGitInit bool
-			for _, initC := range createdPod.Spec.InitContainers {
-				if initC.Name == "git-init-0" {
-					foundGitInit = true
-					// Verify git-init has correct environment variables
-					var foundRepoUrl, foundRef bool
-					for _, env := range initC.Env {
-						if env.Name == "GIT_REPO" && env.Value == "https://github.com/example/repo.git" {
-							foundRepoUrl = true
-						}
-						if env.Name == "GIT_REF" && env.Value == "main" {
-							foundRef = true
-						}
-					}
-					Expect(foundRepoUrl).Should(BeTrue(), "Expected GIT_REPO env var")
-					Expect(foundRef).Should(BeTrue(), "Expected GIT_REF env var")
+			// URL fetch containers are named url-fetch-0, url-fetch-1, etc.
+			var urlFetch *corev1.Container
+			for i := range createdPod.Spec.InitContainers {
+				if createdPod.Spec.InitContainers[i].Name == "url-fetch-0" {
+					urlFetch = &createdPod.Spec.InitContainers[i]
 					break
 				}
 			}
-			Expect(foundGitInit).Should(BeTrue(), "Expected git-init-0 container")
+			Expect(urlFetch).ShouldNot(BeNil(), "Expected url-fetch-0 container")
+
+			envValues := make(map[string]string)
+			secretKeys := make(map[string]string)
+			for _, env := range urlFetch.Env {
+				envValues[env.Name] = env.Value
+				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+					Expect(env.ValueFrom.SecretKeyRef.Name).Should(Equal("api-credentials"))
+					secretKeys[env.Name] = env.ValueFrom.SecretKeyRef.Key
+				}
+			}
+			Expect(envValues["URL_SOURCE"]).Should(Equal("https://api.example.com/openapi.yaml"))
+			Expect(envValues["URL_TARGET"]).Should(Equal("/workspace/specs/openapi.yaml"))
+			Expect(envValues["URL_TIMEOUT"]).Should(Equal("60"))
+			Expect(envValues).ShouldNot(HaveKey("URL_APPEND"))
+			Expect(secretKeys).Should(Equal(map[string]string{
+				"URL_AUTH_TOKEN":    "token",
+				"URL_AUTH_USERNAME": "username",
+				"URL_AUTH_PASSWORD": "password",
+			}))
 
 			By("Cleaning up")
 			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
 		})
-	})
 
-	Context("Server-mode Task execution", func() {
-		It("Should create Pod with --attach flag pointing to server URL", func() {
-			agentName := "test-server-agent-task"
-			taskName := "test-task-server-mode"
+		It("Should fetch URL context without mountPath into the context file", func() {
+			taskName := "test-task-url-context-file"
+			description := "Test URL context without mountPath"
+
+			By("Creating Task with URL context without mountPath")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Type: kubeopenv1alpha1.ContextTypeURL,
+							URL: &kubeopenv1alpha1.URLContext{
+								Source: "https://example.com/guidelines.md",
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking Pod has url-fetch-0 container appending to the context file")
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			var urlFetch *corev1.Container
+			for i := range createdPod.Spec.InitContainers {
+				if createdPod.Spec.InitContainers[i].Name == "url-fetch-0" {
+					urlFetch = &createdPod.Spec.InitContainers[i]
+					break
+				}
+			}
+			Expect(urlFetch).ShouldNot(BeNil(), "Expected url-fetch-0 container")
+
+			envValues := make(map[string]string)
+			for _, env := range urlFetch.Env {
+				envValues[env.Name] = env.Value
+			}
+			Expect(envValues["URL_TARGET"]).Should(Equal("/workspace/" + ContextFileRelPath))
+			Expect(envValues["URL_APPEND"]).Should(Equal("true"))
+			Expect(envValues["URL_TIMEOUT"]).Should(Equal("30"))
+
+			By("Checking agent container loads the context file")
+			var foundConfigContent bool
+			for _, env := range createdPod.Spec.Containers[0].Env {
+				if env.Name == OpenCodeConfigContentEnvVar {
+					foundConfigContent = true
+					Expect(env.Value).Should(ContainSubstring(ContextFileRelPath))
+				}
+			}
+			Expect(foundConfigContent).Should(BeTrue(), "Expected %s env var", OpenCodeConfigContentEnvVar)
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should set URLFetchError condition when url-fetch fails", func() {
+			taskName := "test-task-url-fetch-error"
+			description := "Test URL fetch failure"
+
+			By("Creating Task with URL context")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Type: kubeopenv1alpha1.ContextTypeURL,
+							URL: &kubeopenv1alpha1.URLContext{
+								Source: "https://example.com/missing.md",
+							},
+							MountPath: "missing.md",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating url-fetch failure")
+			createdPod.Status.Phase = corev1.PodFailed
+			createdPod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "url-fetch-0",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 1,
+							Message:  "failed to fetch https://example.com/missing.md: HTTP 404: not found",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task has URLFetchError condition")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonURLFetchError))
+			Expect(createdTask.Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+			cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+			Expect(cond.Message).Should(ContainSubstring("HTTP 404"))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should set GitInitError condition with the git error when git-init fails", func() {
+			taskName := "test-task-git-init-error"
+			description := "Test Git clone failure"
+
+			By("Creating Task with a Git context")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Type: kubeopenv1alpha1.ContextTypeGit,
+							Git: &kubeopenv1alpha1.GitContext{
+								Repository: "https://github.com/example/repo",
+								Ref:        "missing",
+							},
+							MountPath: "/workspace/repo",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podLookupKey := types.NamespacedName{Name: taskName + "-pod", Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating git-init failure")
+			gitError := "failed to clone https://github.com/example/repo: git clone failed: fatal: Remote branch missing not found in upstream origin"
+			createdPod.Status.Phase = corev1.PodFailed
+			createdPod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "git-init-0",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 1,
+							Message:  gitError,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task has GitInitError condition with the git error")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonGitInitError))
+			Expect(createdTask.Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+			cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+			Expect(cond.Message).Should(Equal(gitError))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should push changes back with a git-push sidecar and fail with GitPushError when the push fails", func() {
+			taskName := "test-task-git-push-error"
+			description := "Test Git push back failure"
+
+			By("Creating Task with a Git context that pushes back")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Type: kubeopenv1alpha1.ContextTypeGit,
+							Git: &kubeopenv1alpha1.GitContext{
+								Repository: "https://github.com/example/repo",
+								PushBack:   &kubeopenv1alpha1.GitPushBack{},
+							},
+							MountPath: "/workspace/repo",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podLookupKey := types.NamespacedName{Name: taskName + "-pod", Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Checking the Pod runs a git-push sidecar for the Git context")
+			var sidecar *corev1.Container
+			for i := range createdPod.Spec.InitContainers {
+				if createdPod.Spec.InitContainers[i].Name == "git-push-0" {
+					sidecar = &createdPod.Spec.InitContainers[i]
+				}
+			}
+			Expect(sidecar).ShouldNot(BeNil())
+			Expect(sidecar.RestartPolicy).ShouldNot(BeNil())
+			Expect(*sidecar.RestartPolicy).Should(Equal(corev1.ContainerRestartPolicyAlways))
+			Expect(sidecar.Env).Should(ContainElement(corev1.EnvVar{Name: "GIT_PUSH_BRANCH", Value: "kubeopencode/" + taskName}))
+
+			By("Simulating the agent succeeding and git-push failing")
+			createdPod.Status.Phase = corev1.PodSucceeded
+			createdPod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "git-push-0",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 1,
+							Message:  "failed to push to https://github.com/example/repo: git push failed: exit status 128",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task has GitPushError condition")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonGitPushError))
+			Expect(createdTask.Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+	})
+
+	Context("Resolved contexts", func() {
+		It("Should record the resolved contexts with their commits and digests in status", func() {
+			taskName := "test-task-resolved-contexts"
+			description := "Test resolved contexts"
+
+			By("Creating a ConfigMap and a Task with Text, ConfigMap and Git contexts")
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "resolved-contexts-cm", Namespace: taskNamespace},
+				Data:       map[string]string{"guide.md": "Follow the guide"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).Should(Succeed())
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{Name: "rules", Type: kubeopenv1alpha1.ContextTypeText, Text: "Be concise"},
+						{
+							Type:      kubeopenv1alpha1.ContextTypeConfigMap,
+							ConfigMap: &kubeopenv1alpha1.ConfigMapContext{Name: configMap.Name, Key: "guide.md"},
+						},
+						{
+							Name: "source",
+							Type: kubeopenv1alpha1.ContextTypeGit,
+							Git: &kubeopenv1alpha1.GitContext{
+								Repository: "https://github.com/example/repo",
+								Ref:        "refs/pull/7/head",
+							},
+							MountPath: "/workspace/repo",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking the contexts are recorded when the Task starts")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+			Expect(createdTask.Status.Contexts).Should(Equal([]kubeopenv1alpha1.ResolvedContext{
+				{Type: kubeopenv1alpha1.ContextTypeText, Name: "rules", Digest: contentDigest("Be concise")},
+				{Type: kubeopenv1alpha1.ContextTypeConfigMap, Source: taskNamespace + "/" + configMap.Name, Digest: contentDigest("Follow the guide")},
+				{Type: kubeopenv1alpha1.ContextTypeGit, Name: "source", Source: "https://github.com/example/repo", Ref: "refs/pull/7/head"},
+			}))
+
+			By("Simulating git-init reporting the commit it checked out")
+			podLookupKey := types.NamespacedName{Name: taskName + "-pod", Namespace: taskNamespace}
+			Eventually(func() error {
+				var pod corev1.Pod
+				if err := k8sClient.Get(ctx, podLookupKey, &pod); err != nil {
+					return err
+				}
+				pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+					Name: "git-init-0",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: 0,
+						Message:  `{"commit":"0123456789abcdef0123456789abcdef01234567"}`,
+					}},
+				}}
+				return k8sClient.Status().Update(ctx, &pod)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil || len(createdTask.Status.Contexts) != 3 {
+					return ""
+				}
+				return createdTask.Status.Contexts[2].Commit
+			}, timeout, interval).Should(Equal("0123456789abcdef0123456789abcdef01234567"))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, configMap)).Should(Succeed())
+		})
+	})
+
+	Context("Task timeout", func() {
+		It("Should fail Task with TimedOut reason when timeout is exceeded", func() {
+			taskName := "test-task-timeout"
+			description := "Test task timeout"
+			timeoutSeconds := int32(2)
+
+			By("Creating Task with timeoutSeconds")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:       &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description:    &description,
+					TimeoutSeconds: &timeoutSeconds,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking Pod has activeDeadlineSeconds")
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+			Expect(createdPod.Spec.ActiveDeadlineSeconds).ShouldNot(BeNil())
+			Expect(*createdPod.Spec.ActiveDeadlineSeconds).Should(Equal(int64(2)))
+
+			By("Checking Task fails with TimedOut reason")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+			Expect(createdTask.Status.CompletionTime).ShouldNot(BeNil())
+
+			cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+			Expect(cond).ShouldNot(BeNil())
+			Expect(cond.Reason).Should(Equal(kubeopenv1alpha1.ReasonTimedOut))
+			Expect(cond.Message).Should(ContainSubstring("timed out after"))
+			Expect(cond.Message).Should(ContainSubstring("timeout: 2s"))
+
+			By("Checking Pod is deleted")
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, podLookupKey, &corev1.Pod{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should set TimedOut reason when Pod exceeds activeDeadlineSeconds", func() {
+			taskName := "test-task-deadline-exceeded"
+			description := "Test Pod deadline exceeded"
+			timeoutSeconds := int32(3600)
+
+			By("Creating Task with timeoutSeconds")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:       &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description:    &description,
+					TimeoutSeconds: &timeoutSeconds,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating kubelet enforcing activeDeadlineSeconds")
+			createdPod.Status.Phase = corev1.PodFailed
+			createdPod.Status.Reason = PodReasonDeadlineExceeded
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task fails with TimedOut reason")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonTimedOut))
+			Expect(createdTask.Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should cap TaskTemplate timeout with Agent maxTimeoutSeconds", func() {
+			taskName := "test-task-timeout-ceiling"
+			templateName := "test-template-timeout"
+			agentName := "test-agent-timeout-ceiling"
+			description := "Test timeout ceiling"
+			templateTimeout := int32(7200)
+			maxTimeout := int32(600)
+
+			By("Creating Agent with maxTimeoutSeconds")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ServiceAccountName: "test-agent",
+					WorkspaceDir:       "/workspace",
+					MaxTimeoutSeconds:  &maxTimeout,
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			By("Creating TaskTemplate with timeoutSeconds")
+			template := &kubeopenv1alpha1.TaskTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      templateName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskTemplateSpec{
+					AgentRef:       &kubeopenv1alpha1.AgentReference{Name: agentName},
+					TimeoutSeconds: &templateTimeout,
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).Should(Succeed())
+
+			By("Creating Task with TaskTemplateRef")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					TaskTemplateRef: &kubeopenv1alpha1.TaskTemplateReference{Name: templateName},
+					Description:     &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking Pod activeDeadlineSeconds is capped by the Agent")
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+			Expect(createdPod.Spec.ActiveDeadlineSeconds).ShouldNot(BeNil())
+			Expect(*createdPod.Spec.ActiveDeadlineSeconds).Should(Equal(int64(600)))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, template)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+	})
+
+	Context("Task retry policy", func() {
+		It("Should retry a retryable failure in a fresh Pod and record attempts", func() {
+			taskName := "test-task-retry"
+			description := "Test task retry"
+			backoffSeconds := int32(1)
+
+			By("Creating Task with retryPolicy")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					RetryPolicy: &kubeopenv1alpha1.RetryPolicy{
+						MaxAttempts:    2,
+						BackoffSeconds: &backoffSeconds,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating Pod eviction")
+			createdPod.Status.Phase = corev1.PodFailed
+			createdPod.Status.Reason = PodReasonEvicted
+			createdPod.Status.Message = "The node was low on resource: memory."
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking a second attempt Pod is created")
+			retryPodLookupKey := types.NamespacedName{Name: podName + "-2", Namespace: taskNamespace}
+			retryPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, retryPodLookupKey, retryPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.PodName
+			}, timeout, interval).Should(Equal(podName + "-2"))
+			Expect(createdTask.Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+			Expect(createdTask.Status.Attempts).Should(HaveLen(2))
+			Expect(createdTask.Status.Attempts[0].Attempt).Should(Equal(int32(1)))
+			Expect(createdTask.Status.Attempts[0].PodName).Should(Equal(podName))
+			Expect(createdTask.Status.Attempts[0].Reason).Should(Equal(kubeopenv1alpha1.ReasonEvicted))
+			Expect(createdTask.Status.Attempts[0].EndTime).ShouldNot(BeNil())
+			Expect(createdTask.Status.Attempts[1].Attempt).Should(Equal(int32(2)))
+			Expect(createdTask.Status.Attempts[1].EndTime).Should(BeNil())
+
+			By("Simulating second attempt success")
+			retryPod.Status.Phase = corev1.PodSucceeded
+			Expect(k8sClient.Status().Update(ctx, retryPod)).Should(Succeed())
+
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseCompleted))
+			Expect(createdTask.Status.Attempts[1].EndTime).ShouldNot(BeNil())
+			Expect(createdTask.Status.Attempts[1].ExitCode).ShouldNot(BeNil())
+			Expect(*createdTask.Status.Attempts[1].ExitCode).Should(Equal(int32(0)))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should not retry a failure reason that is not in retryOn", func() {
+			taskName := "test-task-retry-not-retryable"
+			description := "Test non-retryable failure"
+
+			By("Creating Task with retryPolicy using default retryOn")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					RetryPolicy: &kubeopenv1alpha1.RetryPolicy{
+						MaxAttempts: 3,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating agent container failure")
+			createdPod.Status.Phase = corev1.PodFailed
+			createdPod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "agent",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 1,
+							Reason:   "Error",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task fails without retrying")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+			Expect(createdTask.Status.Attempts).Should(HaveLen(1))
+			Expect(createdTask.Status.Attempts[0].Reason).Should(Equal(kubeopenv1alpha1.ReasonPodFailed))
+			Expect(createdTask.Status.Attempts[0].ExitCode).ShouldNot(BeNil())
+			Expect(*createdTask.Status.Attempts[0].ExitCode).Should(Equal(int32(1)))
+
+			cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+			Expect(cond).ShouldNot(BeNil())
+			Expect(cond.Reason).Should(Equal(kubeopenv1alpha1.ReasonPodFailed))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should fail the Task when maxAttempts is exhausted", func() {
+			taskName := "test-task-retry-exhausted"
+			description := "Test retry exhaustion"
+			backoffSeconds := int32(1)
+
+			By("Creating Task with retryPolicy that retries PodFailed")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					RetryPolicy: &kubeopenv1alpha1.RetryPolicy{
+						MaxAttempts:    2,
+						BackoffSeconds: &backoffSeconds,
+						RetryOn:        []string{kubeopenv1alpha1.ReasonPodFailed},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			for _, podName := range []string{taskName + "-pod", taskName + "-pod-2"} {
+				By(fmt.Sprintf("Simulating failure of Pod %s", podName))
+				podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+				createdPod := &corev1.Pod{}
+				Eventually(func() bool {
+					return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+				}, timeout, interval).Should(BeTrue())
+
+				createdPod.Status.Phase = corev1.PodFailed
+				Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+			}
+
+			By("Checking Task is Failed after the last attempt")
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+			Expect(createdTask.Status.Attempts).Should(HaveLen(2))
+			for _, attempt := range createdTask.Status.Attempts {
+				Expect(attempt.Reason).Should(Equal(kubeopenv1alpha1.ReasonPodFailed))
+				Expect(attempt.EndTime).ShouldNot(BeNil())
+			}
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+	})
+
+	Context("Lost Pods", func() {
+		It("Should fail a Running Task whose Pod was deleted", func() {
+			taskName := "test-task-pod-lost"
+			description := "Test lost Pod"
+
+			By("Creating Task")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Deleting the Pod outside the controller")
+			pod := &corev1.Pod{}
+			podLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			Expect(k8sClient.Get(ctx, podLookupKey, pod)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, pod)).Should(Succeed())
+
+			By("Checking Task fails with PodLost")
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+
+			cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+			Expect(cond).ShouldNot(BeNil())
+			Expect(cond.Reason).Should(Equal(kubeopenv1alpha1.ReasonPodLost))
+			Expect(createdTask.Status.Attempts).Should(HaveLen(1))
+			Expect(createdTask.Status.Attempts[0].Reason).Should(Equal(kubeopenv1alpha1.ReasonPodLost))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should retry a lost Pod with the default retryOn", func() {
+			taskName := "test-task-pod-lost-retry"
+			description := "Test lost Pod retry"
+			backoffSeconds := int32(1)
+
+			By("Creating Task with retryPolicy")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					RetryPolicy: &kubeopenv1alpha1.RetryPolicy{
+						MaxAttempts:    2,
+						BackoffSeconds: &backoffSeconds,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			pod := &corev1.Pod{}
+			podLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, pod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Deleting the Pod outside the controller")
+			Expect(k8sClient.Delete(ctx, pod)).Should(Succeed())
+
+			By("Checking a retry Pod is created")
+			retryPodLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod-2", taskName), Namespace: taskNamespace}
+			retryPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, retryPodLookupKey, retryPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() int {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return 0
+				}
+				return len(createdTask.Status.Attempts)
+			}, timeout, interval).Should(Equal(2))
+			Expect(createdTask.Status.Attempts[0].Reason).Should(Equal(kubeopenv1alpha1.ReasonPodLost))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+	})
+
+	Context("Pod failure causes", func() {
+		It("Should fail the Task with OOMKilled when the agent exceeds its memory limit", func() {
+			taskName := "test-task-oomkilled"
+			description := "Test OOMKilled"
+
+			By("Creating Task")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating the agent container being OOMKilled")
+			createdPod.Status.Phase = corev1.PodFailed
+			createdPod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "agent",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 137,
+							Reason:   "OOMKilled",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task fails with OOMKilled")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+
+			cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+			Expect(cond).ShouldNot(BeNil())
+			Expect(cond.Reason).Should(Equal(kubeopenv1alpha1.ReasonOOMKilled))
+			Expect(cond.Message).Should(ContainSubstring("container agent was OOMKilled"))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should flag a Running Task as Stalled while its image cannot be pulled", func() {
+			taskName := "test-task-image-pull"
+			description := "Test image pull stall"
+
+			By("Creating Task")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating ImagePullBackOff on the agent container")
+			createdPod.Status.Phase = corev1.PodPending
+			createdPod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "agent",
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{
+							Reason:  "ImagePullBackOff",
+							Message: `Back-off pulling image "example.com/missing:latest"`,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task is flagged as Stalled with ImagePullError")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeStalled)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonImagePullError))
+			Expect(createdTask.Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+			cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeStalled)
+			Expect(cond.Message).Should(ContainSubstring("example.com/missing:latest"))
+
+			By("Simulating the Pod starting")
+			Expect(k8sClient.Get(ctx, podLookupKey, createdPod)).Should(Succeed())
+			createdPod.Status.Phase = corev1.PodRunning
+			createdPod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "agent",
+					State: corev1.ContainerState{
+						Running: &corev1.ContainerStateRunning{},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking the Stalled condition is cleared")
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return false
+				}
+				return meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeStalled) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should flag a Running Task as Stalled when its Pod is unschedulable", func() {
+			taskName := "test-task-unschedulable"
+			description := "Test unschedulable stall"
+
+			By("Creating Task")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Simulating an Unschedulable PodScheduled condition")
+			createdPod.Status.Phase = corev1.PodPending
+			createdPod.Status.Conditions = []corev1.PodCondition{
+				{
+					Type:    corev1.PodScheduled,
+					Status:  corev1.ConditionFalse,
+					Reason:  corev1.PodReasonUnschedulable,
+					Message: "0/3 nodes are available: 3 Insufficient memory.",
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task is flagged as Stalled with Unschedulable")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeStalled)
+				if cond == nil {
+					return ""
+				}
+				return cond.Message
+			}, timeout, interval).Should(ContainSubstring("Insufficient memory"))
+			Expect(createdTask.Status.Phase).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+	})
+
+	Context("Task results", func() {
+		It("Should copy the agent's structured result into Task status", func() {
+			taskName := "test-task-results"
+			description := "Test task results"
+
+			By("Creating Task")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Checking the agent container reports through the result file")
+			Expect(createdPod.Spec.Containers[0].TerminationMessagePath).Should(Equal("/workspace/" + ResultFileRelPath))
+
+			By("Simulating Pod completion with a result termination message")
+			createdPod.Status.Phase = corev1.PodSucceeded
+			createdPod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "agent",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 0,
+							Message:  `{"outcome":"needs-human","summary":"Cannot decide between two designs","outputs":{"branch":"feature/x","files":2}}`,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, createdPod)).Should(Succeed())
+
+			By("Checking Task status.results")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseCompleted))
+			Expect(createdTask.Status.Results).ShouldNot(BeNil())
+			Expect(createdTask.Status.Results.Outcome).Should(Equal(kubeopenv1alpha1.TaskOutcomeNeedsHuman))
+			Expect(createdTask.Status.Results.Summary).Should(Equal("Cannot decide between two designs"))
+			Expect(createdTask.Status.Results.Outputs).Should(HaveKeyWithValue("branch", "feature/x"))
+			Expect(createdTask.Status.Results.Outputs).Should(HaveKeyWithValue("files", "2"))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+	})
+
+	Context("Git Context configuration", func() {
+		It("Should create git-init container with correct arguments", func() {
+			taskName := "test-task-git-context"
+			description := "Test Git context"
+			mountPath := "/workspace/source"
+
+			By("Creating Task with Git context")
+			depth := 1
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Name: "source-code",
+							Type: kubeopenv1alpha1.ContextTypeGit,
+							Git: &kubeopenv1alpha1.GitContext{
+								Repository: "https://github.com/example/repo.git",
+								Ref:        "main",
+								Depth:      &depth,
+							},
+							MountPath: mountPath,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking Task status")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Checking Pod has git-init-0 container")
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			// Git init containers are named git-init-0, git-init-1, etc.
+			var foundGitInit bool
+			for _, initC := range createdPod.Spec.InitContainers {
+				if initC.Name == "git-init-0" {
+					foundGitInit = true
+					// Verify git-init has correct environment variables
+					var foundRepoUrl, foundRef bool
+					for _, env := range initC.Env {
+						if env.Name == "GIT_REPO" && env.Value == "https://github.com/example/repo.git" {
+							foundRepoUrl = true
+						}
+						if env.Name == "GIT_REF" && env.Value == "main" {
+							foundRef = true
+						}
+					}
+					Expect(foundRepoUrl).Should(BeTrue(), "Expected GIT_REPO env var")
+					Expect(foundRef).Should(BeTrue(), "Expected GIT_REF env var")
+					break
+				}
+			}
+			Expect(foundGitInit).Should(BeTrue(), "Expected git-init-0 container")
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+		})
+
+		It("Should pass submodules, LFS and sparse checkout options to git-init and reject a path outside the sparse checkout", func() {
+			taskName := "test-task-git-checkout-options"
+			description := "Test Git checkout options"
+
+			By("Creating Task with a sparse Git context")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Name: "monorepo",
+							Type: kubeopenv1alpha1.ContextTypeGit,
+							Git: &kubeopenv1alpha1.GitContext{
+								Repository:     "https://github.com/example/monorepo.git",
+								Submodules:     kubeopenv1alpha1.GitSubmodulesShallow,
+								LFS:            &kubeopenv1alpha1.GitLFS{Include: []string{"assets/**"}},
+								SparseCheckout: []string{"services/api", "libs/common"},
+							},
+							MountPath: "/workspace/monorepo",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking git-init-0 environment variables")
+			podLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			env := make(map[string]string)
+			for _, initC := range createdPod.Spec.InitContainers {
+				if initC.Name == "git-init-0" {
+					for _, e := range initC.Env {
+						env[e.Name] = e.Value
+					}
+				}
+			}
+			Expect(env).Should(HaveKeyWithValue("GIT_SUBMODULES", "shallow"))
+			Expect(env).Should(HaveKeyWithValue("GIT_LFS", "true"))
+			Expect(env).Should(HaveKeyWithValue("GIT_LFS_INCLUDE", "assets/**"))
+			Expect(env).Should(HaveKeyWithValue("GIT_SPARSE_CHECKOUT", "services/api\nlibs/common"))
+
+			By("Creating Task mounting a path outside the sparse checkout")
+			invalidTask := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName + "-invalid",
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Name: "monorepo",
+							Type: kubeopenv1alpha1.ContextTypeGit,
+							Git: &kubeopenv1alpha1.GitContext{
+								Repository:     "https://github.com/example/monorepo.git",
+								Path:           "services/web/.claude",
+								SparseCheckout: []string{"services/api"},
+							},
+							MountPath: "/workspace/.claude",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, invalidTask)).Should(Succeed())
+
+			By("Checking Task status is Failed with validation error")
+			invalidLookupKey := types.NamespacedName{Name: invalidTask.Name, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, invalidLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseFailed))
+
+			readyCondition := meta.FindStatusCondition(createdTask.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+			Expect(readyCondition).ShouldNot(BeNil())
+			Expect(readyCondition.Message).Should(ContainSubstring("not within the sparse checkout directories"))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, invalidTask)).Should(Succeed())
+		})
+
+		It("Should create the Git cache claim and clone through it when the Git cache is enabled", func() {
+			taskName := "test-task-git-cache"
+			description := "Test Git mirror cache"
+
+			By("Enabling the Git cache on a PersistentVolumeClaim")
+			config := &kubeopenv1alpha1.KubeOpenCodeConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "default",
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.KubeOpenCodeConfigSpec{
+					GitCache: &kubeopenv1alpha1.GitCacheConfig{
+						PersistentVolumeClaim: &kubeopenv1alpha1.GitCacheVolumeClaim{},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, config)).Should(Succeed())
+
+			By("Creating Task with Git context")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: testAgentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Type:      kubeopenv1alpha1.ContextTypeGit,
+							Git:       &kubeopenv1alpha1.GitContext{Repository: "https://github.com/example/monorepo.git"},
+							MountPath: "/workspace/monorepo",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking the Pod mounts the Git cache claim in git-init")
+			podLookupKey := types.NamespacedName{Name: taskName + "-pod", Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			var claimName string
+			for _, v := range createdPod.Spec.Volumes {
+				if v.Name == GitCacheVolumeName && v.PersistentVolumeClaim != nil {
+					claimName = v.PersistentVolumeClaim.ClaimName
+				}
+			}
+			Expect(claimName).Should(Equal(GitCachePVCName))
+			for _, initC := range createdPod.Spec.InitContainers {
+				if initC.Name == "git-init-0" {
+					Expect(initC.Env).Should(ContainElement(corev1.EnvVar{Name: "GIT_CACHE_DIR", Value: GitCacheMountPath}))
+				}
+			}
+
+			By("Checking the Git cache claim was created")
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: GitCachePVCName, Namespace: taskNamespace}, pvc)).Should(Succeed())
+			Expect(pvc.Spec.AccessModes).Should(ConsistOf(corev1.ReadWriteMany))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, config)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, pvc)).Should(Succeed())
+		})
+	})
+
+	Context("Server-mode Task execution", func() {
+		It("Should create Pod with --attach flag pointing to server URL", func() {
+			agentName := "test-server-agent-task"
+			taskName := "test-task-server-mode"
 			description := "Test server mode task"
 
-			By("Creating Server-mode Agent")
+			By("Creating Server-mode Agent")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					ExecutorImage:      "quay.io/kubeopencode/kubeopencode-agent-devbox:latest",
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						Port: 4096,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+			markServerReady(agentName, taskNamespace)
+
+			By("Waiting for Agent Deployment to be created")
+			deploymentName := ServerDeploymentName(agentName)
+			Eventually(func() error {
+				var deployment appsv1.Deployment
+				return k8sClient.Get(ctx, types.NamespacedName{
+					Name:      deploymentName,
+					Namespace: taskNamespace,
+				}, &deployment)
+			}, timeout, interval).Should(Succeed())
+
+			By("Creating Task with Server-mode Agent")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+					Contexts: []kubeopenv1alpha1.ContextItem{
+						{
+							Name: "source",
+							Type: kubeopenv1alpha1.ContextTypeGit,
+							Git: &kubeopenv1alpha1.GitContext{
+								Repository: "https://github.com/example/repo.git",
+							},
+							MountPath: "git-source",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Checking Task transitions to Running")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Checking Pod command contains --attach flag")
+			podName := fmt.Sprintf("%s-pod", taskName)
+			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
+			createdPod := &corev1.Pod{}
+			Eventually(func() bool {
+				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
+			}, timeout, interval).Should(BeTrue())
+
+			// Server mode should use --attach flag in command
+			container := createdPod.Spec.Containers[0]
+			commandStr := fmt.Sprintf("%v", container.Command)
+			Expect(commandStr).Should(ContainSubstring("--attach"))
+
+			// Should contain server URL
+			expectedURL := ServerURL(agentName, taskNamespace, 4096)
+			Expect(commandStr).Should(ContainSubstring(expectedURL))
+
+			By("Checking the session runs in the Task's workspace on the server")
+			taskWorkspace := ServerTaskWorkspaceDir("/workspace", taskNamespace, taskName)
+			Expect(commandStr).Should(ContainSubstring("--dir " + taskWorkspace))
+			initContainers := createdPod.Spec.InitContainers
+			Expect(initContainers).NotTo(BeEmpty())
+			upload := initContainers[len(initContainers)-1]
+			Expect(upload.Name).To(Equal("workspace-upload"))
+
+			By("Checking the Git context is uploaded with the workspace")
+			Expect(upload.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name:      "git-context-0",
+				MountPath: taskWorkspace + "/git-source",
+				SubPath:   DefaultGitLink,
+			}))
+
+			By("Checking the upload is authorized with the Agent's workspace-sync token")
+			var tokenEnv *corev1.EnvVar
+			for i := range upload.Env {
+				if upload.Env[i].Name == "WORKSPACE_SYNC_TOKEN" {
+					tokenEnv = &upload.Env[i]
+				}
+			}
+			Expect(tokenEnv).NotTo(BeNil())
+			Expect(tokenEnv.ValueFrom.SecretKeyRef.Name).To(Equal(ServerSyncTokenSecretName(agentName)))
+			Eventually(func() []byte {
+				var secret corev1.Secret
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: ServerSyncTokenSecretName(agentName), Namespace: taskNamespace}, &secret); err != nil {
+					return nil
+				}
+				return secret.Data[ServerSyncTokenKey]
+			}, timeout, interval).ShouldNot(BeEmpty())
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+
+		It("Should record the OpenCode session and delete it when the Task is stopped and deleted", func() {
+			agentName := "test-server-agent-session"
+			taskName := "test-task-server-session"
+			description := "Test server session cleanup"
+
+			By("Creating Server-mode Agent")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig:       &kubeopenv1alpha1.ServerConfig{},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+			markServerReady(agentName, taskNamespace)
+
+			By("Creating Task with Server-mode Agent")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil {
+					return ""
+				}
+				return t.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Simulating the attach Pod creating a session on the server")
+			serverURL := ServerURL(agentName, taskNamespace, DefaultServerPort)
+			directory := ServerTaskWorkspaceDir("/workspace", taskNamespace, taskName)
+			fakeOpenCode.addSession(serverURL, directory, "ses_test_session")
+
+			// Trigger a reconcile through a Pod status change
+			podKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			var pod corev1.Pod
+			Expect(k8sClient.Get(ctx, podKey, &pod)).Should(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			Expect(k8sClient.Status().Update(ctx, &pod)).Should(Succeed())
+
+			By("Expecting the session ID to be recorded")
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil {
+					return ""
+				}
+				return t.Status.SessionID
+			}, timeout, interval).Should(Equal("ses_test_session"))
+
+			By("Stopping the Task")
+			var runningTask kubeopenv1alpha1.Task
+			Expect(k8sClient.Get(ctx, taskLookupKey, &runningTask)).Should(Succeed())
+			if runningTask.Annotations == nil {
+				runningTask.Annotations = map[string]string{}
+			}
+			runningTask.Annotations[AnnotationStop] = "true"
+			Expect(k8sClient.Update(ctx, &runningTask)).Should(Succeed())
+
+			By("Expecting the session to be deleted and the session ID cleared")
+			Eventually(func() bool {
+				return fakeOpenCode.hasSession(serverURL, "ses_test_session")
+			}, timeout, interval).Should(BeFalse())
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil {
+					return "error"
+				}
+				return t.Status.SessionID
+			}, timeout, interval).Should(BeEmpty())
+
+			By("Deleting the Task removes sessions left in its workspace")
+			fakeOpenCode.addSession(serverURL, directory, "ses_retry_session")
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Eventually(func() bool {
+				return fakeOpenCode.hasSession(serverURL, "ses_retry_session")
+			}, timeout, interval).Should(BeFalse())
+
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+
+		It("Should continue the session of the Task referenced by sessionRef once it has finished", func() {
+			agentName := "test-server-agent-followup"
+			firstTaskName := "test-task-server-first"
+			followUpTaskName := "test-task-server-followup"
+			description := "Test follow-up Task"
+
+			By("Creating Server-mode Agent")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig:       &kubeopenv1alpha1.ServerConfig{},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+			markServerReady(agentName, taskNamespace)
+
+			By("Creating the first Task and recording its session")
+			firstTask := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      firstTaskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, firstTask)).Should(Succeed())
+
+			firstTaskKey := types.NamespacedName{Name: firstTaskName, Namespace: taskNamespace}
+			Eventually(func() kubeopenv1alpha1.TaskPhase {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, firstTaskKey, &t); err != nil {
+					return ""
+				}
+				return t.Status.Phase
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			serverURL := ServerURL(agentName, taskNamespace, DefaultServerPort)
+			fakeOpenCode.addSession(serverURL, ServerTaskWorkspaceDir("/workspace", taskNamespace, firstTaskName), "ses_followup")
+
+			firstPodKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", firstTaskName), Namespace: taskNamespace}
+			var firstPod corev1.Pod
+			Expect(k8sClient.Get(ctx, firstPodKey, &firstPod)).Should(Succeed())
+			firstPod.Status.Phase = corev1.PodRunning
+			Expect(k8sClient.Status().Update(ctx, &firstPod)).Should(Succeed())
+
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, firstTaskKey, &t); err != nil {
+					return ""
+				}
+				return t.Status.SessionID
+			}, timeout, interval).Should(Equal("ses_followup"))
+
+			By("Creating a follow-up Task while the first Task is still running")
+			followUpTask := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      followUpTaskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+					SessionRef:  &kubeopenv1alpha1.SessionReference{Name: firstTaskName},
+				},
+			}
+			Expect(k8sClient.Create(ctx, followUpTask)).Should(Succeed())
+
+			By("Expecting the follow-up Task to wait for the session")
+			followUpTaskKey := types.NamespacedName{Name: followUpTaskName, Namespace: taskNamespace}
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, followUpTaskKey, &t); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(t.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+				if t.Status.Phase != kubeopenv1alpha1.TaskPhaseQueued || cond == nil || cond.Status != metav1.ConditionTrue {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonWaitingForSession))
+
+			By("Completing the first Task")
+			Expect(k8sClient.Get(ctx, firstPodKey, &firstPod)).Should(Succeed())
+			firstPod.Status.Phase = corev1.PodSucceeded
+			Expect(k8sClient.Status().Update(ctx, &firstPod)).Should(Succeed())
+
+			By("Expecting the follow-up Task to run in the first Task's session")
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, followUpTaskKey, &t); err != nil || t.Status.Phase != kubeopenv1alpha1.TaskPhaseRunning {
+					return ""
+				}
+				return t.Status.SessionID
+			}, timeout, interval).Should(Equal("ses_followup"))
+
+			var followUpPod corev1.Pod
+			followUpPodKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", followUpTaskName), Namespace: taskNamespace}
+			Expect(k8sClient.Get(ctx, followUpPodKey, &followUpPod)).Should(Succeed())
+			Expect(fmt.Sprintf("%v", followUpPod.Spec.Containers[0].Command)).Should(ContainSubstring("--session ses_followup"))
+
+			By("Deleting the first Task keeps the session used by the follow-up Task")
+			Expect(k8sClient.Delete(ctx, firstTask)).Should(Succeed())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, firstTaskKey, &kubeopenv1alpha1.Task{}))
+			}, timeout, interval).Should(BeTrue())
+			Expect(fakeOpenCode.hasSession(serverURL, "ses_followup")).Should(BeTrue())
+
+			By("Deleting the follow-up Task removes the session")
+			Expect(k8sClient.Delete(ctx, followUpTask)).Should(Succeed())
+			Eventually(func() bool {
+				return fakeOpenCode.hasSession(serverURL, "ses_followup")
+			}, timeout, interval).Should(BeFalse())
+
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+
+		It("Should fail a follow-up Task whose sessionRef does not exist", func() {
+			agentName := "test-server-agent-missing-ref"
+			taskName := "test-task-server-missing-ref"
+			description := "Test missing sessionRef"
+
+			By("Creating Server-mode Agent")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig:       &kubeopenv1alpha1.ServerConfig{},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+			markServerReady(agentName, taskNamespace)
+
+			By("Creating a follow-up Task referencing a missing Task")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+					SessionRef:  &kubeopenv1alpha1.SessionReference{Name: "does-not-exist"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Expecting the Task to fail with SessionRefError")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil || t.Status.Phase != kubeopenv1alpha1.TaskPhaseFailed {
+					return ""
+				}
+				cond := meta.FindStatusCondition(t.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonSessionRefError))
+
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+
+		It("Should start an idle-scaled server for a new Task and scale it down again", func() {
+			agentName := "test-server-agent-idle"
+			taskName := "test-task-server-idle"
+			description := "Test idle scale-to-zero"
+			idleTimeout := int32(1)
+
+			By("Creating Server-mode Agent with an idle timeout")
 			agent := &kubeopenv1alpha1.Agent{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      agentName,
 					Namespace: taskNamespace,
 				},
 				Spec: kubeopenv1alpha1.AgentSpec{
-					ExecutorImage:      "quay.io/kubeopencode/kubeopencode-agent-devbox:latest",
 					WorkspaceDir:       "/workspace",
 					ServiceAccountName: "test-agent",
 					ServerConfig: &kubeopenv1alpha1.ServerConfig{
-						Port: 4096,
+						IdleTimeoutSeconds: &idleTimeout,
 					},
 				},
 			}
 			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
 
-			By("Waiting for Agent Deployment to be created")
-			deploymentName := ServerDeploymentName(agentName)
-			Eventually(func() error {
+			deploymentKey := types.NamespacedName{Name: ServerDeploymentName(agentName), Namespace: taskNamespace}
+			deploymentReplicas := func() int32 {
 				var deployment appsv1.Deployment
-				return k8sClient.Get(ctx, types.NamespacedName{
-					Name:      deploymentName,
-					Namespace: taskNamespace,
-				}, &deployment)
-			}, timeout, interval).Should(Succeed())
+				if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil || deployment.Spec.Replicas == nil {
+					return -1
+				}
+				return *deployment.Spec.Replicas
+			}
 
-			By("Creating Task with Server-mode Agent")
+			By("Expecting the idle server to be scaled down")
+			Eventually(deploymentReplicas, timeout, interval).Should(Equal(int32(0)))
+
+			By("Creating a Task for the scaled-down Agent")
 			task := &kubeopenv1alpha1.Task{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      taskName,
@@ -3198,36 +5280,431 @@ var _ = Describe("TaskController", func() {
 			}
 			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
 
-			By("Checking Task transitions to Running")
+			By("Expecting the Task to wait for the server and the server to be scaled up")
 			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
-			createdTask := &kubeopenv1alpha1.Task{}
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil || t.Status.Phase != kubeopenv1alpha1.TaskPhaseQueued {
+					return ""
+				}
+				cond := meta.FindStatusCondition(t.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(BeElementOf(kubeopenv1alpha1.ReasonServerStarting, kubeopenv1alpha1.ReasonServerNotReady))
+			Eventually(deploymentReplicas, timeout, interval).Should(Equal(int32(1)))
+
+			By("Simulating the server becoming ready")
+			markServerReady(agentName, taskNamespace)
+
+			By("Expecting the Task to start")
 			Eventually(func() kubeopenv1alpha1.TaskPhase {
-				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil {
 					return ""
 				}
-				return createdTask.Status.Phase
+				return t.Status.Phase
 			}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
 
-			By("Checking Pod command contains --attach flag")
-			podName := fmt.Sprintf("%s-pod", taskName)
-			podLookupKey := types.NamespacedName{Name: podName, Namespace: taskNamespace}
-			createdPod := &corev1.Pod{}
-			Eventually(func() bool {
-				return k8sClient.Get(ctx, podLookupKey, createdPod) == nil
-			}, timeout, interval).Should(BeTrue())
+			By("Completing the Task")
+			podKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			var pod corev1.Pod
+			Expect(k8sClient.Get(ctx, podKey, &pod)).Should(Succeed())
+			pod.Status.Phase = corev1.PodSucceeded
+			Expect(k8sClient.Status().Update(ctx, &pod)).Should(Succeed())
+
+			By("Expecting the server to be scaled down again once idle")
+			Eventually(deploymentReplicas, timeout, interval).Should(Equal(int32(0)))
+			Eventually(func() int32 {
+				var a kubeopenv1alpha1.Agent
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: agentName, Namespace: taskNamespace}, &a); err != nil || a.Status.ServerStatus == nil {
+					return -1
+				}
+				return a.Status.ServerStatus.ReadyReplicas
+			}, timeout, interval).Should(Equal(int32(0)))
 
-			// Server mode should use --attach flag in command
-			container := createdPod.Spec.Containers[0]
-			commandStr := fmt.Sprintf("%v", container.Command)
-			Expect(commandStr).Should(ContainSubstring("--attach"))
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
 
-			// Should contain server URL
-			expectedURL := ServerURL(agentName, taskNamespace, 4096)
-			Expect(commandStr).Should(ContainSubstring(expectedURL))
+		It("Should start an idle-scaled server for a Task whose Agent comes from a TaskTemplate", func() {
+			agentName := "test-server-agent-idle-template"
+			templateName := "test-template-server-idle"
+			taskName := "test-task-server-idle-template"
+			description := "Test idle scale-up from a TaskTemplate"
+			idleTimeout := int32(1)
+
+			By("Creating Server-mode Agent with an idle timeout")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						IdleTimeoutSeconds: &idleTimeout,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			deploymentKey := types.NamespacedName{Name: ServerDeploymentName(agentName), Namespace: taskNamespace}
+			deploymentReplicas := func() int32 {
+				var deployment appsv1.Deployment
+				if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil || deployment.Spec.Replicas == nil {
+					return -1
+				}
+				return *deployment.Spec.Replicas
+			}
+
+			By("Expecting the idle server to be scaled down")
+			Eventually(deploymentReplicas, timeout, interval).Should(Equal(int32(0)))
+
+			By("Creating a TaskTemplate referencing the Agent")
+			template := &kubeopenv1alpha1.TaskTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      templateName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskTemplateSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).Should(Succeed())
+
+			By("Creating a Task without spec.agentRef")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					TaskTemplateRef: &kubeopenv1alpha1.TaskTemplateReference{Name: templateName},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Expecting the Agent from the TaskTemplate to be resolved and its server scaled up")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil || t.Status.AgentRef == nil {
+					return ""
+				}
+				return t.Status.AgentRef.Name
+			}, timeout, interval).Should(Equal(agentName))
+			Eventually(deploymentReplicas, timeout, interval).Should(Equal(int32(1)))
+
+			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, template)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+
+		It("Should queue Tasks until the server is ready and fail them after readyTimeoutSeconds", func() {
+			agentName := "test-server-agent-not-ready"
+			taskName := "test-task-server-not-ready"
+			description := "Test server readiness gate"
+			readyTimeout := int32(2)
+
+			By("Creating Server-mode Agent whose server never becomes ready")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						ReadyTimeoutSeconds: &readyTimeout,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+
+			By("Creating a Task for the Agent")
+			task := &kubeopenv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.TaskSpec{
+					AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+					Description: &description,
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Expecting the Task to be Queued with ServerNotReady and no Pod")
+			taskLookupKey := types.NamespacedName{Name: taskName, Namespace: taskNamespace}
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil || t.Status.Phase != kubeopenv1alpha1.TaskPhaseQueued {
+					return ""
+				}
+				cond := meta.FindStatusCondition(t.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonServerNotReady))
+			podKey := types.NamespacedName{Name: fmt.Sprintf("%s-pod", taskName), Namespace: taskNamespace}
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, podKey, &corev1.Pod{}))).Should(BeTrue())
+
+			By("Expecting the Task to fail once it has waited longer than readyTimeoutSeconds")
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, taskLookupKey, &t); err != nil || t.Status.Phase != kubeopenv1alpha1.TaskPhaseFailed {
+					return ""
+				}
+				cond := meta.FindStatusCondition(t.Status.Conditions, kubeopenv1alpha1.ConditionTypeReady)
+				if cond == nil {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonServerNotReady))
 
-			By("Cleaning up")
 			Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
 			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
 		})
+
+		It("Should drain Running Tasks before rolling out a change to the server", func() {
+			agentName := "test-server-agent-drain"
+			agentKey := types.NamespacedName{Name: agentName, Namespace: taskNamespace}
+			deploymentKey := types.NamespacedName{Name: ServerDeploymentName(agentName), Namespace: taskNamespace}
+			description := "Test server drain"
+
+			By("Creating a ready Server-mode Agent with a Running Task")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig:       &kubeopenv1alpha1.ServerConfig{Port: 4096},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+			markServerReady(agentName, taskNamespace)
+
+			newTask := func(name string) *kubeopenv1alpha1.Task {
+				task := &kubeopenv1alpha1.Task{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: taskNamespace},
+					Spec: kubeopenv1alpha1.TaskSpec{
+						AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+						Description: &description,
+					},
+				}
+				Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+				return task
+			}
+			taskPhase := func(name string) kubeopenv1alpha1.TaskPhase {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: taskNamespace}, &t); err != nil {
+					return ""
+				}
+				return t.Status.Phase
+			}
+			running := newTask("test-task-drain-running")
+			Eventually(func() kubeopenv1alpha1.TaskPhase { return taskPhase(running.Name) }, timeout, interval).
+				Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Changing the server configuration")
+			Eventually(func() error {
+				var a kubeopenv1alpha1.Agent
+				if err := k8sClient.Get(ctx, agentKey, &a); err != nil {
+					return err
+				}
+				a.Spec.ServerConfig.Port = 8080
+				return k8sClient.Update(ctx, &a)
+			}, timeout, interval).Should(Succeed())
+
+			By("Expecting the Agent to drain instead of updating the Deployment")
+			Eventually(func() string {
+				var a kubeopenv1alpha1.Agent
+				if err := k8sClient.Get(ctx, agentKey, &a); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(a.Status.Conditions, AgentConditionDraining)
+				if cond == nil || cond.Status != metav1.ConditionTrue {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(AgentReasonWaitingForTasks))
+			serverPort := func() int32 {
+				var deployment appsv1.Deployment
+				if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil {
+					return 0
+				}
+				return deployment.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort
+			}
+			Consistently(serverPort, "1s", interval).Should(Equal(int32(4096)))
+
+			By("Expecting a new Task to be queued while the Agent drains")
+			queued := newTask("test-task-drain-queued")
+			Eventually(func() string {
+				var t kubeopenv1alpha1.Task
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: queued.Name, Namespace: taskNamespace}, &t); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(t.Status.Conditions, kubeopenv1alpha1.ConditionTypeQueued)
+				if cond == nil || cond.Status != metav1.ConditionTrue {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(kubeopenv1alpha1.ReasonServerDraining))
+
+			By("Completing the Running Task")
+			podKey := types.NamespacedName{Name: running.Name + "-pod", Namespace: taskNamespace}
+			Eventually(func() error {
+				var pod corev1.Pod
+				if err := k8sClient.Get(ctx, podKey, &pod); err != nil {
+					return err
+				}
+				pod.Status.Phase = corev1.PodSucceeded
+				return k8sClient.Status().Update(ctx, &pod)
+			}, timeout, interval).Should(Succeed())
+			Eventually(func() kubeopenv1alpha1.TaskPhase { return taskPhase(running.Name) }, timeout, interval).
+				Should(Equal(kubeopenv1alpha1.TaskPhaseCompleted))
+
+			By("Expecting the change to be rolled out and the queued Task to start")
+			Eventually(serverPort, timeout, interval).Should(Equal(int32(8080)))
+			Eventually(func() string {
+				var a kubeopenv1alpha1.Agent
+				if err := k8sClient.Get(ctx, agentKey, &a); err != nil {
+					return ""
+				}
+				cond := meta.FindStatusCondition(a.Status.Conditions, AgentConditionDraining)
+				if cond == nil || cond.Status != metav1.ConditionFalse {
+					return ""
+				}
+				return cond.Reason
+			}, timeout, interval).Should(Equal(AgentReasonDrained))
+			Eventually(func() kubeopenv1alpha1.TaskPhase { return taskPhase(queued.Name) }, timeout, interval).
+				Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+
+			By("Cleaning up")
+			Expect(k8sClient.Delete(ctx, running)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, queued)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
+
+		It("Should pin Tasks of a multi-replica server to the least loaded replica", func() {
+			agentName := "test-server-agent-replicas"
+			description := "Test server replica affinity"
+			replicas := int32(2)
+
+			By("Creating Server-mode Agent with two replicas")
+			agent := &kubeopenv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      agentName,
+					Namespace: taskNamespace,
+				},
+				Spec: kubeopenv1alpha1.AgentSpec{
+					WorkspaceDir:       "/workspace",
+					ServiceAccountName: "test-agent",
+					ServerConfig: &kubeopenv1alpha1.ServerConfig{
+						Port:     4096,
+						Replicas: &replicas,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).Should(Succeed())
+			markServerReady(agentName, taskNamespace)
+
+			By("Simulating two ready server Pods")
+			podIPs := map[string]string{
+				agentName + "-server-a": "127.0.0.1",
+				agentName + "-server-b": "127.0.0.2",
+			}
+			for name, ip := range podIPs {
+				serverPod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: taskNamespace,
+						Labels: map[string]string{
+							AgentLabelKey:                 agentName,
+							"app.kubernetes.io/component": "server",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: ServerContainerName, Image: "test-executor-image"}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, serverPod)).Should(Succeed())
+				serverPod.Status = corev1.PodStatus{
+					Phase:      corev1.PodRunning,
+					PodIP:      ip,
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				}
+				Expect(k8sClient.Status().Update(ctx, serverPod)).Should(Succeed())
+			}
+
+			By("Creating two Tasks one after the other")
+			var tasks []*kubeopenv1alpha1.Task
+			pinned := map[string]bool{}
+			for _, taskName := range []string{"test-task-replica-1", "test-task-replica-2"} {
+				task := &kubeopenv1alpha1.Task{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      taskName,
+						Namespace: taskNamespace,
+					},
+					Spec: kubeopenv1alpha1.TaskSpec{
+						AgentRef:    &kubeopenv1alpha1.AgentReference{Name: agentName},
+						Description: &description,
+					},
+				}
+				Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+				tasks = append(tasks, task)
+
+				created := &kubeopenv1alpha1.Task{}
+				Eventually(func() kubeopenv1alpha1.TaskPhase {
+					if err := k8sClient.Get(ctx, types.NamespacedName{Name: taskName, Namespace: taskNamespace}, created); err != nil {
+						return ""
+					}
+					return created.Status.Phase
+				}, timeout, interval).Should(Equal(kubeopenv1alpha1.TaskPhaseRunning))
+				Expect(podIPs).To(HaveKey(created.Status.ServerPod))
+				pinned[created.Status.ServerPod] = true
+
+				By("Checking the Task attaches to its replica directly")
+				var taskPod corev1.Pod
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: taskName + "-pod", Namespace: taskNamespace}, &taskPod)).Should(Succeed())
+				commandStr := fmt.Sprintf("%v", taskPod.Spec.Containers[0].Command)
+				Expect(commandStr).Should(ContainSubstring("--attach " + ServerReplicaURL(podIPs[created.Status.ServerPod], 4096)))
+			}
+			Expect(pinned).To(HaveLen(2), "each Task should be pinned to a different replica")
+
+			By("Cleaning up")
+			for _, task := range tasks {
+				Expect(k8sClient.Delete(ctx, task)).Should(Succeed())
+			}
+			for name := range podIPs {
+				Expect(k8sClient.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: taskNamespace}})).Should(Succeed())
+			}
+			Expect(k8sClient.Delete(ctx, agent)).Should(Succeed())
+		})
 	})
 })
+
+// markServerReady simulates the server Deployment of a Server-mode Agent becoming
+// ready, since envtest runs no Deployment controller.
+func markServerReady(agentName, namespace string) {
+	deploymentKey := types.NamespacedName{Name: ServerDeploymentName(agentName), Namespace: namespace}
+	Eventually(func() error {
+		var deployment appsv1.Deployment
+		if err := k8sClient.Get(ctx, deploymentKey, &deployment); err != nil {
+			return err
+		}
+		deployment.Status.Replicas = 1
+		deployment.Status.ReadyReplicas = 1
+		deployment.Status.AvailableReplicas = 1
+		return k8sClient.Status().Update(ctx, &deployment)
+	}, timeout, interval).Should(Succeed())
+}
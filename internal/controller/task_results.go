@@ -0,0 +1,119 @@
+// Copyright Contributors to the KubeOpenCode project
+
+package controller
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+const (
+	// MaxResultSummaryLength is the maximum length in bytes of the result summary
+	// copied into Task status. Longer summaries are truncated.
+	MaxResultSummaryLength = 2048
+
+	// MaxResultOutputs is the maximum number of result outputs copied into Task status.
+	// Outputs beyond the limit are dropped (in key order).
+	MaxResultOutputs = 32
+)
+
+// agentResultFile is the JSON document the agent writes to ResultFileRelPath.
+type agentResultFile struct {
+	Outcome string                     `json:"outcome"`
+	Summary string                     `json:"summary"`
+	Outputs map[string]json.RawMessage `json:"outputs"`
+}
+
+// agentResults extracts the structured result reported by the agent container
+// through its termination message. Returns nil if the agent reported nothing.
+func agentResults(pod *corev1.Pod) *kubeopenv1alpha1.TaskResults {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != "agent" || cs.State.Terminated == nil {
+			continue
+		}
+		return parseTaskResults(cs.State.Terminated.Message)
+	}
+	return nil
+}
+
+// parseTaskResults parses an agent result message into bounded TaskResults.
+// JSON messages are parsed as {"outcome", "summary", "outputs"}; any other
+// non-empty message is used as the summary. Returns nil for an empty message.
+func parseTaskResults(message string) *kubeopenv1alpha1.TaskResults {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return nil
+	}
+
+	var file agentResultFile
+	if err := json.Unmarshal([]byte(message), &file); err != nil {
+		// Plain text termination message: use it as the summary
+		return &kubeopenv1alpha1.TaskResults{Summary: truncateString(message, MaxResultSummaryLength)}
+	}
+
+	results := &kubeopenv1alpha1.TaskResults{
+		Outcome: parseTaskOutcome(file.Outcome),
+		Summary: truncateString(strings.TrimSpace(file.Summary), MaxResultSummaryLength),
+	}
+
+	if len(file.Outputs) > 0 {
+		keys := make([]string, 0, len(file.Outputs))
+		for k := range file.Outputs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) > MaxResultOutputs {
+			keys = keys[:MaxResultOutputs]
+		}
+
+		results.Outputs = make(map[string]string, len(keys))
+		for _, k := range keys {
+			// String values are stored as-is; other JSON values are stored as compact JSON
+			var value string
+			if err := json.Unmarshal(file.Outputs[k], &value); err != nil {
+				value = string(file.Outputs[k])
+			}
+			results.Outputs[k] = value
+		}
+	}
+
+	if results.Outcome == "" && results.Summary == "" && len(results.Outputs) == 0 {
+		return nil
+	}
+	return results
+}
+
+// parseTaskOutcome maps an agent-reported outcome to a TaskOutcome.
+// Matching is case-insensitive and ignores separators, so "needs-human",
+// "needs_human" and "NeedsHuman" are equivalent. Unknown values are ignored.
+func parseTaskOutcome(outcome string) kubeopenv1alpha1.TaskOutcome {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(outcome))
+	switch normalized {
+	case "succeeded", "success":
+		return kubeopenv1alpha1.TaskOutcomeSucceeded
+	case "failed", "failure":
+		return kubeopenv1alpha1.TaskOutcomeFailed
+	case "needshuman":
+		return kubeopenv1alpha1.TaskOutcomeNeedsHuman
+	default:
+		return ""
+	}
+}
+
+// truncateString truncates s to at most maxBytes bytes without splitting a UTF-8 rune.
+func truncateString(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	s = s[:maxBytes]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
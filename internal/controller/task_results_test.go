@@ -0,0 +1,159 @@
+// Copyright Contributors to the KubeOpenCode project
+
+//go:build !integration
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kubeopenv1alpha1 "github.com/kubeopencode/kubeopencode/api/v1alpha1"
+)
+
+func TestParseTaskResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    *kubeopenv1alpha1.TaskResults
+	}{
+		{
+			name:    "empty message",
+			message: "  \n",
+			want:    nil,
+		},
+		{
+			name:    "full result",
+			message: `{"outcome":"succeeded","summary":"Opened PR","outputs":{"prURL":"https://example.com/pr/1","count":3,"meta":{"a":true}}}`,
+			want: &kubeopenv1alpha1.TaskResults{
+				Outcome: kubeopenv1alpha1.TaskOutcomeSucceeded,
+				Summary: "Opened PR",
+				Outputs: map[string]string{
+					"prURL": "https://example.com/pr/1",
+					"count": "3",
+					"meta":  `{"a":true}`,
+				},
+			},
+		},
+		{
+			name:    "needs-human outcome",
+			message: `{"outcome":"needs-human","summary":"Ambiguous requirements"}`,
+			want: &kubeopenv1alpha1.TaskResults{
+				Outcome: kubeopenv1alpha1.TaskOutcomeNeedsHuman,
+				Summary: "Ambiguous requirements",
+			},
+		},
+		{
+			name:    "outcome is case-insensitive",
+			message: `{"outcome":"Needs_Human"}`,
+			want:    &kubeopenv1alpha1.TaskResults{Outcome: kubeopenv1alpha1.TaskOutcomeNeedsHuman},
+		},
+		{
+			name:    "unknown outcome is ignored",
+			message: `{"outcome":"maybe","summary":"done"}`,
+			want:    &kubeopenv1alpha1.TaskResults{Summary: "done"},
+		},
+		{
+			name:    "plain text message",
+			message: "all tests passed\n",
+			want:    &kubeopenv1alpha1.TaskResults{Summary: "all tests passed"},
+		},
+		{
+			name:    "empty JSON object",
+			message: `{}`,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTaskResults(tt.message)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("parseTaskResults() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("parseTaskResults() = nil, want %+v", tt.want)
+			}
+			if got.Outcome != tt.want.Outcome {
+				t.Errorf("Outcome = %q, want %q", got.Outcome, tt.want.Outcome)
+			}
+			if got.Summary != tt.want.Summary {
+				t.Errorf("Summary = %q, want %q", got.Summary, tt.want.Summary)
+			}
+			if len(got.Outputs) != len(tt.want.Outputs) {
+				t.Fatalf("len(Outputs) = %d, want %d", len(got.Outputs), len(tt.want.Outputs))
+			}
+			for k, v := range tt.want.Outputs {
+				if got.Outputs[k] != v {
+					t.Errorf("Outputs[%q] = %q, want %q", k, got.Outputs[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTaskResults_Bounds(t *testing.T) {
+	outputs := make([]string, 0, MaxResultOutputs+5)
+	for i := 0; i < MaxResultOutputs+5; i++ {
+		outputs = append(outputs, fmt.Sprintf(`"key%02d":"v"`, i))
+	}
+	// Multi-byte runes must not be split by truncation
+	summary := strings.Repeat("é", MaxResultSummaryLength)
+	message := fmt.Sprintf(`{"summary":%q,"outputs":{%s}}`, summary, strings.Join(outputs, ","))
+
+	got := parseTaskResults(message)
+	if got == nil {
+		t.Fatal("parseTaskResults() = nil")
+	}
+	if len(got.Summary) > MaxResultSummaryLength {
+		t.Errorf("len(Summary) = %d, want <= %d", len(got.Summary), MaxResultSummaryLength)
+	}
+	if !strings.HasPrefix(summary, got.Summary) {
+		t.Errorf("Summary was not truncated on a rune boundary")
+	}
+	if len(got.Outputs) != MaxResultOutputs {
+		t.Errorf("len(Outputs) = %d, want %d", len(got.Outputs), MaxResultOutputs)
+	}
+	if _, ok := got.Outputs["key00"]; !ok {
+		t.Errorf("Outputs should keep the first keys in sorted order")
+	}
+}
+
+func TestAgentResults(t *testing.T) {
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "sidecar",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Message: `{"summary":"wrong container"}`},
+					},
+				},
+				{
+					Name: "agent",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Message: `{"outcome":"failed","summary":"tests failed"}`},
+					},
+				},
+			},
+		},
+	}
+
+	got := agentResults(pod)
+	if got == nil {
+		t.Fatal("agentResults() = nil")
+	}
+	if got.Outcome != kubeopenv1alpha1.TaskOutcomeFailed || got.Summary != "tests failed" {
+		t.Errorf("agentResults() = %+v", got)
+	}
+
+	if got := agentResults(&corev1.Pod{}); got != nil {
+		t.Errorf("agentResults() for Pod without statuses = %+v, want nil", got)
+	}
+}
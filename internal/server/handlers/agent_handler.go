@@ -116,9 +116,20 @@ func agentToResponse(agent *kubeopenv1alpha1.Agent) types.AgentResponse {
 	}
 
 	if agent.Spec.Quota != nil {
-		resp.Quota = &types.QuotaInfo{
-			MaxTaskStarts: agent.Spec.Quota.MaxTaskStarts,
-			WindowSeconds: agent.Spec.Quota.WindowSeconds,
+		resp.Quota = quotaToInfo(agent.Spec.Quota)
+	}
+
+	if fairShare := agent.Spec.FairShare; fairShare != nil {
+		resp.FairShare = &types.FairShareInfo{
+			MaxConcurrentTasksPerNamespace: fairShare.MaxConcurrentTasksPerNamespace,
+			QuotaPerNamespace:              quotaToInfo(fairShare.QuotaPerNamespace),
+		}
+		for _, share := range fairShare.Namespaces {
+			resp.FairShare.Namespaces = append(resp.FairShare.Namespaces, types.NamespaceShareInfo{
+				Namespace:          share.Namespace,
+				MaxConcurrentTasks: share.MaxConcurrentTasks,
+				Quota:              quotaToInfo(share.Quota),
+			})
 		}
 	}
 
@@ -150,3 +161,14 @@ func agentToResponse(agent *kubeopenv1alpha1.Agent) types.AgentResponse {
 
 	return resp
 }
+
+// quotaToInfo converts a QuotaConfig to an API response (nil if not set)
+func quotaToInfo(quota *kubeopenv1alpha1.QuotaConfig) *types.QuotaInfo {
+	if quota == nil {
+		return nil
+	}
+	return &types.QuotaInfo{
+		MaxTaskStarts: quota.MaxTaskStarts,
+		WindowSeconds: quota.WindowSeconds,
+	}
+}
@@ -108,7 +108,9 @@ func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 		},
-		Spec: kubeopenv1alpha1.TaskSpec{},
+		Spec: kubeopenv1alpha1.TaskSpec{
+			Priority: req.Priority,
+		},
 	}
 
 	// Set description if provided
@@ -116,6 +118,11 @@ func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 		task.Spec.Description = &req.Description
 	}
 
+	// Continue the session of a previous Task if requested
+	if req.SessionRef != "" {
+		task.Spec.SessionRef = &kubeopenv1alpha1.SessionReference{Name: req.SessionRef}
+	}
+
 	// Set task template reference if provided
 	if req.TaskTemplateRef != nil {
 		task.Spec.TaskTemplateRef = &kubeopenv1alpha1.TaskTemplateReference{
@@ -344,13 +351,20 @@ func taskToResponse(task *kubeopenv1alpha1.Task) types.TaskResponse {
 	}
 
 	resp := types.TaskResponse{
-		Name:         task.Name,
-		Namespace:    task.Namespace,
-		Phase:        string(task.Status.Phase),
-		Description:  description,
-		PodName:      task.Status.PodName,
-		PodNamespace: task.Status.PodNamespace,
-		CreatedAt:    task.CreationTimestamp.Time,
+		Name:          task.Name,
+		Namespace:     task.Namespace,
+		Phase:         string(task.Status.Phase),
+		Priority:      task.Spec.Priority,
+		QueuePosition: task.Status.QueuePosition,
+		Description:   description,
+		PodName:       task.Status.PodName,
+		PodNamespace:  task.Status.PodNamespace,
+		SessionID:     task.Status.SessionID,
+		CreatedAt:     task.CreationTimestamp.Time,
+	}
+
+	if task.Spec.SessionRef != nil {
+		resp.SessionRef = task.Spec.SessionRef.Name
 	}
 
 	if task.Spec.AgentRef != nil {
@@ -397,5 +411,13 @@ func taskToResponse(task *kubeopenv1alpha1.Task) types.TaskResponse {
 		})
 	}
 
+	if task.Status.Results != nil {
+		resp.Results = &types.TaskResults{
+			Outcome: string(task.Status.Results.Outcome),
+			Summary: task.Status.Results.Summary,
+			Outputs: task.Status.Results.Outputs,
+		}
+	}
+
 	return resp
 }
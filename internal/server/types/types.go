@@ -44,6 +44,9 @@ type CreateTaskRequest struct {
 	AgentRef        *AgentReference        `json:"agentRef,omitempty"`
 	TaskTemplateRef *TaskTemplateReference `json:"taskTemplateRef,omitempty"`
 	Contexts        []ContextItem          `json:"contexts,omitempty"`
+	Priority        int32                  `json:"priority,omitempty"`
+	// SessionRef is the name of a previous Task whose OpenCode session is continued
+	SessionRef string `json:"sessionRef,omitempty"`
 }
 
 // TaskResponse represents a task in API responses
@@ -51,15 +54,27 @@ type TaskResponse struct {
 	Name           string          `json:"name"`
 	Namespace      string          `json:"namespace"`
 	Phase          string          `json:"phase"`
+	Priority       int32           `json:"priority,omitempty"`
+	QueuePosition  int32           `json:"queuePosition,omitempty"`
 	Description    string          `json:"description,omitempty"`
 	AgentRef       *AgentReference `json:"agentRef,omitempty"`
 	PodName        string          `json:"podName,omitempty"`
 	PodNamespace   string          `json:"podNamespace,omitempty"`
+	SessionID      string          `json:"sessionID,omitempty"`
+	SessionRef     string          `json:"sessionRef,omitempty"`
 	StartTime      *time.Time      `json:"startTime,omitempty"`
 	CompletionTime *time.Time      `json:"completionTime,omitempty"`
 	Duration       string          `json:"duration,omitempty"`
 	CreatedAt      time.Time       `json:"createdAt"`
 	Conditions     []Condition     `json:"conditions,omitempty"`
+	Results        *TaskResults    `json:"results,omitempty"`
+}
+
+// TaskResults represents the structured result reported by the agent
+type TaskResults struct {
+	Outcome string            `json:"outcome,omitempty"`
+	Summary string            `json:"summary,omitempty"`
+	Outputs map[string]string `json:"outputs,omitempty"`
 }
 
 // TaskListResponse represents a list of tasks
@@ -90,6 +105,20 @@ type QuotaInfo struct {
 	WindowSeconds int32 `json:"windowSeconds,omitempty"`
 }
 
+// NamespaceShareInfo represents a per-namespace fair share override
+type NamespaceShareInfo struct {
+	Namespace          string     `json:"namespace"`
+	MaxConcurrentTasks *int32     `json:"maxConcurrentTasks,omitempty"`
+	Quota              *QuotaInfo `json:"quota,omitempty"`
+}
+
+// FairShareInfo represents per-namespace fair share configuration
+type FairShareInfo struct {
+	MaxConcurrentTasksPerNamespace *int32               `json:"maxConcurrentTasksPerNamespace,omitempty"`
+	QuotaPerNamespace              *QuotaInfo           `json:"quotaPerNamespace,omitempty"`
+	Namespaces                     []NamespaceShareInfo `json:"namespaces,omitempty"`
+}
+
 // AgentResponse represents an agent in API responses
 type AgentResponse struct {
 	Name               string           `json:"name"`
@@ -101,6 +130,7 @@ type AgentResponse struct {
 	CredentialsCount   int              `json:"credentialsCount"`
 	MaxConcurrentTasks *int32           `json:"maxConcurrentTasks,omitempty"`
 	Quota              *QuotaInfo       `json:"quota,omitempty"`
+	FairShare          *FairShareInfo   `json:"fairShare,omitempty"`
 	AllowedNamespaces  []string         `json:"allowedNamespaces,omitempty"`
 	Credentials        []CredentialInfo `json:"credentials,omitempty"`
 	Contexts           []ContextItem    `json:"contexts,omitempty"`
@@ -14,19 +14,30 @@ export interface Condition {
   message?: string;
 }
 
+export interface TaskResults {
+  outcome?: string;
+  summary?: string;
+  outputs?: Record<string, string>;
+}
+
 export interface Task {
   name: string;
   namespace: string;
   phase: string;
+  priority?: number;
+  queuePosition?: number;
   description?: string;
   agentRef?: AgentReference;
   podName?: string;
   podNamespace?: string;
+  sessionID?: string;
+  sessionRef?: string;
   startTime?: string;
   completionTime?: string;
   duration?: string;
   createdAt: string;
   conditions?: Condition[];
+  results?: TaskResults;
 }
 
 export interface TaskListResponse {
@@ -44,6 +55,8 @@ export interface CreateTaskRequest {
   description?: string;
   agentRef?: AgentReference;
   taskTemplateRef?: TaskTemplateReference;
+  priority?: number;
+  sessionRef?: string;
 }
 
 export interface ContextItem {
@@ -65,6 +78,18 @@ export interface QuotaInfo {
   windowSeconds?: number;
 }
 
+export interface NamespaceShareInfo {
+  namespace: string;
+  maxConcurrentTasks?: number;
+  quota?: QuotaInfo;
+}
+
+export interface FairShareInfo {
+  maxConcurrentTasksPerNamespace?: number;
+  quotaPerNamespace?: QuotaInfo;
+  namespaces?: NamespaceShareInfo[];
+}
+
 export interface Agent {
   name: string;
   namespace: string;
@@ -75,6 +100,7 @@ export interface Agent {
   credentialsCount: number;
   maxConcurrentTasks?: number;
   quota?: QuotaInfo;
+  fairShare?: FairShareInfo;
   allowedNamespaces?: string[];
   credentials?: CredentialInfo[];
   contexts?: ContextItem[];
@@ -108,6 +108,36 @@ function AgentDetailPage() {
             </div>
           )}
 
+          {/* Fair Share */}
+          {agent.fairShare && (
+            <div>
+              <h3 className="text-lg font-medium text-gray-900 mb-4">Fair Share</h3>
+              <div className="bg-gray-50 rounded-md p-4 space-y-1">
+                {!!agent.fairShare.maxConcurrentTasksPerNamespace && (
+                  <p className="text-sm text-gray-700">
+                    Each namespace runs at most {agent.fairShare.maxConcurrentTasksPerNamespace}{' '}
+                    concurrent tasks
+                  </p>
+                )}
+                {agent.fairShare.quotaPerNamespace && (
+                  <p className="text-sm text-gray-700">
+                    Each namespace starts at most {agent.fairShare.quotaPerNamespace.maxTaskStarts}{' '}
+                    tasks per {agent.fairShare.quotaPerNamespace.windowSeconds} seconds
+                  </p>
+                )}
+                {agent.fairShare.namespaces?.map((share) => (
+                  <p key={share.namespace} className="text-sm text-gray-700">
+                    <span className="font-mono">{share.namespace}</span>
+                    {share.maxConcurrentTasks !== undefined &&
+                      `: max ${share.maxConcurrentTasks || 'unlimited'} concurrent`}
+                    {share.quota &&
+                      `, ${share.quota.maxTaskStarts} starts per ${share.quota.windowSeconds}s`}
+                  </p>
+                ))}
+              </div>
+            </div>
+          )}
+
           {/* Allowed Namespaces */}
           {agent.allowedNamespaces && agent.allowedNamespaces.length > 0 && (
             <div>
@@ -147,6 +147,15 @@ function TaskDetailPage() {
                 {task.completionTime ? new Date(task.completionTime).toLocaleString() : '-'}
               </dd>
             </div>
+            {task.phase === 'Queued' && !!task.queuePosition && (
+              <div>
+                <dt className="text-sm font-medium text-gray-500">Queue Position</dt>
+                <dd className="mt-1 text-sm text-gray-900">
+                  #{task.queuePosition}
+                  {task.priority ? ` (priority ${task.priority})` : ''}
+                </dd>
+              </div>
+            )}
             {task.podName && (
               <div>
                 <dt className="text-sm font-medium text-gray-500">Pod</dt>
@@ -155,6 +164,25 @@ function TaskDetailPage() {
                 </dd>
               </div>
             )}
+            {task.sessionID && (
+              <div>
+                <dt className="text-sm font-medium text-gray-500">OpenCode Session</dt>
+                <dd className="mt-1 text-sm text-gray-900 font-mono">{task.sessionID}</dd>
+              </div>
+            )}
+            {task.sessionRef && (
+              <div>
+                <dt className="text-sm font-medium text-gray-500">Continues Session Of</dt>
+                <dd className="mt-1 text-sm text-gray-900">
+                  <Link
+                    to={`/tasks/${task.namespace}/${task.sessionRef}`}
+                    className="text-primary-600 hover:text-primary-800"
+                  >
+                    {task.sessionRef}
+                  </Link>
+                </dd>
+              </div>
+            )}
           </div>
 
           {task.description && (
@@ -166,6 +194,32 @@ function TaskDetailPage() {
             </div>
           )}
 
+          {task.results && (
+            <div>
+              <dt className="text-sm font-medium text-gray-500 mb-2">Results</dt>
+              <dd className="bg-gray-50 rounded-md p-4 space-y-2">
+                {task.results.outcome && (
+                  <p className="text-sm text-gray-900">
+                    <span className="font-medium">Outcome:</span> {task.results.outcome}
+                  </p>
+                )}
+                {task.results.summary && (
+                  <pre className="text-sm text-gray-900 whitespace-pre-wrap">{task.results.summary}</pre>
+                )}
+                {task.results.outputs && Object.keys(task.results.outputs).length > 0 && (
+                  <dl className="text-sm">
+                    {Object.entries(task.results.outputs).map(([key, value]) => (
+                      <div key={key} className="flex gap-2">
+                        <dt className="font-mono text-gray-500">{key}:</dt>
+                        <dd className="font-mono text-gray-900 break-all">{value}</dd>
+                      </div>
+                    ))}
+                  </dl>
+                )}
+              </dd>
+            </div>
+          )}
+
           {task.conditions && task.conditions.length > 0 && (
             <div>
               <dt className="text-sm font-medium text-gray-500 mb-2">Conditions</dt>